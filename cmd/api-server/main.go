@@ -3,22 +3,27 @@ package main
 import (
 	"flag"
 	"log"
+	"os"
 	"wildproject/internal/app"
-	"wildproject/pkg/env"
 
 	"github.com/joho/godotenv"
 )
 
 func main() {
+	configFile := flag.String("config", os.Getenv("CONFIG_FILE"), "path to YAML or TOML config, env variables take precedence")
+	printConfig := flag.Bool("print-config", false, "print resolved config with secrets redacted and exit")
 	flag.Parse()
 
-	configPath := env.String("CONFIG_PATH")
-	if err := godotenv.Load(configPath); err != nil {
-		log.Fatal(err)
+	// Optional, env file doesn't override the process env
+	if configPath := os.Getenv("CONFIG_PATH"); configPath != "" {
+		if err := godotenv.Load(configPath); err != nil {
+			log.Fatal(err)
+		}
 	}
 
 	a := app.New()
 	a.Run(&app.AppFlags{
-		ConfigPath: configPath,
+		ConfigFile:  *configFile,
+		PrintConfig: *printConfig,
 	})
 }
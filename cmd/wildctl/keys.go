@@ -0,0 +1,20 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// Creates the key the new access tokens are signed with. Running servers
+// switch to it on the event, tokens signed with the previous key stay
+// valid until they expire
+func rotateSigningKeys(ctx context.Context, c *cli, args []string) error {
+	keyID, err := c.keys.Rotate(ctx, operatorDevice)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("rotated signing keys, new key", keyID)
+
+	return nil
+}
@@ -0,0 +1,238 @@
+// Admin CLI for operational tasks. It is configured the same way as
+// the API server and works with the same database and sessions storage.
+//
+// Usage: wildctl <group> <command> [flags]
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"wildproject/internal/app"
+	"wildproject/internal/app/data/database"
+	repo "wildproject/internal/app/data/repositories"
+	event "wildproject/internal/app/domain/events"
+	manager "wildproject/internal/app/domain/managers"
+	model "wildproject/internal/app/domain/models"
+	service "wildproject/internal/app/domain/services"
+
+	"github.com/joho/godotenv"
+	"golang.org/x/term"
+)
+
+var (
+	ErrUnknownCommand   = errors.New("unknown command")
+	ErrDatabaseRequired = errors.New("postgres database driver is required")
+	// Local bus doesn't reach running servers, they would keep serving
+	// cached sessions the commands drop
+	ErrEventsRequired = errors.New("postgres events driver is required")
+)
+
+// Dependencies shared by the commands
+type cli struct {
+	cfg    *model.Config
+	db     database.Instance
+	repos  repo.Repositories
+	uow    repo.UnitOfWork
+	events event.Publisher
+	users  service.UsersService
+	roles  service.RolesService
+	keys   service.SigningKeysService
+	// Every mutation is recorded, as operators bypass the API
+	audit service.AuditRecorder
+}
+
+type command struct {
+	usage string
+	// Needs only the database, the rest of the config isn't validated
+	// and nothing else is set up
+	databaseOnly bool
+	run          func(ctx context.Context, c *cli, args []string) error
+}
+
+var commands = map[string]command{
+	"migrate up":             {"apply all the pending migrations", true, migrateUp},
+	"migrate down":           {"revert latest migrations, -steps 1 by default", true, migrateDown},
+	"migrate status":         {"list migrations with their apply time", true, migrateStatus},
+	"migrate baseline":       {"record migrations up to -version as applied without running them", true, migrateBaseline},
+	"users create":           {"create user, -email and -password", false, createUser},
+	"users reset-password":   {"set new password and drop all the sessions, -email and -password", false, resetPassword},
+	"users grant-roles":      {"grant comma separated -roles to the user, -email", false, grantRoles},
+	"sessions list":          {"list user's sessions, -email", false, listSessions},
+	"sessions revoke":        {"drop user's session by -id or all of them, -email", false, revokeSessions},
+	"sessions purge-expired": {"drop sessions past their expiration", false, purgeExpiredSessions},
+	"keys rotate":            {"sign new access tokens with a new key", false, rotateSigningKeys},
+}
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintln(os.Stderr, "wildctl:", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	if len(os.Args) < 3 {
+		usage()
+		return ErrUnknownCommand
+	}
+
+	name := os.Args[1] + " " + os.Args[2]
+
+	cmd, ok := commands[name]
+	if !ok {
+		usage()
+		return fmt.Errorf("%w: %s", ErrUnknownCommand, name)
+	}
+
+	// Optional, env file doesn't override the process env
+	if configPath := os.Getenv("CONFIG_PATH"); configPath != "" {
+		if err := godotenv.Load(configPath); err != nil {
+			return err
+		}
+	}
+
+	a := app.New()
+	a.LoadConfig(&app.AppFlags{
+		ConfigFile:   os.Getenv("CONFIG_FILE"),
+		DatabaseOnly: cmd.databaseOnly,
+	})
+
+	cfg := a.Config()
+	if cfg.Database.Driver != database.DriverPostgres {
+		return ErrDatabaseRequired
+	}
+
+	if !cmd.databaseOnly && cfg.Events.Driver != event.DriverPostgres {
+		return ErrEventsRequired
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	db, repos, dbDispose := a.InitDatabase()
+	defer dbDispose()
+
+	audit := service.NewAudit(repos.AuditEvents, []byte(cfg.Auth.AuthJwtSecret))
+
+	if cmd.databaseOnly {
+		return cmd.run(ctx, &cli{cfg: cfg, db: db, repos: repos, audit: audit}, os.Args[3:])
+	}
+
+	sr, srDispose := a.InitSessionsRepo(repos.Sessions)
+	defer srDispose()
+
+	repos.Sessions = sr
+
+	uow := a.InitUnitOfWork(db, repos)
+	// Notifies running servers, so they drop cached sessions
+	bus := a.InitEvents(ctx, db)
+
+	bc, bcDispose := a.InitBreachCorpus(cfg.Password.BreachCorpusPath)
+	defer bcDispose()
+
+	users, err := newUsersService(&a, repos, uow, bc, audit, bus)
+	if err != nil {
+		return err
+	}
+
+	c := &cli{
+		cfg:    cfg,
+		db:     db,
+		repos:  repos,
+		uow:    uow,
+		events: bus,
+		users:  users,
+		roles:  service.NewRoles(&cfg.Users, repos.UserRoles, repos.Users, audit),
+		keys:   service.NewSigningKeys(&cfg.Auth, repos.SigningKeys, audit, bus),
+		audit:  audit,
+	}
+
+	return cmd.run(ctx, c, os.Args[3:])
+}
+
+// Users are changed through the same service as by the API, so the
+// commands follow the same password rules and keep the same history
+func newUsersService(
+	a *app.App,
+	repos repo.Repositories,
+	uow repo.UnitOfWork,
+	bc manager.BreachChecker,
+	ar service.AuditRecorder,
+	bus event.Publisher,
+) (service.UsersService, error) {
+	cfg := a.Config()
+
+	ph, err := manager.NewPasswordHasher(&cfg.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	return service.NewUsers(
+		&cfg.Users,
+		&cfg.Password,
+		repos.Users,
+		repos.PasswordHistory,
+		manager.NewPasswordPolicy(&cfg.Password, bc),
+		ph,
+		manager.NewAvatarProcessor(&cfg.Avatar, a.InitStorage()),
+		repos.EmailChanges,
+		a.InitMailer(),
+		ar,
+		bus,
+		uow,
+	), nil
+}
+
+func usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(os.Stderr, "Usage: wildctl <group> <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-24s %s\n", name, commands[name].usage)
+	}
+}
+
+// Shared by the reads, so input buffered by one isn't lost for the next
+var stdin = bufio.NewReader(os.Stdin)
+
+// Returns flag value or reads it from stdin, so secrets don't stay
+// in the shell history. Typed input isn't echoed, piped one is read
+// line by line
+func valueOrStdin(value, name string) (string, error) {
+	if value != "" {
+		return value, nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Fprintf(os.Stderr, "%s: ", name)
+
+		line, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("read %s: %w", name, err)
+		}
+
+		return string(line), nil
+	}
+
+	line, err := stdin.ReadString('\n')
+	// Last line may have no newline
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return "", fmt.Errorf("read %s: %w", name, err)
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
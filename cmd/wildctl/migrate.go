@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	migration "wildproject/internal/app/data/migrations"
+	model "wildproject/internal/app/domain/models"
+	service "wildproject/internal/app/domain/services"
+)
+
+var (
+	ErrVersionRequired = errors.New("-version is required")
+)
+
+func migrator(c *cli) (*migration.Migrator, error) {
+	migrations, err := migration.Embedded()
+	if err != nil {
+		return nil, err
+	}
+
+	return migration.NewMigrator(c.db, migrations), nil
+}
+
+func migrateUp(ctx context.Context, c *cli, args []string) error {
+	m, err := migrator(c)
+	if err != nil {
+		return err
+	}
+
+	applied, err := m.Up(ctx)
+	for _, mig := range applied {
+		fmt.Println("applied", mig)
+	}
+
+	recordMigrations(ctx, c, model.AuditMigrationsApplied, applied)
+
+	if err == nil && len(applied) == 0 {
+		fmt.Println("no pending migrations")
+	}
+
+	return err
+}
+
+func migrateDown(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("migrate down", flag.ExitOnError)
+	steps := fs.Int("steps", 1, "number of migrations to revert")
+	fs.Parse(args)
+
+	m, err := migrator(c)
+	if err != nil {
+		return err
+	}
+
+	reverted, err := m.Down(ctx, *steps)
+	for _, mig := range reverted {
+		fmt.Println("reverted", mig)
+	}
+
+	recordMigrations(ctx, c, model.AuditMigrationsReverted, reverted)
+
+	return err
+}
+
+func migrateStatus(ctx context.Context, c *cli, args []string) error {
+	m, err := migrator(c)
+	if err != nil {
+		return err
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "MIGRATION\tAPPLIED AT")
+
+	for _, s := range statuses {
+		appliedAt := s.AppliedAt
+		if appliedAt == "" {
+			appliedAt = "pending"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\n", s.Migration, appliedAt)
+	}
+
+	return w.Flush()
+}
+
+// Adopts a database created by the SQL scripts preceding the migrations,
+// they match the schema of version 2
+func migrateBaseline(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("migrate baseline", flag.ExitOnError)
+	version := fs.Int("version", 0, "latest migration the database schema already has")
+	fs.Parse(args)
+
+	if *version <= 0 {
+		return ErrVersionRequired
+	}
+
+	m, err := migrator(c)
+	if err != nil {
+		return err
+	}
+
+	recorded, err := m.Baseline(ctx, *version)
+	if err != nil {
+		return err
+	}
+
+	for _, mig := range recorded {
+		fmt.Println("recorded", mig)
+	}
+
+	recordMigrations(ctx, c, model.AuditMigrationsBaselined, recorded)
+
+	return nil
+}
+
+// Records migrations changed even if the command failed midway. The
+// log is itself migrated, so the record is lost if its table is not
+// there, e.g. after reverting all the migrations
+func recordMigrations(ctx context.Context, c *cli, auditType string, migrations []migration.Migration) {
+	if len(migrations) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(migrations))
+	for _, mig := range migrations {
+		names = append(names, mig.String())
+	}
+
+	c.audit.Record(ctx, service.OperatorEvent(auditType, "", operatorDevice, map[string]string{
+		"migrations": strings.Join(names, ","),
+	}))
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	event "wildproject/internal/app/domain/events"
+	model "wildproject/internal/app/domain/models"
+	service "wildproject/internal/app/domain/services"
+)
+
+func listSessions(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("sessions list", flag.ExitOnError)
+	email := fs.String("email", "", "email of the user")
+	fs.Parse(args)
+
+	if *email == "" {
+		return ErrEmailRequired
+	}
+
+	user, err := c.repos.Users.FindByEmail(ctx, *email)
+	if err != nil {
+		return fmt.Errorf("find user: %w", err)
+	}
+
+	sessions, err := c.repos.Sessions.FindAllByUserID(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tUSER AGENT\tCREATED AT\tEXPIRES AT")
+
+	for _, s := range sessions {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.SessionID, s.Uagent, s.CreatedAt, s.ExpiresAt)
+	}
+
+	return w.Flush()
+}
+
+func revokeSessions(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("sessions revoke", flag.ExitOnError)
+	email := fs.String("email", "", "email of the user")
+	sessionID := fs.Int("id", 0, "session to drop, all the sessions if not set")
+	fs.Parse(args)
+
+	if *email == "" {
+		return ErrEmailRequired
+	}
+
+	user, err := c.repos.Users.FindByEmail(ctx, *email)
+	if err != nil {
+		return fmt.Errorf("find user: %w", err)
+	}
+
+	data := map[string]string{"reason": "revoked_by_admin"}
+	auditType := model.AuditSessionsDropped
+
+	if *sessionID == 0 {
+		err = c.repos.Sessions.DropAll(ctx, user.ID)
+	} else {
+		err = dropUserSession(ctx, c, user.ID, *sessionID)
+		data["session_id"] = fmt.Sprint(*sessionID)
+		auditType = model.AuditSessionDropped
+	}
+
+	if err != nil {
+		return err
+	}
+
+	c.events.Publish(event.New(event.SessionRevoked, user.ID, data))
+	c.audit.Record(ctx, service.OperatorEvent(auditType, user.ID, operatorDevice, data))
+	fmt.Println("revoked")
+
+	return nil
+}
+
+// Drops the session only if it belongs to the user
+func dropUserSession(ctx context.Context, c *cli, userID string, sessionID int) error {
+	session, err := c.repos.Sessions.FindBySessionID(ctx, sessionID)
+	if err != nil {
+		return fmt.Errorf("find session: %w", err)
+	}
+
+	if session.UserID != userID {
+		return fmt.Errorf("session %d belongs to another user", sessionID)
+	}
+
+	return c.repos.Sessions.Drop(ctx, sessionID)
+}
+
+func purgeExpiredSessions(ctx context.Context, c *cli, args []string) error {
+	dropped, err := c.repos.Sessions.DropExpired(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.audit.Record(ctx, service.OperatorEvent(model.AuditExpiredSessionsPurged, "", operatorDevice, map[string]string{
+		"count": fmt.Sprint(dropped),
+	}))
+
+	fmt.Println("dropped", dropped, "expired sessions")
+
+	return nil
+}
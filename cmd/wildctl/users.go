@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	model "wildproject/internal/app/domain/models"
+)
+
+var (
+	ErrEmailRequired = errors.New("-email is required")
+	ErrRolesRequired = errors.New("-roles is required")
+)
+
+// Device the audit events of the commands are recorded with
+var operatorDevice = model.DeviceInfo{Uagent: "wildctl"}
+
+func createUser(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("users create", flag.ExitOnError)
+	email := fs.String("email", "", "email of the user")
+	password := fs.String("password", "", "password, read from stdin if not set")
+	fs.Parse(args)
+
+	if *email == "" {
+		return ErrEmailRequired
+	}
+
+	value, err := valueOrStdin(*password, "password")
+	if err != nil {
+		return err
+	}
+
+	userID, err := c.users.Provision(ctx, *email, value, operatorDevice)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("created user", userID)
+
+	return nil
+}
+
+// Sets new password and drops all the user's sessions, so the user
+// has to log in again everywhere
+func resetPassword(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("users reset-password", flag.ExitOnError)
+	email := fs.String("email", "", "email of the user")
+	password := fs.String("password", "", "new password, read from stdin if not set")
+	fs.Parse(args)
+
+	if *email == "" {
+		return ErrEmailRequired
+	}
+
+	user, err := c.repos.Users.FindByEmail(ctx, *email)
+	if err != nil {
+		return fmt.Errorf("find user: %w", err)
+	}
+
+	value, err := valueOrStdin(*password, "password")
+	if err != nil {
+		return err
+	}
+
+	if err := c.users.ResetPassword(ctx, user.ID, value, operatorDevice); err != nil {
+		return err
+	}
+
+	fmt.Println("password reset for user", user.ID)
+
+	return nil
+}
+
+// Adds the roles to the ones the user already has
+func grantRoles(ctx context.Context, c *cli, args []string) error {
+	fs := flag.NewFlagSet("users grant-roles", flag.ExitOnError)
+	email := fs.String("email", "", "email of the user")
+	roles := fs.String("roles", "", "comma separated roles, e.g. admin,support")
+	fs.Parse(args)
+
+	if *email == "" {
+		return ErrEmailRequired
+	}
+
+	if *roles == "" {
+		return ErrRolesRequired
+	}
+
+	user, err := c.repos.Users.FindByEmail(ctx, *email)
+	if err != nil {
+		return fmt.Errorf("find user: %w", err)
+	}
+
+	names := strings.Split(*roles, ",")
+	for i := range names {
+		names[i] = strings.TrimSpace(names[i])
+	}
+
+	granted, err := c.roles.Grant(ctx, user.ID, names, operatorDevice)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("user %s has roles: %s\n", user.ID, strings.Join(granted, ", "))
+
+	return nil
+}
@@ -1,18 +1,38 @@
 package app
 
 import (
+	"context"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 	"wildproject/internal/app/data/database"
+	migration "wildproject/internal/app/data/migrations"
+	repo "wildproject/internal/app/data/repositories"
+	"wildproject/internal/app/data/storage"
+	event "wildproject/internal/app/domain/events"
+	manager "wildproject/internal/app/domain/managers"
 	model "wildproject/internal/app/domain/models"
+	job "wildproject/internal/app/jobs"
+	"wildproject/internal/app/mail"
 	"wildproject/internal/app/router"
 
 	"github.com/getsentry/sentry-go"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/log"
+	"github.com/redis/go-redis/v9"
+	"gopkg.in/yaml.v3"
 )
 
 type AppFlags struct {
-	ConfigPath string
+	// Optional YAML or TOML config file, env variables take precedence
+	ConfigFile string
+	// Print the resolved config with secrets redacted and exit
+	PrintConfig bool
+	// Validate only the database section, for the schema migrations
+	DatabaseOnly bool
 }
 
 type App struct {
@@ -26,48 +46,146 @@ func New() App {
 func (a *App) Run(f *AppFlags) {
 	a.LoadConfig(f)
 
+	if f.PrintConfig {
+		if err := a.PrintConfig(os.Stdout); err != nil {
+			log.Fatalf("print config error: %s", err)
+		}
+
+		return
+	}
+
 	app := fiber.New(fiber.Config{
+		BodyLimit:    bodyLimit(&a.cfg.Avatar),
 		ReadTimeout:  a.cfg.Server.ReadTimeout,
 		WriteTimeout: a.cfg.Server.WriteTimeout,
 		IdleTimeout:  a.cfg.Server.IdleTimeout,
 	})
 
-	dbInstance, dbDispose := a.InitDatabase(a.cfg.Database.Conn)
-	defer dbDispose()
-
+	// Deferred first to flush the errors of the rest of the shutdown
 	sentryDispose := a.InitSentry()
 	defer sentryDispose()
 
+	dbInstance, repos, dbDispose := a.InitDatabase()
+	defer dbDispose()
+
+	if a.cfg.Database.MigrateOnStart {
+		a.RunMigrations(dbInstance)
+	}
+
+	breaches, breachesDispose := a.InitBreachCorpus(a.cfg.Password.BreachCorpusPath)
+	defer breachesDispose()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sr, srDispose := a.InitSessionsRepo(repos.Sessions)
+	defer srDispose()
+
+	repos.Sessions = sr
+	uow := a.InitUnitOfWork(dbInstance, repos)
+
+	st := a.InitStorage()
+	pst := a.InitPrivateStorage()
+	m := a.InitMailer()
+	bus := a.InitEvents(ctx, dbInstance)
+
+	tm := a.InitTokenManager(ctx, repos.SigningKeys)
+
+	jobsWait := a.RunJobs(ctx, repos, uow, st, pst, bus, tm)
+
 	r := router.NewRouter(app, a.cfg)
-	r.Setup(dbInstance)
+	if err := r.Setup(repos, uow, breaches, st, pst, m, bus, tm); err != nil {
+		log.Fatalf("router setup error: %s", err)
+	}
+
+	sig, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	listenErr := make(chan error, 1)
 
 	log.Info("Running up server")
-	if err := app.Listen(a.cfg.Server.Addr); err != nil {
-		log.Error(err)
+	go func() {
+		listenErr <- app.Listen(a.cfg.Server.Addr)
+	}()
+
+	select {
+	case err := <-listenErr:
+		if err != nil {
+			log.Error(err)
+		}
+	case <-sig.Done():
+		a.shutdown(app, &r)
 	}
 
-	// TODO: Add graceful shutdown
+	// Jobs are stopped after the server, as requests may still publish
+	// events until then
+	cancel()
+	jobsWait()
+
 	log.Info("Shut down server")
 }
 
+// Room for the multipart boundaries and part headers around the avatar
+const multipartOverhead = 64 * 1024
+
+// Largest request body, so multipart uploads of avatars up to the max
+// size are accepted and the upload handler checks their size itself
+func bodyLimit(cfg *model.AvatarConfig) int {
+	return max(fiber.DefaultBodyLimit, int(cfg.MaxSize)+multipartOverhead)
+}
+
+// Stops the server letting in-flight requests finish
+func (a *App) shutdown(app *fiber.App, r *router.Router) {
+	log.Info("Shutting down server")
+
+	r.Drain()
+	time.Sleep(a.cfg.Server.ShutdownDelay)
+
+	if err := app.ShutdownWithTimeout(a.cfg.Server.ShutdownTimeout); err != nil {
+		log.Errorf("server shutdown error: %s", err)
+	}
+}
+
 func (a *App) LoadConfig(f *AppFlags) {
 	log.Info("loading app config")
 
-	cfg, err := loadConfigFromEnv()
+	validate := validateConfig
+	if f.DatabaseOnly {
+		validate = validateDatabaseConfig
+	}
+
+	cfg, err := loadConfig(f.ConfigFile, validate)
 	if err != nil {
-		log.Errorf("unable to load app config %s", err)
+		log.Fatalf("unable to load app config: %s", err)
 	}
 
 	a.cfg = cfg
 }
 
+// Writes the resolved config as YAML, secrets are redacted
+func (a *App) PrintConfig(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(a.cfg); err != nil {
+		return err
+	}
+
+	return enc.Close()
+}
+
+func (a *App) Config() *model.Config {
+	return a.cfg
+}
+
 func (a *App) InitSentry() func() {
 	log.Info("Setting up sentry")
 
 	err := sentry.Init(sentry.ClientOptions{
-		Dsn:              a.cfg.Sentry.Dsn,
+		Dsn:              string(a.cfg.Sentry.Dsn),
 		Debug:            a.cfg.Sentry.Debug,
 		ServerName:       a.cfg.Name,
+		EnableTracing:    a.cfg.Sentry.TracesSampleRate > 0,
 		TracesSampleRate: a.cfg.Sentry.TracesSampleRate,
 		AttachStacktrace: a.cfg.Sentry.AttachStackTrace,
 	})
@@ -80,20 +198,283 @@ func (a *App) InitSentry() func() {
 	}
 }
 
-func (a *App) InitDatabase(conn string) (database.Instance, func()) {
-	log.Info("Establishing database connection")
+// Opens database and builds repos on top of it. Instance is nil
+// for the in-memory database
+func (a *App) InitDatabase() (database.Instance, repo.Repositories, func()) {
+	log.Infof("Setting up %s database", a.cfg.Database.Driver)
+
+	switch a.cfg.Database.Driver {
+	case database.DriverPostgres:
+		log.Info("Establishing database connection")
 
-	pg := database.NewPostgres()
-	if err := pg.Open(conn); err != nil {
-		log.Errorf("open database error: %s", err)
+		pg := database.NewPostgres()
+		if err := pg.Open(string(a.cfg.Database.Conn)); err != nil {
+			log.Errorf("open database error: %s", err)
+		}
+
+		instance, err := pg.Instance()
+		if err != nil {
+			log.Errorf("get database instance error: %s", err)
+		}
+
+		return instance, repo.NewPostgresRepositories(instance, a.cfg.Database.QueryTimeout), func() {
+			pg.Close()
+		}
+	case database.DriverMemory:
+		log.Warn("Using in-memory database, all the data is lost on exit")
+		return nil, repo.NewMemoryRepositories(), func() {}
 	}
 
-	instance, err := pg.Instance()
+	log.Fatalf("database init error: %s: %s", database.ErrUnknownDriver, a.cfg.Database.Driver)
+	return nil, repo.Repositories{}, nil
+}
+
+// Applies pending schema migrations embedded into the binary
+func (a *App) RunMigrations(db database.Instance) {
+	log.Info("Applying database migrations")
+
+	migrations, err := migration.Embedded()
 	if err != nil {
-		log.Errorf("get database instance error: %s", err)
+		log.Fatalf("load migrations error: %s", err)
 	}
 
-	return instance, func() {
-		pg.Close()
+	applied, err := migration.NewMigrator(db, migrations).Up(context.Background())
+	for _, m := range applied {
+		log.Infof("Applied migration %s", m)
 	}
+
+	if err != nil {
+		log.Fatalf("apply migrations error: %s", err)
+	}
+}
+
+// Token manager signs with the latest rotated key, the config secret is
+// used until the first rotation
+func (a *App) InitTokenManager(ctx context.Context, kr repo.SigningKeysRepo) *manager.JwtAccessManager {
+	log.Info("Loading signing keys")
+
+	tm := manager.NewJwtManager([]byte(a.cfg.Auth.AuthJwtSecret), a.cfg.Auth.AccessTokenTTL)
+
+	// Keys are reloaded by the job on rotation
+	if err := job.LoadSigningKeys(ctx, kr, tm); err != nil {
+		log.Fatalf("load signing keys error: %s", err)
+	}
+
+	return tm
+}
+
+// Opens breached passwords corpus if path is set, otherwise returns nil checker
+func (a *App) InitBreachCorpus(path string) (manager.BreachChecker, func()) {
+	if path == "" {
+		log.Warn("Breached passwords corpus is not configured, check disabled")
+		return nil, func() {}
+	}
+
+	log.Info("Opening breached passwords corpus")
+
+	corpus, err := manager.OpenPwnedCorpus(path)
+	if err != nil {
+		log.Fatalf("open breached passwords corpus error: %s", err)
+	}
+
+	return corpus, func() {
+		corpus.Close()
+	}
+}
+
+// Sessions are stored either in the database or in the Redis protocol
+// compatible server for high-churn deployments
+func (a *App) InitSessionsRepo(dbRepo repo.SessionsRepo) (repo.SessionsRepo, func()) {
+	log.Infof("Setting up %s sessions repo", a.cfg.Sessions.Driver)
+
+	cfg := a.cfg.Sessions
+
+	switch cfg.Driver {
+	case repo.SessionsDriverDatabase:
+		return dbRepo, func() {}
+	case repo.SessionsDriverRedis:
+		rdb := redis.NewClient(&redis.Options{
+			Addr:     cfg.RedisAddr,
+			Password: string(cfg.RedisPassword),
+			DB:       cfg.RedisDB,
+		})
+
+		if err := rdb.Ping(context.Background()).Err(); err != nil {
+			log.Fatalf("redis connection error: %s", err)
+		}
+
+		return repo.NewRedisSessions(rdb, cfg.RedisPrefix, cfg.RedisExpiredRetention), func() {
+			rdb.Close()
+		}
+	}
+
+	log.Fatalf("sessions repo init error: %s: %s", repo.ErrUnknownSessionsDriver, cfg.Driver)
+	return nil, nil
+}
+
+// Unit of work runs multi-repo operations atomically. Sessions stored
+// outside of the database are not the part of its transactions
+func (a *App) InitUnitOfWork(db database.Instance, repos repo.Repositories) repo.UnitOfWork {
+	switch a.cfg.Database.Driver {
+	case database.DriverPostgres:
+		if a.cfg.Sessions.Driver == repo.SessionsDriverDatabase {
+			return repo.NewPostgresUnitOfWork(db, a.cfg.Database.QueryTimeout, nil)
+		}
+
+		return repo.NewPostgresUnitOfWork(db, a.cfg.Database.QueryTimeout, repos.Sessions)
+	case database.DriverMemory:
+		return repo.NewMemoryUnitOfWork(repos)
+	}
+
+	log.Fatalf("unit of work init error: %s: %s", database.ErrUnknownDriver, a.cfg.Database.Driver)
+	return nil
+}
+
+func (a *App) InitStorage() storage.Storage {
+	log.Infof("Setting up %s storage", a.cfg.Storage.Driver)
+
+	cfg := a.cfg.Storage
+
+	switch cfg.Driver {
+	case storage.DriverLocal:
+		return storage.NewLocal(cfg.LocalDir, cfg.PublicURL)
+	case storage.DriverS3:
+		return storage.NewS3(storage.S3Options{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: string(cfg.S3SecretKey),
+			PathStyle: cfg.S3PathStyle,
+			PublicURL: cfg.PublicURL,
+		})
+	}
+
+	log.Fatalf("storage init error: %s: %s", storage.ErrUnknownDriver, cfg.Driver)
+	return nil
+}
+
+// Storage of the objects readable only through the app, like data
+// export archives. It's kept apart from the public storage, so such
+// objects are never served by the public URL
+func (a *App) InitPrivateStorage() storage.Objects {
+	log.Infof("Setting up %s private storage", a.cfg.Storage.Driver)
+
+	cfg := a.cfg.Storage
+
+	switch cfg.Driver {
+	case storage.DriverLocal:
+		return storage.NewLocal(cfg.PrivateDir, "")
+	case storage.DriverS3:
+		return storage.NewS3(storage.S3Options{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3PrivateBucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: string(cfg.S3SecretKey),
+			PathStyle: cfg.S3PathStyle,
+		})
+	}
+
+	log.Fatalf("private storage init error: %s: %s", storage.ErrUnknownDriver, cfg.Driver)
+	return nil
+}
+
+func (a *App) InitMailer() mail.Mailer {
+	log.Infof("Setting up %s mailer", a.cfg.Mail.Driver)
+
+	cfg := a.cfg.Mail
+
+	switch cfg.Driver {
+	case mail.DriverLog:
+		return mail.NewLog()
+	case mail.DriverSMTP:
+		return mail.NewSMTP(mail.SMTPOptions{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: string(cfg.SMTPPassword),
+			From:     cfg.From,
+		})
+	}
+
+	log.Fatalf("mailer init error: %s: %s", mail.ErrUnknownDriver, cfg.Driver)
+	return nil
+}
+
+// Sets up events bus, Postgres one is listening until context is canceled
+func (a *App) InitEvents(ctx context.Context, db database.Instance) event.PubSub {
+	log.Infof("Setting up %s events bus", a.cfg.Events.Driver)
+
+	switch a.cfg.Events.Driver {
+	case event.DriverLocal:
+		return event.NewBus()
+	case event.DriverPostgres:
+		if db == nil {
+			log.Fatalf("events bus init error: %s driver requires postgres database", event.DriverPostgres)
+		}
+
+		bus, err := event.NewPostgresBus(db, string(a.cfg.Database.Conn), a.cfg.Events.Channel)
+		if err != nil {
+			log.Fatalf("events bus init error: %s", err)
+		}
+
+		go bus.Run(ctx)
+
+		return bus
+	}
+
+	log.Fatalf("events bus init error: %s: %s", event.ErrUnknownDriver, a.cfg.Events.Driver)
+	return nil
+}
+
+// Starts background jobs, which are stopped on context cancel.
+// Returns func waiting for the jobs to stop
+func (a *App) RunJobs(
+	ctx context.Context,
+	repos repo.Repositories,
+	uow repo.UnitOfWork,
+	st storage.Storage,
+	pst storage.Objects,
+	bus event.PubSub,
+	ks manager.SigningKeySet,
+) func() {
+	log.Info("Starting background jobs")
+
+	var wg sync.WaitGroup
+
+	am := manager.NewAvatarProcessor(&a.cfg.Avatar, st)
+
+	deletion := job.NewAccountDeletion(&a.cfg.Users, repos.Users, uow, am, pst, bus)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		deletion.Run(ctx)
+	}()
+
+	exports := job.NewDataExports(
+		&a.cfg.Export,
+		repos.DataExports,
+		repos.Users,
+		repos.Sessions,
+		repos.PasswordHistory,
+		repos.AuditEvents,
+		st,
+		pst,
+		bus,
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		exports.Run(ctx)
+	}()
+
+	keys := job.NewSigningKeys(repos.SigningKeys, ks, bus)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		keys.Run(ctx)
+	}()
+
+	return wg.Wait
 }
@@ -0,0 +1,27 @@
+package app
+
+import (
+	"testing"
+	model "wildproject/internal/app/domain/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestBodyLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		maxSize int64
+		want    int
+	}{
+		{"avatar above the default limit", 5 * 1024 * 1024, 5*1024*1024 + multipartOverhead},
+		{"small avatar keeps the default limit", 1024, fiber.DefaultBodyLimit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := bodyLimit(&model.AvatarConfig{MaxSize: tt.maxSize}); got != tt.want {
+				t.Fatalf("expected %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
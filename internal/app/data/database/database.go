@@ -1,11 +1,19 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 )
 
+const (
+	DriverPostgres = "postgres"
+	// Keeps all the data in the process memory, for demos and development
+	DriverMemory = "memory"
+)
+
 var (
+	ErrUnknownDriver = errors.New("unknown database driver")
 	ErrAlreadyOpened = errors.New("cannot open: database connection already opened")
 	ErrNotOpened     = errors.New("cannot close: no opened database connections found")
 )
@@ -16,7 +24,17 @@ type Database interface {
 	Close() error
 }
 
-type Instance interface {
+// Runs queries either on the connection pool or in a transaction
+type Executor interface {
 	Query(query string, args ...any) (*sql.Rows, error)
 	QueryRow(query string, args ...any) *sql.Row
+	Exec(query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+type Instance interface {
+	Executor
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
 }
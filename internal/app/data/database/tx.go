@@ -0,0 +1,30 @@
+package database
+
+import (
+	"context"
+	"fmt"
+)
+
+// Runs "fn" in a transaction, which is committed if "fn" returns nil
+// and rolled back otherwise. If "ex" is already a transaction, "fn" joins it
+func InTx(ctx context.Context, ex Executor, fn func(tx Executor) error) error {
+	db, ok := ex.(Instance)
+	if !ok {
+		return fn(ex)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%w, rollback error: %s", err, rbErr)
+		}
+
+		return err
+	}
+
+	return tx.Commit()
+}
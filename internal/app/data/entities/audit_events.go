@@ -0,0 +1,12 @@
+package entity
+
+type AuditEvent struct {
+	EventID   int64
+	EventType string
+	ActorID   string
+	TargetID  string
+	IP        string
+	Uagent    string
+	Metadata  map[string]string
+	CreatedAt string
+}
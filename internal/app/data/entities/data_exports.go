@@ -0,0 +1,15 @@
+package entity
+
+import "database/sql"
+
+type DataExport struct {
+	ExportID      string
+	UserID        string
+	Status        string
+	Format        string
+	ObjectKey     string
+	DownloadToken string
+	Error         string
+	ExpiresAt     sql.NullString
+	CreatedAt     string
+}
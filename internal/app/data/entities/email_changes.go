@@ -0,0 +1,11 @@
+package entity
+
+type EmailChange struct {
+	ChangeID     string
+	UserID       string
+	Email        string
+	ConfirmToken string
+	CancelToken  string
+	ExpiresAt    string
+	CreatedAt    string
+}
@@ -0,0 +1,8 @@
+package entity
+
+type PasswordHistory struct {
+	HistoryID    int
+	UserID       string
+	PasswordHash string
+	CreatedAt    string
+}
@@ -1,5 +1,7 @@
 package entity
 
+import "time"
+
 type RefreshSession struct {
 	SessionID    int
 	RefreshToken string
@@ -7,6 +9,36 @@ type RefreshSession struct {
 	UserID       string
 	Uagent       string
 	Fprint       string
+	Device       SessionDevice
 	ExpiresAt    string
 	CreatedAt    string
+	// Set only by the page query
+	IsCurrent bool
+}
+
+// Client info parsed from the user agent
+type SessionDevice struct {
+	Browser        string
+	BrowserVersion string
+	OS             string
+	OSVersion      string
+	Type           string
+	Model          string
+	AppVersion     string
+}
+
+type SessionsFilter struct {
+	UserID string
+	// Session the request is made with, marked as current
+	CurrentSessionID int
+	// Either "active", "expired" or empty for both
+	Status string
+	// Browser, os or device type, ignoring case
+	Device       string
+	CreatedAfter *time.Time
+	// Id of the last session of the previous page, 0 for the first page
+	Cursor int
+	Desc   bool
+	// 0 means no limit
+	Limit int
 }
@@ -0,0 +1,7 @@
+package entity
+
+type SigningKey struct {
+	KeyID     string
+	Secret    []byte
+	CreatedAt string
+}
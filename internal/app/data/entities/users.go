@@ -1,16 +1,24 @@
 package entity
 
+import "database/sql"
+
 type User struct {
 	ID           string
 	Email        string
 	PasswordHash string
 	CreatedAt    string
 	UpdatetdAt   string
+	DeleteAfter  sql.NullString
 }
 
 type UserDetailed struct {
 	User
-	SexID    int
-	Name     string
-	ImageURL string
+	SexID         int
+	Name          string
+	ImageVariants []ImageVariant
+}
+
+type ImageVariant struct {
+	Size int    `json:"size"`
+	URL  string `json:"url"`
 }
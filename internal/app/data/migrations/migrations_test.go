@@ -0,0 +1,139 @@
+package migration
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+func testFile(content string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(content)}
+}
+
+func TestLoadOrdersByVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0010_tenth.up.sql":    testFile("CREATE TABLE tenth ();"),
+		"0010_tenth.down.sql":  testFile("DROP TABLE tenth;"),
+		"0002_second.up.sql":   testFile("CREATE TABLE second ();"),
+		"0002_second.down.sql": testFile("DROP TABLE second;"),
+		"0001_first.up.sql":    testFile("CREATE TABLE first ();"),
+		"0001_first.down.sql":  testFile("DROP TABLE first;"),
+	}
+
+	migrations, err := Load(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"0001_first", "0002_second", "0010_tenth"}
+
+	if len(migrations) != len(want) {
+		t.Fatalf("expected %d migrations, got %d", len(want), len(migrations))
+	}
+
+	for i, m := range migrations {
+		if m.String() != want[i] {
+			t.Fatalf("expected %s at %d, got %s", want[i], i, m)
+		}
+	}
+
+	first := migrations[0]
+	if first.Up != "CREATE TABLE first ();" || first.Down != "DROP TABLE first;" {
+		t.Fatalf("unexpected scripts of %s: %q, %q", first, first.Up, first.Down)
+	}
+}
+
+func TestLoadChecksumsUpScript(t *testing.T) {
+	load := func(up, down string) Migration {
+		migrations, err := Load(fstest.MapFS{
+			"0001_first.up.sql":   testFile(up),
+			"0001_first.down.sql": testFile(down),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return migrations[0]
+	}
+
+	m := load("CREATE TABLE first ();", "DROP TABLE first;")
+
+	sum := sha256.Sum256([]byte("CREATE TABLE first ();"))
+	if m.Checksum != hex.EncodeToString(sum[:]) {
+		t.Fatalf("unexpected checksum %s", m.Checksum)
+	}
+
+	// Down script may be fixed after the migration is applied
+	if load("CREATE TABLE first ();", "DROP TABLE IF EXISTS first;").Checksum != m.Checksum {
+		t.Fatal("expected checksum to ignore down script")
+	}
+
+	if load("CREATE TABLE first (id int);", "DROP TABLE first;").Checksum == m.Checksum {
+		t.Fatal("expected checksum to change with up script")
+	}
+}
+
+func TestLoadRejectsInvalidFiles(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+		want error
+	}{
+		{
+			"missing down",
+			fstest.MapFS{"0001_first.up.sql": testFile("CREATE TABLE first ();")},
+			ErrMissingDown,
+		},
+		{
+			"missing up",
+			fstest.MapFS{"0001_first.down.sql": testFile("DROP TABLE first;")},
+			ErrMissingUp,
+		},
+		{
+			"empty down",
+			fstest.MapFS{
+				"0001_first.up.sql":   testFile("CREATE TABLE first ();"),
+				"0001_first.down.sql": testFile(""),
+			},
+			ErrMissingDown,
+		},
+		{
+			"duplicate version",
+			fstest.MapFS{
+				"0001_first.up.sql":    testFile("CREATE TABLE first ();"),
+				"0001_first.down.sql":  testFile("DROP TABLE first;"),
+				"0001_second.up.sql":   testFile("CREATE TABLE second ();"),
+				"0001_second.down.sql": testFile("DROP TABLE second;"),
+			},
+			ErrDuplicateVersion,
+		},
+		{
+			"invalid name",
+			fstest.MapFS{"first.sql": testFile("CREATE TABLE first ();")},
+			ErrInvalidFileName,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Load(tt.fsys); !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestEmbeddedVersionsAreSequential(t *testing.T) {
+	migrations, err := Embedded()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Fatalf("expected version %d, got %s", i+1, m)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"wildproject/internal/app/data/database"
+	query "wildproject/internal/app/data/queries"
+)
+
+// Key of the advisory lock guarding migrations, an arbitrary constant
+const lockKey int64 = 0x77696c646d6967
+
+var (
+	ErrChecksumMismatch = errors.New("applied migration differs from the embedded one")
+	ErrUnknownVersion   = errors.New("database has migration unknown to this binary")
+	ErrAlreadyMigrated  = errors.New("database already has applied migrations")
+	// In-memory database has no instance, its schema is defined by the code
+	ErrNoDatabase = errors.New("migrations require postgres database")
+)
+
+// Migration recorded in the schema_migrations table
+type Applied struct {
+	Version   int
+	Name      string
+	Checksum  string
+	AppliedAt string
+}
+
+type Status struct {
+	Migration
+	// Empty if the migration is pending
+	AppliedAt string
+}
+
+// Applies migrations one by one, every migration runs in its own
+// transaction holding the advisory lock, so concurrently starting
+// instances never apply the same migration twice
+type Migrator struct {
+	db         database.Instance
+	migrations []Migration
+}
+
+func NewMigrator(db database.Instance, migrations []Migration) *Migrator {
+	return &Migrator{db, migrations}
+}
+
+// Applies all the pending migrations in version order.
+//
+// Returns applied migrations, which are also returned on error
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	done := make([]Migration, 0)
+
+	for {
+		var next *Migration
+
+		err := m.locked(ctx, func(tx database.Executor, applied map[int]Applied) error {
+			for _, mig := range m.migrations {
+				if _, ok := applied[mig.Version]; ok {
+					continue
+				}
+
+				if _, err := tx.ExecContext(ctx, mig.Up); err != nil {
+					return fmt.Errorf("apply %s: %w", mig, err)
+				}
+
+				_, err := tx.ExecContext(
+					ctx, query.CreateSchemaMigration, mig.Version, mig.Name, mig.Checksum,
+				)
+				if err != nil {
+					return err
+				}
+
+				next = &mig
+				return nil
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return done, err
+		}
+
+		if next == nil {
+			return done, nil
+		}
+
+		done = append(done, *next)
+	}
+}
+
+// Reverts up to "steps" latest applied migrations.
+//
+// Returns reverted migrations, which are also returned on error
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	done := make([]Migration, 0, steps)
+
+	for range steps {
+		var last *Migration
+
+		err := m.locked(ctx, func(tx database.Executor, applied map[int]Applied) error {
+			for i := len(m.migrations) - 1; i >= 0; i-- {
+				mig := m.migrations[i]
+
+				if _, ok := applied[mig.Version]; !ok {
+					continue
+				}
+
+				if _, err := tx.ExecContext(ctx, mig.Down); err != nil {
+					return fmt.Errorf("revert %s: %w", mig, err)
+				}
+
+				if _, err := tx.ExecContext(ctx, query.DeleteSchemaMigration, mig.Version); err != nil {
+					return err
+				}
+
+				last = &mig
+				return nil
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return done, err
+		}
+
+		if last == nil {
+			break
+		}
+
+		done = append(done, *last)
+	}
+
+	return done, nil
+}
+
+// Records migrations up to "version" as applied without running them.
+// Adopts databases created before the migrations, whose schema already
+// matches these versions
+//
+// Returns recorded migrations
+func (m *Migrator) Baseline(ctx context.Context, version int) ([]Migration, error) {
+	done := make([]Migration, 0)
+
+	err := m.locked(ctx, func(tx database.Executor, applied map[int]Applied) error {
+		if len(applied) > 0 {
+			return ErrAlreadyMigrated
+		}
+
+		for _, mig := range m.migrations {
+			if mig.Version > version {
+				break
+			}
+
+			_, err := tx.ExecContext(
+				ctx, query.CreateSchemaMigration, mig.Version, mig.Name, mig.Checksum,
+			)
+			if err != nil {
+				return err
+			}
+
+			done = append(done, mig)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return done, nil
+}
+
+// Returns all the known migrations with their apply time. Read only:
+// neither takes the lock nor creates the migrations table, all the
+// migrations are pending if there is no table yet
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	if m.db == nil {
+		return nil, ErrNoDatabase
+	}
+
+	var exists bool
+	if err := m.db.QueryRowContext(ctx, query.SchemaMigrationsTableExists).Scan(&exists); err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]Applied)
+
+	if exists {
+		var err error
+		if applied, err = m.applied(ctx, m.db); err != nil {
+			return nil, err
+		}
+
+		if err := m.verify(applied); err != nil {
+			return nil, err
+		}
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		statuses = append(statuses, Status{
+			Migration: mig,
+			AppliedAt: applied[mig.Version].AppliedAt,
+		})
+	}
+
+	return statuses, nil
+}
+
+// Runs "fn" in a transaction holding the migrations lock. Applied
+// migrations are verified against the known ones before "fn" is called
+func (m *Migrator) locked(
+	ctx context.Context,
+	fn func(tx database.Executor, applied map[int]Applied) error,
+) error {
+	if m.db == nil {
+		return ErrNoDatabase
+	}
+
+	return database.InTx(ctx, m.db, func(tx database.Executor) error {
+		if _, err := tx.ExecContext(ctx, query.LockSchemaMigrations, lockKey); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, query.CreateSchemaMigrationsTable); err != nil {
+			return err
+		}
+
+		applied, err := m.applied(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		if err := m.verify(applied); err != nil {
+			return err
+		}
+
+		return fn(tx, applied)
+	})
+}
+
+func (m *Migrator) applied(ctx context.Context, db database.Executor) (map[int]Applied, error) {
+	rows, err := db.QueryContext(ctx, query.FindSchemaMigrations)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]Applied)
+
+	for rows.Next() {
+		var a Applied
+
+		if err := rows.Scan(&a.Version, &a.Name, &a.Checksum, &a.AppliedAt); err != nil {
+			return nil, err
+		}
+
+		applied[a.Version] = a
+	}
+
+	return applied, rows.Err()
+}
+
+// Checks that every applied migration is known and was not changed
+func (m *Migrator) verify(applied map[int]Applied) error {
+	known := make(map[int]Migration, len(m.migrations))
+	for _, mig := range m.migrations {
+		known[mig.Version] = mig
+	}
+
+	for version, a := range applied {
+		mig, ok := known[version]
+		if !ok {
+			return fmt.Errorf("%w: %04d_%s", ErrUnknownVersion, a.Version, a.Name)
+		}
+
+		if mig.Checksum != a.Checksum {
+			return fmt.Errorf("%w: %s", ErrChecksumMismatch, mig)
+		}
+	}
+
+	return nil
+}
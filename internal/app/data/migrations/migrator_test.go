@@ -0,0 +1,365 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"testing/fstest"
+	"time"
+	query "wildproject/internal/app/data/queries"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	_ "github.com/lib/pq"
+)
+
+// Postgres tests run only if the variable holds connection string
+// of a disposable database, they work in a schema of their own
+const testDatabaseEnv = "TEST_DATABASE_CONN_STRING"
+
+func testMigrations(t *testing.T) []Migration {
+	migrations, err := Load(fstest.MapFS{
+		"0001_first.up.sql":    testFile("CREATE TABLE first ();"),
+		"0001_first.down.sql":  testFile("DROP TABLE first;"),
+		"0002_second.up.sql":   testFile("CREATE TABLE second ();"),
+		"0002_second.down.sql": testFile("DROP TABLE second;"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return migrations
+}
+
+func newMockMigrator(t *testing.T) (*Migrator, sqlmock.Sqlmock, []Migration) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+
+		db.Close()
+	})
+
+	migrations := testMigrations(t)
+
+	return NewMigrator(db, migrations), mock, migrations
+}
+
+// Expects the transaction taking the lock and reading applied migrations
+func expectLocked(mock sqlmock.Sqlmock, applied ...Migration) {
+	mock.ExpectBegin()
+	mock.ExpectExec(query.LockSchemaMigrations).WithArgs(lockKey).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(query.CreateSchemaMigrationsTable).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	rows := sqlmock.NewRows([]string{"version", "name", "checksum", "applied_at"})
+	for _, m := range applied {
+		rows.AddRow(m.Version, m.Name, m.Checksum, "2024-01-01T00:00:00Z")
+	}
+
+	mock.ExpectQuery(query.FindSchemaMigrations).WillReturnRows(rows)
+}
+
+func expectVersions(t *testing.T, got []Migration, want ...int) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("expected versions %v, got %v", want, got)
+	}
+
+	for i, m := range got {
+		if m.Version != want[i] {
+			t.Fatalf("expected versions %v, got %v", want, got)
+		}
+	}
+}
+
+func TestMigratorUpAppliesPending(t *testing.T) {
+	m, mock, ms := newMockMigrator(t)
+
+	expectLocked(mock, ms[0])
+	mock.ExpectExec(ms[1].Up).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(query.CreateSchemaMigration).
+		WithArgs(ms[1].Version, ms[1].Name, ms[1].Checksum).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	expectLocked(mock, ms...)
+	mock.ExpectCommit()
+
+	applied, err := m.Up(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectVersions(t, applied, 2)
+}
+
+func TestMigratorUpStopsOnFailure(t *testing.T) {
+	m, mock, ms := newMockMigrator(t)
+
+	expectLocked(mock, ms[0])
+	mock.ExpectExec(ms[1].Up).WillReturnError(errors.New("syntax error"))
+	mock.ExpectRollback()
+
+	applied, err := m.Up(context.Background())
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	expectVersions(t, applied)
+}
+
+func TestMigratorRejectsUnknownOrChangedMigrations(t *testing.T) {
+	changed := testMigrations(t)[0]
+	changed.Checksum = "changed"
+
+	unknown := Migration{Version: 3, Name: "third", Checksum: "third"}
+
+	tests := []struct {
+		name    string
+		applied Migration
+		want    error
+	}{
+		{"changed", changed, ErrChecksumMismatch},
+		{"unknown", unknown, ErrUnknownVersion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, mock, _ := newMockMigrator(t)
+
+			expectLocked(mock, tt.applied)
+			mock.ExpectRollback()
+
+			if _, err := m.Up(context.Background()); !errors.Is(err, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestMigratorDownRevertsLatest(t *testing.T) {
+	m, mock, ms := newMockMigrator(t)
+
+	expectLocked(mock, ms...)
+	mock.ExpectExec(ms[1].Down).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(query.DeleteSchemaMigration).
+		WithArgs(ms[1].Version).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	reverted, err := m.Down(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectVersions(t, reverted, 2)
+}
+
+func TestMigratorBaseline(t *testing.T) {
+	t.Run("records up to version", func(t *testing.T) {
+		m, mock, ms := newMockMigrator(t)
+
+		expectLocked(mock)
+		mock.ExpectExec(query.CreateSchemaMigration).
+			WithArgs(ms[0].Version, ms[0].Name, ms[0].Checksum).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		recorded, err := m.Baseline(context.Background(), 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectVersions(t, recorded, 1)
+	})
+
+	t.Run("rejects migrated database", func(t *testing.T) {
+		m, mock, ms := newMockMigrator(t)
+
+		expectLocked(mock, ms[0])
+		mock.ExpectRollback()
+
+		if _, err := m.Baseline(context.Background(), 2); !errors.Is(err, ErrAlreadyMigrated) {
+			t.Fatalf("expected ErrAlreadyMigrated, got %v", err)
+		}
+	})
+}
+
+func TestMigratorStatusIsReadOnly(t *testing.T) {
+	m, mock, ms := newMockMigrator(t)
+
+	// Neither the lock nor the table is created
+	mock.ExpectQuery(query.SchemaMigrationsTableExists).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	statuses, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(statuses) != len(ms) {
+		t.Fatalf("expected %d statuses, got %d", len(ms), len(statuses))
+	}
+
+	for _, s := range statuses {
+		if s.AppliedAt != "" {
+			t.Fatalf("expected %s to be pending, got %s", s.Migration, s.AppliedAt)
+		}
+	}
+}
+
+// Memory database has no instance to migrate
+func TestMigratorWithoutDatabase(t *testing.T) {
+	ctx := context.Background()
+	m := NewMigrator(nil, testMigrations(t))
+
+	if _, err := m.Up(ctx); !errors.Is(err, ErrNoDatabase) {
+		t.Fatalf("Up: expected ErrNoDatabase, got %v", err)
+	}
+
+	if _, err := m.Down(ctx, 1); !errors.Is(err, ErrNoDatabase) {
+		t.Fatalf("Down: expected ErrNoDatabase, got %v", err)
+	}
+
+	if _, err := m.Baseline(ctx, 1); !errors.Is(err, ErrNoDatabase) {
+		t.Fatalf("Baseline: expected ErrNoDatabase, got %v", err)
+	}
+
+	if _, err := m.Status(ctx); !errors.Is(err, ErrNoDatabase) {
+		t.Fatalf("Status: expected ErrNoDatabase, got %v", err)
+	}
+}
+
+// Opens the test database with a single connection bound to a schema,
+// which is dropped after the test
+func openTestPostgres(t *testing.T) *sql.DB {
+	conn := os.Getenv(testDatabaseEnv)
+	if conn == "" {
+		t.Skipf("%s is not set", testDatabaseEnv)
+	}
+
+	db, err := sql.Open("postgres", conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	// Search path is set per connection
+	db.SetMaxOpenConns(1)
+
+	schema := fmt.Sprintf("migrations_test_%d", time.Now().UnixNano())
+
+	if _, err := db.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+	if _, err := db.Exec("SET search_path TO " + schema); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func TestMigratorPostgresUpAndDown(t *testing.T) {
+	ctx := context.Background()
+	db := openTestPostgres(t)
+
+	migrations, err := Embedded()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewMigrator(db, migrations)
+
+	applied, err := m.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(applied) != len(migrations) {
+		t.Fatalf("expected %d applied migrations, got %d", len(migrations), len(applied))
+	}
+
+	reverted, err := m.Down(ctx, len(migrations))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reverted) != len(migrations) {
+		t.Fatalf("expected %d reverted migrations, got %d", len(migrations), len(reverted))
+	}
+
+	// Down scripts must leave the schema the up scripts can run on again
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Databases created by the scripts preceding the migrations have
+// the schema of version 2 and are upgraded from it
+func TestMigratorPostgresUpgradesBaseline(t *testing.T) {
+	ctx := context.Background()
+	db := openTestPostgres(t)
+
+	migrations, err := Embedded()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mig := range migrations[:2] {
+		if _, err := db.Exec(mig.Up); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var userID string
+
+	err = db.QueryRow(`INSERT INTO users (email, password_hash) VALUES ('a@example.com', 'hash') RETURNING user_id`).
+		Scan(&userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = db.Exec(`INSERT INTO user_info (user_id, img_url) VALUES ($1, 'https://cdn.example.com/a.jpg')`, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewMigrator(db, migrations)
+
+	if _, err := m.Baseline(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var raw []byte
+	if err := db.QueryRow(`SELECT img_variants FROM user_info WHERE user_id = $1`, userID).Scan(&raw); err != nil {
+		t.Fatal(err)
+	}
+
+	var variants []struct {
+		Size int    `json:"size"`
+		URL  string `json:"url"`
+	}
+
+	if err := json.Unmarshal(raw, &variants); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(variants) != 1 || variants[0].URL != "https://cdn.example.com/a.jpg" {
+		t.Fatalf("expected avatar to be moved to variants, got %s", raw)
+	}
+}
@@ -0,0 +1,3 @@
+DROP TABLE user_info;
+DROP TABLE sex;
+DROP TABLE users;
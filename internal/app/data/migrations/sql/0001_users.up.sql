@@ -1,9 +1,3 @@
--- Active: 1710705105856@@127.0.0.1@5432@wildb-dev
-
-DROP TABLE IF EXISTS user_info;
-DROP TABLE IF EXISTS sex;
-DROP TABLE IF EXISTS users;
-
 CREATE TABLE users (
   user_id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
   email text NOT NULL UNIQUE,
@@ -1,6 +1,3 @@
--- Active: 1710705105856@@127.0.0.1@5432@wildb-dev
-DROP TABLE IF EXISTS refresh_sessions;
-
 CREATE TABLE refresh_sessions (
   session_id serial PRIMARY KEY,
   refresh_token uuid DEFAULT gen_random_uuid(),
@@ -0,0 +1,12 @@
+CREATE TABLE password_history (
+  history_id serial PRIMARY KEY,
+  user_id uuid NOT NULL
+    REFERENCES users (user_id)
+      ON DELETE CASCADE
+      ON UPDATE CASCADE,
+  password_hash text NOT NULL,
+  created_at timestamp with time zone NOT NULL DEFAULT current_timestamp
+);
+
+CREATE INDEX password_history_user_id_idx
+  ON password_history (user_id, created_at DESC);
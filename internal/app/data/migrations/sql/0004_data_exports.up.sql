@@ -0,0 +1,26 @@
+CREATE TABLE data_exports (
+  export_id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id uuid NOT NULL
+    REFERENCES users (user_id)
+      ON DELETE CASCADE
+      ON UPDATE CASCADE,
+  status text NOT NULL DEFAULT 'pending',
+  format text NOT NULL,
+  object_key text NOT NULL DEFAULT '',
+  download_token text NOT NULL DEFAULT '',
+  error text NOT NULL DEFAULT '',
+  expires_at timestamp with time zone,
+  started_at timestamp with time zone,
+  created_at timestamp with time zone NOT NULL DEFAULT current_timestamp
+);
+
+CREATE INDEX data_exports_user_id_idx ON data_exports (user_id);
+CREATE INDEX data_exports_status_idx ON data_exports (status, created_at);
+CREATE UNIQUE INDEX data_exports_download_token_idx
+  ON data_exports (download_token)
+  WHERE download_token <> '';
+-- Export is requested only if there is no active one, the index keeps
+-- concurrent requests from queueing two of them
+CREATE UNIQUE INDEX data_exports_user_id_active_idx
+  ON data_exports (user_id)
+  WHERE status IN ('pending', 'processing');
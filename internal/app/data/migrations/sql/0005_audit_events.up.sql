@@ -0,0 +1,34 @@
+-- Append-only log of security events. It has no references to users
+-- on purpose, so records outlive deleted accounts
+CREATE TABLE audit_events (
+  event_id bigserial PRIMARY KEY,
+  event_type text NOT NULL,
+  actor_id text NOT NULL DEFAULT '',
+  target_id text NOT NULL DEFAULT '',
+  ip text NOT NULL DEFAULT '',
+  user_agent text NOT NULL DEFAULT '',
+  metadata jsonb NOT NULL DEFAULT '{}',
+  created_at timestamp with time zone NOT NULL DEFAULT current_timestamp
+);
+
+CREATE INDEX audit_events_actor_id_idx ON audit_events (actor_id, event_id DESC);
+CREATE INDEX audit_events_target_id_idx ON audit_events (target_id, event_id DESC);
+
+-- Deleted accounts must not leave personal data behind, so the only
+-- update allowed is erasing ip, user agent and metadata of an event
+CREATE RULE audit_events_no_update AS
+  ON UPDATE TO audit_events
+  WHERE NOT (
+    NEW.event_id = OLD.event_id
+    AND NEW.event_type = OLD.event_type
+    AND NEW.actor_id = OLD.actor_id
+    AND NEW.target_id = OLD.target_id
+    AND NEW.created_at = OLD.created_at
+    AND NEW.ip = ''
+    AND NEW.user_agent = ''
+    AND NEW.metadata = '{}'
+  )
+  DO INSTEAD NOTHING;
+
+CREATE RULE audit_events_no_delete AS
+  ON DELETE TO audit_events DO INSTEAD NOTHING;
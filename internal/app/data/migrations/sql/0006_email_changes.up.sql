@@ -0,0 +1,16 @@
+-- Email change requests waiting for confirmation from the new address.
+-- Email is changed only after confirmation, the old address may cancel it
+CREATE TABLE email_changes (
+  change_id uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id uuid NOT NULL
+    REFERENCES users (user_id)
+      ON DELETE CASCADE
+      ON UPDATE CASCADE,
+  email text NOT NULL,
+  confirm_token text NOT NULL UNIQUE,
+  cancel_token text NOT NULL UNIQUE,
+  expires_at timestamp with time zone NOT NULL,
+  created_at timestamp with time zone NOT NULL DEFAULT current_timestamp
+);
+
+CREATE INDEX email_changes_user_id_idx ON email_changes (user_id);
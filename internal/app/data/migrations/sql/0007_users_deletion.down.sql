@@ -0,0 +1,3 @@
+ALTER TABLE users
+  DROP COLUMN delete_after,
+  DROP COLUMN deleted_at;
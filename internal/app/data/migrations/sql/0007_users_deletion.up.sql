@@ -0,0 +1,9 @@
+-- Accounts pending deletion are deleted or anonymized once their grace
+-- period is over, anonymized ones keep the row with deleted_at set
+ALTER TABLE users
+  ADD COLUMN delete_after timestamp with time zone,
+  ADD COLUMN deleted_at timestamp with time zone;
+
+CREATE INDEX users_delete_after_idx
+  ON users (delete_after)
+  WHERE delete_after IS NOT NULL AND deleted_at IS NULL;
@@ -0,0 +1,15 @@
+-- Only the largest variant is kept
+ALTER TABLE user_info
+  ADD COLUMN img_url text NOT NULL DEFAULT '';
+
+UPDATE user_info
+SET img_url = (
+  SELECT v ->> 'url'
+  FROM jsonb_array_elements(img_variants) AS v
+  ORDER BY (v ->> 'size')::int DESC
+  LIMIT 1
+)
+WHERE img_variants <> '[]';
+
+ALTER TABLE user_info
+  DROP COLUMN img_variants;
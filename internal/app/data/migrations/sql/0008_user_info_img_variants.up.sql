@@ -0,0 +1,11 @@
+-- Uploaded avatars are stored in several sizes. The single image of
+-- the earlier accounts becomes a variant of unknown size 0
+ALTER TABLE user_info
+  ADD COLUMN img_variants jsonb NOT NULL DEFAULT '[]';
+
+UPDATE user_info
+SET img_variants = jsonb_build_array(jsonb_build_object('size', 0, 'url', img_url))
+WHERE img_url <> '';
+
+ALTER TABLE user_info
+  DROP COLUMN img_url;
@@ -0,0 +1 @@
+DROP INDEX refresh_sessions_user_id_idx;
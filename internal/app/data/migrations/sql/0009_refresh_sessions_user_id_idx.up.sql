@@ -0,0 +1,3 @@
+-- Sessions list is paginated by id within the user's sessions
+CREATE INDEX refresh_sessions_user_id_idx
+  ON refresh_sessions (user_id, session_id);
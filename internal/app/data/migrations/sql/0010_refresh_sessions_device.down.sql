@@ -0,0 +1,8 @@
+ALTER TABLE refresh_sessions
+  DROP COLUMN browser,
+  DROP COLUMN browser_version,
+  DROP COLUMN os,
+  DROP COLUMN os_version,
+  DROP COLUMN device_type,
+  DROP COLUMN device_model,
+  DROP COLUMN app_version;
@@ -0,0 +1,11 @@
+-- Parsed from the user agent on creation. Sessions created earlier are
+-- left empty, they are parsed when read and stored once the sessions
+-- are filtered by device
+ALTER TABLE refresh_sessions
+  ADD COLUMN browser text NOT NULL DEFAULT '',
+  ADD COLUMN browser_version text NOT NULL DEFAULT '',
+  ADD COLUMN os text NOT NULL DEFAULT '',
+  ADD COLUMN os_version text NOT NULL DEFAULT '',
+  ADD COLUMN device_type text NOT NULL DEFAULT '',
+  ADD COLUMN device_model text NOT NULL DEFAULT '',
+  ADD COLUMN app_version text NOT NULL DEFAULT '';
@@ -0,0 +1,10 @@
+-- Roles granted to the users by the operators
+CREATE TABLE user_roles (
+  user_id uuid NOT NULL
+    REFERENCES users (user_id)
+      ON DELETE CASCADE
+      ON UPDATE CASCADE,
+  role text NOT NULL,
+  granted_at timestamp with time zone NOT NULL DEFAULT current_timestamp,
+  PRIMARY KEY (user_id, role)
+);
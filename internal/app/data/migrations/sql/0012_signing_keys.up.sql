@@ -0,0 +1,9 @@
+-- Keys the access tokens are signed with, the latest one signs the new
+-- tokens and the previous ones only verify the tokens issued before
+CREATE TABLE signing_keys (
+  key_id text PRIMARY KEY,
+  secret bytea NOT NULL,
+  created_at timestamp with time zone NOT NULL DEFAULT current_timestamp
+);
+
+CREATE INDEX signing_keys_created_at_idx ON signing_keys (created_at DESC);
@@ -0,0 +1,35 @@
+package query
+
+const (
+	CreateAuditEvent = `
+		INSERT INTO audit_events (
+			event_type, actor_id, target_id, ip, user_agent, metadata
+		)
+		VALUES ($1, $2, $3, $4, $5, $6);
+	`
+
+	// Events either made by the user or made to the user, newest first
+	FindAuditEventsByUserID = `
+		SELECT event_id, 
+			event_type, 
+			actor_id, 
+			target_id, 
+			ip, 
+			user_agent, 
+			metadata, 
+			created_at
+		FROM audit_events
+		WHERE (actor_id = $1 OR target_id = $1)
+			AND event_id < $2
+		ORDER BY event_id DESC
+		LIMIT $3;
+	`
+
+	// Erases personal data of the user's events, the rest is kept as
+	// the security record. It's the only update allowed by the table rules
+	AnonymizeAuditEventsByUserID = `
+		UPDATE audit_events
+		SET ip = '', user_agent = '', metadata = '{}'
+		WHERE actor_id = $1 OR target_id = $1;
+	`
+)
@@ -0,0 +1,142 @@
+package query
+
+const (
+	FindDataExportByID = `
+		SELECT export_id, 
+			user_id, 
+			status, 
+			format, 
+			object_key, 
+			download_token, 
+			error, 
+			expires_at, 
+			created_at
+		FROM data_exports
+		WHERE export_id = $1;
+	`
+
+	FindDataExportByToken = `
+		SELECT export_id, 
+			user_id, 
+			status, 
+			format, 
+			object_key, 
+			download_token, 
+			error, 
+			expires_at, 
+			created_at
+		FROM data_exports
+		WHERE download_token = $1;
+	`
+
+	FindActiveDataExport = `
+		SELECT export_id, 
+			user_id, 
+			status, 
+			format, 
+			object_key, 
+			download_token, 
+			error, 
+			expires_at, 
+			created_at
+		FROM data_exports
+		WHERE user_id = $1
+			AND status IN ('pending', 'processing')
+		ORDER BY created_at DESC
+		LIMIT 1;
+	`
+
+	FindReadyDataExportsByUserID = `
+		SELECT export_id, 
+			user_id, 
+			status, 
+			format, 
+			object_key, 
+			download_token, 
+			error, 
+			expires_at, 
+			created_at
+		FROM data_exports
+		WHERE user_id = $1
+			AND status = 'ready';
+	`
+
+	CreateDataExport = `
+		INSERT INTO data_exports (user_id, format)
+		VALUES ($1, $2)
+		RETURNING export_id;
+	`
+
+	// Concurrent workers never claim the same export
+	ClaimDataExport = `
+		UPDATE data_exports
+			SET status = 'processing',
+				started_at = current_timestamp
+		WHERE export_id = (
+			SELECT export_id
+			FROM data_exports
+			WHERE status = 'pending'
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING export_id, 
+			user_id, 
+			status, 
+			format, 
+			object_key, 
+			download_token, 
+			error, 
+			expires_at, 
+			created_at;
+	`
+
+	// Returns exports of the crashed workers back to the queue
+	RequeueStaleDataExports = `
+		UPDATE data_exports
+			SET status = 'pending',
+				started_at = NULL
+		WHERE status = 'processing'
+			AND started_at < $1;
+	`
+
+	CompleteDataExport = `
+		UPDATE data_exports
+			SET status = 'ready',
+				object_key = $1,
+				download_token = $2,
+				expires_at = $3
+		WHERE export_id = $4;
+	`
+
+	FailDataExport = `
+		UPDATE data_exports
+			SET status = 'failed',
+				error = $1
+		WHERE export_id = $2;
+	`
+
+	FindExpiredDataExports = `
+		SELECT export_id, 
+			user_id, 
+			status, 
+			format, 
+			object_key, 
+			download_token, 
+			error, 
+			expires_at, 
+			created_at
+		FROM data_exports
+		WHERE status = 'ready'
+			AND expires_at <= current_timestamp
+		LIMIT $1;
+	`
+
+	ExpireDataExport = `
+		UPDATE data_exports
+			SET status = 'expired',
+				object_key = '',
+				download_token = ''
+		WHERE export_id = $1;
+	`
+)
@@ -0,0 +1,43 @@
+package query
+
+const (
+	FindEmailChangeByConfirmToken = `
+		SELECT change_id,
+			user_id,
+			email,
+			confirm_token,
+			cancel_token,
+			expires_at,
+			created_at
+		FROM email_changes
+		WHERE confirm_token = $1;
+	`
+
+	FindEmailChangeByCancelToken = `
+		SELECT change_id,
+			user_id,
+			email,
+			confirm_token,
+			cancel_token,
+			expires_at,
+			created_at
+		FROM email_changes
+		WHERE cancel_token = $1;
+	`
+
+	CreateEmailChange = `
+		INSERT INTO email_changes (user_id, email, confirm_token, cancel_token, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING change_id;
+	`
+
+	DeleteEmailChange = `
+		DELETE FROM email_changes
+		WHERE change_id = $1;
+	`
+
+	DeleteEmailChangesByUserID = `
+		DELETE FROM email_changes
+		WHERE user_id = $1;
+	`
+)
@@ -0,0 +1,28 @@
+package query
+
+const (
+	FindPasswordHistory = `
+		SELECT history_id, user_id, password_hash, created_at
+		FROM password_history
+		WHERE user_id = $1
+		ORDER BY created_at DESC, history_id DESC
+		LIMIT $2;
+	`
+
+	CreatePasswordHistory = `
+		INSERT INTO password_history (user_id, password_hash)
+		VALUES ($1, $2);
+	`
+
+	PrunePasswordHistory = `
+		DELETE FROM password_history
+		WHERE user_id = $1
+			AND history_id NOT IN (
+				SELECT history_id
+				FROM password_history
+				WHERE user_id = $1
+				ORDER BY created_at DESC, history_id DESC
+				LIMIT $2
+			);
+	`
+)
@@ -0,0 +1,38 @@
+package query
+
+const (
+	CreateSchemaMigrationsTable = `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version integer PRIMARY KEY,
+			name text NOT NULL,
+			checksum text NOT NULL,
+			applied_at timestamp with time zone NOT NULL DEFAULT current_timestamp
+		);
+	`
+
+	SchemaMigrationsTableExists = `
+		SELECT to_regclass('schema_migrations') IS NOT NULL;
+	`
+
+	// Held until the end of the transaction, so concurrently starting
+	// instances apply migrations one by one
+	LockSchemaMigrations = `
+		SELECT pg_advisory_xact_lock($1);
+	`
+
+	FindSchemaMigrations = `
+		SELECT version, name, checksum, applied_at
+		FROM schema_migrations
+		ORDER BY version;
+	`
+
+	CreateSchemaMigration = `
+		INSERT INTO schema_migrations (version, name, checksum)
+		VALUES ($1, $2, $3);
+	`
+
+	DeleteSchemaMigration = `
+		DELETE FROM schema_migrations
+		WHERE version = $1;
+	`
+)
@@ -8,12 +8,60 @@ const (
 			user_id, 
 			user_agent, 
 			fingerprint, 
+			browser,
+			browser_version,
+			os,
+			os_version,
+			device_type,
+			device_model,
+			app_version,
 			expires_at, 
 			created_at
 		FROM refresh_sessions 
 		WHERE user_id = $1;
 	`
 
+	// Page of user's sessions, every filter is skipped if its parameter
+	// is empty. Device is matched against the parsed browser, os and
+	// device type. Sessions are ordered by "session_id", which follows
+	// creation order, so it is also used as the cursor
+	FindSessionsPage = `
+		SELECT session_id,
+			refresh_token,
+			access_token,
+			user_id,
+			user_agent,
+			fingerprint,
+			browser,
+			browser_version,
+			os,
+			os_version,
+			device_type,
+			device_model,
+			app_version,
+			expires_at,
+			created_at,
+			session_id = $2 AS is_current
+		FROM refresh_sessions
+		WHERE user_id = $1
+			AND (
+				$3 = ''
+				OR ($3 = 'active' AND expires_at > current_timestamp)
+				OR ($3 = 'expired' AND expires_at <= current_timestamp)
+			)
+			AND ($4 = '' OR lower($4) IN (lower(browser), lower(os), lower(device_type)))
+			AND ($5::timestamptz IS NULL OR created_at > $5)
+			AND (
+				$6 = 0
+				OR ($7 AND session_id < $6)
+				OR (NOT $7 AND session_id > $6)
+			)
+		ORDER BY
+			CASE WHEN $7 THEN session_id END DESC,
+			CASE WHEN NOT $7 THEN session_id END ASC
+		LIMIT NULLIF($8, 0);
+	`
+
 	FindSessionsByUserDevice = `
 		SELECT session_id, 
 			refresh_token, 
@@ -21,6 +69,13 @@ const (
 			user_id, 
 			user_agent, 
 			fingerprint, 
+			browser,
+			browser_version,
+			os,
+			os_version,
+			device_type,
+			device_model,
+			app_version,
 			expires_at, 
 			created_at
 		FROM refresh_sessions 
@@ -36,6 +91,13 @@ const (
 			user_id, 
 			user_agent, 
 			fingerprint, 
+			browser,
+			browser_version,
+			os,
+			os_version,
+			device_type,
+			device_model,
+			app_version,
 			expires_at, 
 			created_at
 		FROM refresh_sessions 
@@ -49,6 +111,13 @@ const (
 			user_id, 
 			user_agent, 
 			fingerprint, 
+			browser,
+			browser_version,
+			os,
+			os_version,
+			device_type,
+			device_model,
+			app_version,
 			expires_at, 
 			created_at
 		FROM refresh_sessions 
@@ -56,8 +125,20 @@ const (
 	`
 
 	CreateSession = `
-		INSERT INTO refresh_sessions (user_id, user_agent, fingerprint, expires_at) 
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO refresh_sessions (
+			user_id,
+			user_agent,
+			fingerprint,
+			browser,
+			browser_version,
+			os,
+			os_version,
+			device_type,
+			device_model,
+			app_version,
+			expires_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 		RETURNING session_id, refresh_token;
 	`
 
@@ -67,6 +148,18 @@ const (
 		WHERE session_id = $2;
 	`
 
+	SetSessionDevice = `
+		UPDATE refresh_sessions
+		SET browser = $1,
+			browser_version = $2,
+			os = $3,
+			os_version = $4,
+			device_type = $5,
+			device_model = $6,
+			app_version = $7
+		WHERE session_id = $8;
+	`
+
 	DropSession = `
 		DELETE FROM refresh_sessions
 		WHERE session_id = $1;
@@ -76,4 +169,9 @@ const (
 		DELETE FROM refresh_sessions
 		WHERE user_id = $1;
 	`
+
+	DropExpiredSessions = `
+		DELETE FROM refresh_sessions
+		WHERE expires_at <= current_timestamp;
+	`
 )
@@ -0,0 +1,29 @@
+package query
+
+const (
+	CreateSigningKey = `
+		INSERT INTO signing_keys (key_id, secret)
+		VALUES ($1, $2);
+	`
+
+	// Newest first, so the first one signs the tokens
+	FindSigningKeys = `
+		SELECT key_id,
+			secret,
+			created_at
+		FROM signing_keys
+		ORDER BY created_at DESC, key_id;
+	`
+
+	// Deletes keys replaced by a newer one before $1, the latest key
+	// is never deleted
+	DropSupersededSigningKeys = `
+		DELETE FROM signing_keys k
+		WHERE EXISTS (
+			SELECT 1
+			FROM signing_keys n
+			WHERE n.created_at > k.created_at
+				AND n.created_at <= $1
+		);
+	`
+)
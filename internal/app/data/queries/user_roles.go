@@ -0,0 +1,22 @@
+package query
+
+const (
+	// Roles the user already has are left as is
+	GrantUserRoles = `
+		INSERT INTO user_roles (user_id, role)
+		SELECT $1, unnest($2::text[])
+		ON CONFLICT (user_id, role) DO NOTHING;
+	`
+
+	FindUserRoles = `
+		SELECT role
+		FROM user_roles
+		WHERE user_id = $1
+		ORDER BY role;
+	`
+
+	DeleteUserRolesByUserID = `
+		DELETE FROM user_roles
+		WHERE user_id = $1;
+	`
+)
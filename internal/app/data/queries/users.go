@@ -2,15 +2,17 @@ package query
 
 const (
 	FindUserByID = `
-		SELECT user_id, email, password_hash, created_at, updated_at
+		SELECT user_id, email, password_hash, created_at, updated_at, delete_after
 		FROM users 
-		WHERE user_id = $1;
+		WHERE user_id = $1 
+			AND deleted_at IS NULL;
 	`
 
 	FindUserByEmail = `
-		SELECT user_id, email, password_hash, created_at, updated_at
+		SELECT user_id, email, password_hash, created_at, updated_at, delete_after
 		FROM users 
-		WHERE email = $1;
+		WHERE email = $1 
+			AND deleted_at IS NULL;
 	`
 
 	FindDetailedUserByID = `
@@ -20,13 +22,15 @@ const (
 			u.password_hash, 
 			u.created_at, 
 			u.updated_at,
+			u.delete_after,
 			ui.sex_id,
 			ui.name,
-			ui.img_url
+			ui.img_variants
 		FROM users AS u
 		INNER JOIN user_info AS ui
 			USING(user_id)
-		WHERE u.user_id = $1;
+		WHERE u.user_id = $1 
+			AND u.deleted_at IS NULL;
 	`
 
 	CountUsersByEmail = `
@@ -50,11 +54,10 @@ const (
 		RETURNING name;
 	`
 
-	UpdateUserImgURL = `
+	UpdateUserImgVariants = `
 		UPDATE user_info
-			SET img_url = $1
-		WHERE user_id = $2
-		RETURNING img_url;
+			SET img_variants = $1
+		WHERE user_id = $2;
 	`
 
 	UpdateUserEmail = `
@@ -76,4 +79,68 @@ const (
 			SET password_hash = $1
 		WHERE user_id = $2;
 	`
+
+	ScheduleUserDeletion = `
+		UPDATE users
+			SET delete_after = $1
+		WHERE user_id = $2;
+	`
+
+	CancelUserDeletion = `
+		UPDATE users
+			SET delete_after = NULL
+		WHERE user_id = $1
+			AND delete_after > current_timestamp;
+	`
+
+	FindUsersDueDeletion = `
+		SELECT user_id
+		FROM users
+		WHERE delete_after <= current_timestamp
+			AND deleted_at IS NULL
+		ORDER BY delete_after
+		LIMIT $1;
+	`
+
+	FindDetailedUserDueDeletion = `
+		SELECT 
+			u.user_id, 
+			u.email, 
+			u.password_hash, 
+			u.created_at, 
+			u.updated_at,
+			u.delete_after,
+			ui.sex_id,
+			ui.name,
+			ui.img_variants
+		FROM users AS u
+		INNER JOIN user_info AS ui
+			USING(user_id)
+		WHERE u.user_id = $1 
+			AND u.delete_after <= current_timestamp
+			AND u.deleted_at IS NULL
+		FOR UPDATE OF u;
+	`
+
+	AnonymizeUser = `
+		UPDATE users
+			SET email = user_id || '@deleted.invalid',
+				password_hash = '',
+				delete_after = NULL,
+				deleted_at = current_timestamp
+		WHERE user_id = $1;
+	`
+
+	AnonymizeUserInfo = `
+		UPDATE user_info
+			SET name = '',
+				img_variants = DEFAULT,
+				sex_id = DEFAULT
+		WHERE user_id = $1;
+	`
+
+	DeleteUser = `
+		DELETE FROM users
+		WHERE user_id = $1;
+	`
 )
@@ -0,0 +1,81 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+	"wildproject/internal/app/data/database"
+	entity "wildproject/internal/app/data/entities"
+	query "wildproject/internal/app/data/queries"
+)
+
+type AuditEvents struct {
+	db      database.Executor
+	timeout time.Duration
+}
+
+func NewAuditEvents(db database.Executor, timeout time.Duration) *AuditEvents {
+	return &AuditEvents{db, timeout}
+}
+
+func (a *AuditEvents) Create(ctx context.Context, e entity.AuditEvent) error {
+	ctx, cancel := withTimeout(ctx, a.timeout)
+	defer cancel()
+
+	metadata, err := json.Marshal(e.Metadata)
+	if err != nil {
+		return err
+	}
+
+	_, err = a.db.ExecContext(
+		ctx,
+		query.CreateAuditEvent,
+		e.EventType, e.ActorID, e.TargetID, e.IP, e.Uagent, metadata,
+	)
+
+	return err
+}
+
+// Returns up to "limit" user's events with id less than "before", newest first
+func (a *AuditEvents) FindAllByUserID(ctx context.Context, userID string, before int64, limit int) ([]entity.AuditEvent, error) {
+	ctx, cancel := withTimeout(ctx, a.timeout)
+	defer cancel()
+
+	rows, err := a.db.QueryContext(ctx, query.FindAuditEventsByUserID, userID, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]entity.AuditEvent, 0)
+
+	for rows.Next() {
+		var e entity.AuditEvent
+		var metadata []byte
+
+		err := rows.Scan(
+			&e.EventID, &e.EventType, &e.ActorID, &e.TargetID,
+			&e.IP, &e.Uagent, &metadata, &e.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(metadata, &e.Metadata); err != nil {
+			return nil, err
+		}
+
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
+
+// Erases ip, user agent and metadata of events made by or to the user
+func (a *AuditEvents) AnonymizeByUserID(ctx context.Context, userID string) error {
+	ctx, cancel := withTimeout(ctx, a.timeout)
+	defer cancel()
+
+	_, err := a.db.ExecContext(ctx, query.AnonymizeAuditEventsByUserID, userID)
+	return err
+}
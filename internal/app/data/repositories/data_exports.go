@@ -0,0 +1,170 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	"wildproject/internal/app/data/database"
+	entity "wildproject/internal/app/data/entities"
+	query "wildproject/internal/app/data/queries"
+
+	"github.com/lib/pq"
+)
+
+// Unique index allowing a single pending or processing export per user
+const activeExportConstraint = "data_exports_user_id_active_idx"
+
+var (
+	ErrActiveExportExists = errors.New("user already has active export")
+)
+
+type DataExports struct {
+	db      database.Executor
+	timeout time.Duration
+}
+
+func NewDataExports(db database.Executor, timeout time.Duration) *DataExports {
+	return &DataExports{db, timeout}
+}
+
+func (d *DataExports) FindByID(ctx context.Context, exportID string) (entity.DataExport, error) {
+	ctx, cancel := withTimeout(ctx, d.timeout)
+	defer cancel()
+
+	return d.scan(d.db.QueryRowContext(ctx, query.FindDataExportByID, exportID))
+}
+
+func (d *DataExports) FindByToken(ctx context.Context, token string) (entity.DataExport, error) {
+	ctx, cancel := withTimeout(ctx, d.timeout)
+	defer cancel()
+
+	return d.scan(d.db.QueryRowContext(ctx, query.FindDataExportByToken, token))
+}
+
+// Finds user's pending or processing export
+func (d *DataExports) FindActive(ctx context.Context, userID string) (entity.DataExport, error) {
+	ctx, cancel := withTimeout(ctx, d.timeout)
+	defer cancel()
+
+	return d.scan(d.db.QueryRowContext(ctx, query.FindActiveDataExport, userID))
+}
+
+// Returns "export_id" of created pending export or ErrActiveExportExists
+// if the user already has pending or processing one
+func (d *DataExports) Create(ctx context.Context, userID, format string) (string, error) {
+	ctx, cancel := withTimeout(ctx, d.timeout)
+	defer cancel()
+
+	var exportID string
+
+	err := d.db.QueryRowContext(ctx, query.CreateDataExport, userID, format).Scan(&exportID)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == pqUniqueViolation && pqErr.Constraint == activeExportConstraint {
+			return "", ErrActiveExportExists
+		}
+
+		return "", err
+	}
+
+	return exportID, nil
+}
+
+// Marks the oldest pending export as processing.
+//
+// Returns sql.ErrNoRows if there are no pending exports
+func (d *DataExports) Claim(ctx context.Context) (entity.DataExport, error) {
+	ctx, cancel := withTimeout(ctx, d.timeout)
+	defer cancel()
+
+	return d.scan(d.db.QueryRowContext(ctx, query.ClaimDataExport))
+}
+
+// Returns exports processed since before "startedBefore" back to pending
+func (d *DataExports) RequeueStale(ctx context.Context, startedBefore time.Time) error {
+	ctx, cancel := withTimeout(ctx, d.timeout)
+	defer cancel()
+
+	_, err := d.db.ExecContext(ctx, query.RequeueStaleDataExports, startedBefore)
+	return err
+}
+
+func (d *DataExports) Complete(ctx context.Context, exportID, objectKey, token string, expiresAt time.Time) error {
+	ctx, cancel := withTimeout(ctx, d.timeout)
+	defer cancel()
+
+	_, err := d.db.ExecContext(ctx, query.CompleteDataExport, objectKey, token, expiresAt, exportID)
+	return err
+}
+
+func (d *DataExports) Fail(ctx context.Context, exportID, reason string) error {
+	ctx, cancel := withTimeout(ctx, d.timeout)
+	defer cancel()
+
+	_, err := d.db.ExecContext(ctx, query.FailDataExport, reason, exportID)
+	return err
+}
+
+// Finds user's exports, which archives are stored
+func (d *DataExports) FindReadyByUserID(ctx context.Context, userID string) ([]entity.DataExport, error) {
+	ctx, cancel := withTimeout(ctx, d.timeout)
+	defer cancel()
+
+	return d.scanAll(d.db.QueryContext(ctx, query.FindReadyDataExportsByUserID, userID))
+}
+
+func (d *DataExports) FindExpired(ctx context.Context, limit int) ([]entity.DataExport, error) {
+	ctx, cancel := withTimeout(ctx, d.timeout)
+	defer cancel()
+
+	return d.scanAll(d.db.QueryContext(ctx, query.FindExpiredDataExports, limit))
+}
+
+func (d *DataExports) scanAll(rows *sql.Rows, err error) ([]entity.DataExport, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exports := make([]entity.DataExport, 0)
+
+	for rows.Next() {
+		export, err := d.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		exports = append(exports, export)
+	}
+
+	return exports, rows.Err()
+}
+
+func (d *DataExports) Expire(ctx context.Context, exportID string) error {
+	ctx, cancel := withTimeout(ctx, d.timeout)
+	defer cancel()
+
+	_, err := d.db.ExecContext(ctx, query.ExpireDataExport, exportID)
+	return err
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func (d *DataExports) scan(row scanner) (entity.DataExport, error) {
+	var export entity.DataExport
+
+	err := row.Scan(
+		&export.ExportID, &export.UserID, &export.Status, &export.Format,
+		&export.ObjectKey, &export.DownloadToken, &export.Error,
+		&export.ExpiresAt, &export.CreatedAt,
+	)
+
+	if err != nil {
+		return entity.DataExport{}, err
+	}
+
+	return export, nil
+}
@@ -0,0 +1,92 @@
+package repo
+
+import (
+	"context"
+	"time"
+	"wildproject/internal/app/data/database"
+	entity "wildproject/internal/app/data/entities"
+	query "wildproject/internal/app/data/queries"
+)
+
+type EmailChanges struct {
+	db      database.Executor
+	timeout time.Duration
+}
+
+func NewEmailChanges(db database.Executor, timeout time.Duration) *EmailChanges {
+	return &EmailChanges{db, timeout}
+}
+
+func (e *EmailChanges) FindByConfirmToken(ctx context.Context, token string) (entity.EmailChange, error) {
+	ctx, cancel := withTimeout(ctx, e.timeout)
+	defer cancel()
+
+	return e.scan(e.db.QueryRowContext(ctx, query.FindEmailChangeByConfirmToken, token))
+}
+
+func (e *EmailChanges) FindByCancelToken(ctx context.Context, token string) (entity.EmailChange, error) {
+	ctx, cancel := withTimeout(ctx, e.timeout)
+	defer cancel()
+
+	return e.scan(e.db.QueryRowContext(ctx, query.FindEmailChangeByCancelToken, token))
+}
+
+// Returns "change_id" of created email change
+func (e *EmailChanges) Create(
+	ctx context.Context,
+	userID, email, confirmToken, cancelToken string,
+	expiresAt time.Time,
+) (string, error) {
+	ctx, cancel := withTimeout(ctx, e.timeout)
+	defer cancel()
+
+	var changeID string
+
+	err := e.db.QueryRowContext(
+		ctx,
+		query.CreateEmailChange,
+		userID,
+		email,
+		confirmToken,
+		cancelToken,
+		expiresAt,
+	).Scan(&changeID)
+	if err != nil {
+		return "", err
+	}
+
+	return changeID, nil
+}
+
+func (e *EmailChanges) Delete(ctx context.Context, changeID string) error {
+	ctx, cancel := withTimeout(ctx, e.timeout)
+	defer cancel()
+
+	_, err := e.db.ExecContext(ctx, query.DeleteEmailChange, changeID)
+	return err
+}
+
+// Deletes all user's email changes, both pending and expired
+func (e *EmailChanges) DeleteAllByUserID(ctx context.Context, userID string) error {
+	ctx, cancel := withTimeout(ctx, e.timeout)
+	defer cancel()
+
+	_, err := e.db.ExecContext(ctx, query.DeleteEmailChangesByUserID, userID)
+	return err
+}
+
+func (e *EmailChanges) scan(row scanner) (entity.EmailChange, error) {
+	var change entity.EmailChange
+
+	err := row.Scan(
+		&change.ChangeID,
+		&change.UserID,
+		&change.Email,
+		&change.ConfirmToken,
+		&change.CancelToken,
+		&change.ExpiresAt,
+		&change.CreatedAt,
+	)
+
+	return change, err
+}
@@ -0,0 +1,223 @@
+package repo
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+	entity "wildproject/internal/app/data/entities"
+)
+
+var (
+	// Returned by the in-memory repos instead of the foreign key violation
+	ErrUserNotExists = errors.New("referenced user does not exist")
+)
+
+type memoryUser struct {
+	entity.UserDetailed
+	deleted bool
+}
+
+// Returns the time the user is deleted after, false if it isn't scheduled
+func (u *memoryUser) deleteAfter() (time.Time, bool) {
+	if !u.DeleteAfter.Valid {
+		return time.Time{}, false
+	}
+
+	after, err := time.Parse(time.RFC3339, u.DeleteAfter.String)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return after, true
+}
+
+type memoryExport struct {
+	entity.DataExport
+	startedAt time.Time
+	seq       int
+}
+
+type memorySigningKey struct {
+	entity.SigningKey
+	createdAt time.Time
+}
+
+type rwLocker interface {
+	sync.Locker
+	RLock()
+	RUnlock()
+}
+
+// Lock of the store copy a unit of work runs on, which is guarded
+// by the lock of the original store
+type noLock struct{}
+
+func (noLock) Lock()    {}
+func (noLock) Unlock()  {}
+func (noLock) RLock()   {}
+func (noLock) RUnlock() {}
+
+// Data of the in-memory repos. Repos share a single lock, so operations
+// touching several "tables", like cascading user deletion, are atomic
+type MemoryStore struct {
+	mu rwLocker
+
+	users        map[string]*memoryUser
+	sessions     map[int]*entity.RefreshSession
+	sessionSeq   int
+	history      []entity.PasswordHistory
+	historySeq   int
+	exports      map[string]*memoryExport
+	exportSeq    int
+	auditEvents  []entity.AuditEvent
+	auditSeq     int64
+	emailChanges map[string]*entity.EmailChange
+	roles        map[string][]string
+	signingKeys  []memorySigningKey
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		mu:           &sync.RWMutex{},
+		users:        make(map[string]*memoryUser),
+		sessions:     make(map[int]*entity.RefreshSession),
+		exports:      make(map[string]*memoryExport),
+		emailChanges: make(map[string]*entity.EmailChange),
+		roles:        make(map[string][]string),
+	}
+}
+
+// Builds repos keeping the data in the process memory, it is lost on exit
+func NewMemoryRepositories() Repositories {
+	return newMemoryRepositories(NewMemoryStore())
+}
+
+func newMemoryRepositories(s *MemoryStore) Repositories {
+	return Repositories{
+		Users:           NewMemoryUsers(s),
+		Sessions:        NewMemorySessions(s),
+		PasswordHistory: NewMemoryPasswordHistory(s),
+		DataExports:     NewMemoryDataExports(s),
+		AuditEvents:     NewMemoryAuditEvents(s),
+		EmailChanges:    NewMemoryEmailChanges(s),
+		UserRoles:       NewMemoryUserRoles(s),
+		SigningKeys:     NewMemorySigningKeys(s),
+	}
+}
+
+// Returns deep copy of the data, which is not guarded by any lock.
+// Must be called with the lock held
+func (s *MemoryStore) clone() *MemoryStore {
+	c := &MemoryStore{
+		mu:           noLock{},
+		users:        make(map[string]*memoryUser, len(s.users)),
+		sessions:     make(map[int]*entity.RefreshSession, len(s.sessions)),
+		sessionSeq:   s.sessionSeq,
+		history:      slices.Clone(s.history),
+		historySeq:   s.historySeq,
+		exports:      make(map[string]*memoryExport, len(s.exports)),
+		exportSeq:    s.exportSeq,
+		auditEvents:  slices.Clone(s.auditEvents),
+		auditSeq:     s.auditSeq,
+		emailChanges: make(map[string]*entity.EmailChange, len(s.emailChanges)),
+		roles:        make(map[string][]string, len(s.roles)),
+		signingKeys:  slices.Clone(s.signingKeys),
+	}
+
+	for id, u := range s.users {
+		user := *u
+		user.ImageVariants = slices.Clone(u.ImageVariants)
+		c.users[id] = &user
+	}
+
+	for id, session := range s.sessions {
+		session := *session
+		c.sessions[id] = &session
+	}
+
+	for id, e := range s.exports {
+		export := *e
+		c.exports[id] = &export
+	}
+
+	for id, change := range s.emailChanges {
+		change := *change
+		c.emailChanges[id] = &change
+	}
+
+	for id, roles := range s.roles {
+		c.roles[id] = slices.Clone(roles)
+	}
+
+	for i, e := range c.auditEvents {
+		c.auditEvents[i].Metadata = maps.Clone(e.Metadata)
+	}
+
+	return c
+}
+
+// Replaces the data with the one of "c" keeping the lock.
+// Must be called with the lock held
+func (s *MemoryStore) replace(c *MemoryStore) {
+	mu := s.mu
+	*s = *c
+	s.mu = mu
+}
+
+// Mirrors "ON DELETE CASCADE" of the tables referencing users.
+// Must be called with the write lock held
+func (s *MemoryStore) deleteUser(userID string) {
+	delete(s.users, userID)
+
+	for id, session := range s.sessions {
+		if session.UserID == userID {
+			delete(s.sessions, id)
+		}
+	}
+
+	for id, e := range s.exports {
+		if e.UserID == userID {
+			delete(s.exports, id)
+		}
+	}
+
+	s.deleteEmailChanges(userID)
+	delete(s.roles, userID)
+
+	s.history = deleteFunc(s.history, func(h entity.PasswordHistory) bool {
+		return h.UserID == userID
+	})
+}
+
+// Must be called with the write lock held
+func (s *MemoryStore) deleteEmailChanges(userID string) {
+	for id, c := range s.emailChanges {
+		if c.UserID == userID {
+			delete(s.emailChanges, id)
+		}
+	}
+}
+
+// Must be called with the lock held
+func (s *MemoryStore) userExists(userID string) bool {
+	_, ok := s.users[userID]
+	return ok
+}
+
+// Formats timestamps the same way as they are scanned from Postgres
+func memoryStamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func deleteFunc[T any](s []T, del func(T) bool) []T {
+	kept := s[:0]
+	for _, v := range s {
+		if !del(v) {
+			kept = append(kept, v)
+		}
+	}
+
+	return kept
+}
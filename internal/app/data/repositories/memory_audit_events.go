@@ -0,0 +1,75 @@
+package repo
+
+import (
+	"context"
+	"maps"
+	"time"
+	entity "wildproject/internal/app/data/entities"
+)
+
+type MemoryAuditEvents struct {
+	s *MemoryStore
+}
+
+func NewMemoryAuditEvents(s *MemoryStore) *MemoryAuditEvents {
+	return &MemoryAuditEvents{s}
+}
+
+func (a *MemoryAuditEvents) Create(ctx context.Context, e entity.AuditEvent) error {
+	a.s.mu.Lock()
+	defer a.s.mu.Unlock()
+
+	a.s.auditSeq++
+
+	e.EventID = a.s.auditSeq
+	e.Metadata = maps.Clone(e.Metadata)
+	e.CreatedAt = memoryStamp(time.Now())
+
+	if e.Metadata == nil {
+		e.Metadata = map[string]string{}
+	}
+
+	a.s.auditEvents = append(a.s.auditEvents, e)
+
+	return nil
+}
+
+// Returns events made by or to the user with id less than "before", newest first
+func (a *MemoryAuditEvents) FindAllByUserID(ctx context.Context, userID string, before int64, limit int) ([]entity.AuditEvent, error) {
+	a.s.mu.RLock()
+	defer a.s.mu.RUnlock()
+
+	events := make([]entity.AuditEvent, 0)
+
+	// Events are appended in the id order
+	for i := len(a.s.auditEvents) - 1; i >= 0 && len(events) < limit; i-- {
+		e := a.s.auditEvents[i]
+
+		if e.EventID >= before || (e.ActorID != userID && e.TargetID != userID) {
+			continue
+		}
+
+		e.Metadata = maps.Clone(e.Metadata)
+		events = append(events, e)
+	}
+
+	return events, nil
+}
+
+// Erases ip, user agent and metadata of events made by or to the user
+func (a *MemoryAuditEvents) AnonymizeByUserID(ctx context.Context, userID string) error {
+	a.s.mu.Lock()
+	defer a.s.mu.Unlock()
+
+	for i, e := range a.s.auditEvents {
+		if e.ActorID != userID && e.TargetID != userID {
+			continue
+		}
+
+		a.s.auditEvents[i].IP = ""
+		a.s.auditEvents[i].Uagent = ""
+		a.s.auditEvents[i].Metadata = map[string]string{}
+	}
+
+	return nil
+}
@@ -0,0 +1,226 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+	entity "wildproject/internal/app/data/entities"
+
+	"github.com/google/uuid"
+)
+
+// Export statuses, the same as in the data_exports table
+const (
+	exportPending    = "pending"
+	exportProcessing = "processing"
+	exportReady      = "ready"
+	exportFailed     = "failed"
+	exportExpired    = "expired"
+)
+
+type MemoryDataExports struct {
+	s *MemoryStore
+}
+
+func NewMemoryDataExports(s *MemoryStore) *MemoryDataExports {
+	return &MemoryDataExports{s}
+}
+
+func (d *MemoryDataExports) FindByID(ctx context.Context, exportID string) (entity.DataExport, error) {
+	d.s.mu.RLock()
+	defer d.s.mu.RUnlock()
+
+	e, ok := d.s.exports[exportID]
+	if !ok {
+		return entity.DataExport{}, sql.ErrNoRows
+	}
+
+	return e.DataExport, nil
+}
+
+func (d *MemoryDataExports) FindByToken(ctx context.Context, token string) (entity.DataExport, error) {
+	exports := d.find(0, func(e *memoryExport) bool {
+		return e.DownloadToken == token
+	})
+
+	if len(exports) == 0 {
+		return entity.DataExport{}, sql.ErrNoRows
+	}
+
+	return exports[0].DataExport, nil
+}
+
+// Returns the latest pending or processing export of the user
+func (d *MemoryDataExports) FindActive(ctx context.Context, userID string) (entity.DataExport, error) {
+	exports := d.find(0, func(e *memoryExport) bool {
+		return e.UserID == userID && (e.Status == exportPending || e.Status == exportProcessing)
+	})
+
+	if len(exports) == 0 {
+		return entity.DataExport{}, sql.ErrNoRows
+	}
+
+	return exports[len(exports)-1].DataExport, nil
+}
+
+// Returns "export_id" of created pending export or ErrActiveExportExists
+// if the user already has pending or processing one
+func (d *MemoryDataExports) Create(ctx context.Context, userID, format string) (string, error) {
+	d.s.mu.Lock()
+	defer d.s.mu.Unlock()
+
+	if !d.s.userExists(userID) {
+		return "", ErrUserNotExists
+	}
+
+	for _, e := range d.s.exports {
+		if e.UserID == userID && (e.Status == exportPending || e.Status == exportProcessing) {
+			return "", ErrActiveExportExists
+		}
+	}
+
+	d.s.exportSeq++
+	exportID := uuid.NewString()
+
+	d.s.exports[exportID] = &memoryExport{
+		DataExport: entity.DataExport{
+			ExportID:  exportID,
+			UserID:    userID,
+			Status:    exportPending,
+			Format:    format,
+			CreatedAt: memoryStamp(time.Now()),
+		},
+		seq: d.s.exportSeq,
+	}
+
+	return exportID, nil
+}
+
+// Marks the oldest pending export as processing.
+//
+// Returns sql.ErrNoRows if there are no pending exports
+func (d *MemoryDataExports) Claim(ctx context.Context) (entity.DataExport, error) {
+	d.s.mu.Lock()
+	defer d.s.mu.Unlock()
+
+	var oldest *memoryExport
+	for _, e := range d.s.exports {
+		if e.Status == exportPending && (oldest == nil || e.seq < oldest.seq) {
+			oldest = e
+		}
+	}
+
+	if oldest == nil {
+		return entity.DataExport{}, sql.ErrNoRows
+	}
+
+	oldest.Status = exportProcessing
+	oldest.startedAt = time.Now()
+
+	return oldest.DataExport, nil
+}
+
+// Returns exports of the crashed workers back to the queue
+func (d *MemoryDataExports) RequeueStale(ctx context.Context, startedBefore time.Time) error {
+	return d.update(func(e *memoryExport) {
+		if e.Status == exportProcessing && e.startedAt.Before(startedBefore) {
+			e.Status = exportPending
+			e.startedAt = time.Time{}
+		}
+	})
+}
+
+func (d *MemoryDataExports) Complete(ctx context.Context, exportID, objectKey, token string, expiresAt time.Time) error {
+	return d.update(func(e *memoryExport) {
+		if e.ExportID == exportID {
+			e.Status = exportReady
+			e.ObjectKey = objectKey
+			e.DownloadToken = token
+			e.ExpiresAt = sql.NullString{String: memoryStamp(expiresAt), Valid: true}
+		}
+	})
+}
+
+func (d *MemoryDataExports) Fail(ctx context.Context, exportID, reason string) error {
+	return d.update(func(e *memoryExport) {
+		if e.ExportID == exportID {
+			e.Status = exportFailed
+			e.Error = reason
+		}
+	})
+}
+
+func (d *MemoryDataExports) FindReadyByUserID(ctx context.Context, userID string) ([]entity.DataExport, error) {
+	return d.entities(d.find(0, func(e *memoryExport) bool {
+		return e.UserID == userID && e.Status == exportReady
+	})), nil
+}
+
+// Returns up to "limit" ready exports past their expiration
+func (d *MemoryDataExports) FindExpired(ctx context.Context, limit int) ([]entity.DataExport, error) {
+	now := time.Now()
+
+	return d.entities(d.find(limit, func(e *memoryExport) bool {
+		if e.Status != exportReady || !e.ExpiresAt.Valid {
+			return false
+		}
+
+		expiresAt, err := time.Parse(time.RFC3339, e.ExpiresAt.String)
+		return err == nil && !expiresAt.After(now)
+	})), nil
+}
+
+func (d *MemoryDataExports) Expire(ctx context.Context, exportID string) error {
+	return d.update(func(e *memoryExport) {
+		if e.ExportID == exportID {
+			e.Status = exportExpired
+			e.ObjectKey = ""
+			e.DownloadToken = ""
+		}
+	})
+}
+
+// Returns copies of up to "limit" matching exports, oldest first.
+// Pass "limit" of 0 to get all of them
+func (d *MemoryDataExports) find(limit int, match func(e *memoryExport) bool) []memoryExport {
+	d.s.mu.RLock()
+	defer d.s.mu.RUnlock()
+
+	exports := make([]memoryExport, 0)
+	for _, e := range d.s.exports {
+		if match(e) {
+			exports = append(exports, *e)
+		}
+	}
+
+	sort.Slice(exports, func(i, j int) bool {
+		return exports[i].seq < exports[j].seq
+	})
+
+	if limit > 0 && len(exports) > limit {
+		exports = exports[:limit]
+	}
+
+	return exports
+}
+
+func (d *MemoryDataExports) update(fn func(e *memoryExport)) error {
+	d.s.mu.Lock()
+	defer d.s.mu.Unlock()
+
+	for _, e := range d.s.exports {
+		fn(e)
+	}
+
+	return nil
+}
+
+func (d *MemoryDataExports) entities(exports []memoryExport) []entity.DataExport {
+	ents := make([]entity.DataExport, 0, len(exports))
+	for _, e := range exports {
+		ents = append(ents, e.DataExport)
+	}
+
+	return ents
+}
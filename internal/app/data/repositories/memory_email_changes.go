@@ -0,0 +1,97 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+	entity "wildproject/internal/app/data/entities"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrEmailChangeTokenExists = errors.New("email change token already exists")
+)
+
+type MemoryEmailChanges struct {
+	s *MemoryStore
+}
+
+func NewMemoryEmailChanges(s *MemoryStore) *MemoryEmailChanges {
+	return &MemoryEmailChanges{s}
+}
+
+func (e *MemoryEmailChanges) FindByConfirmToken(ctx context.Context, token string) (entity.EmailChange, error) {
+	return e.find(func(c *entity.EmailChange) bool {
+		return c.ConfirmToken == token
+	})
+}
+
+func (e *MemoryEmailChanges) FindByCancelToken(ctx context.Context, token string) (entity.EmailChange, error) {
+	return e.find(func(c *entity.EmailChange) bool {
+		return c.CancelToken == token
+	})
+}
+
+// Returns "change_id" of created change
+func (e *MemoryEmailChanges) Create(
+	ctx context.Context, userID, email, confirmToken, cancelToken string, expiresAt time.Time,
+) (string, error) {
+	e.s.mu.Lock()
+	defer e.s.mu.Unlock()
+
+	if !e.s.userExists(userID) {
+		return "", ErrUserNotExists
+	}
+
+	for _, c := range e.s.emailChanges {
+		if c.ConfirmToken == confirmToken || c.CancelToken == cancelToken {
+			return "", ErrEmailChangeTokenExists
+		}
+	}
+
+	changeID := uuid.NewString()
+
+	e.s.emailChanges[changeID] = &entity.EmailChange{
+		ChangeID:     changeID,
+		UserID:       userID,
+		Email:        email,
+		ConfirmToken: confirmToken,
+		CancelToken:  cancelToken,
+		ExpiresAt:    memoryStamp(expiresAt),
+		CreatedAt:    memoryStamp(time.Now()),
+	}
+
+	return changeID, nil
+}
+
+func (e *MemoryEmailChanges) Delete(ctx context.Context, changeID string) error {
+	e.s.mu.Lock()
+	defer e.s.mu.Unlock()
+
+	delete(e.s.emailChanges, changeID)
+	return nil
+}
+
+// Deletes all the pending changes of the user
+func (e *MemoryEmailChanges) DeleteAllByUserID(ctx context.Context, userID string) error {
+	e.s.mu.Lock()
+	defer e.s.mu.Unlock()
+
+	e.s.deleteEmailChanges(userID)
+	return nil
+}
+
+func (e *MemoryEmailChanges) find(match func(c *entity.EmailChange) bool) (entity.EmailChange, error) {
+	e.s.mu.RLock()
+	defer e.s.mu.RUnlock()
+
+	for _, c := range e.s.emailChanges {
+		if match(c) {
+			return *c, nil
+		}
+	}
+
+	return entity.EmailChange{}, sql.ErrNoRows
+}
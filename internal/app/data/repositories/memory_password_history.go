@@ -0,0 +1,81 @@
+package repo
+
+import (
+	"context"
+	"sort"
+	"time"
+	entity "wildproject/internal/app/data/entities"
+)
+
+type MemoryPasswordHistory struct {
+	s *MemoryStore
+}
+
+func NewMemoryPasswordHistory(s *MemoryStore) *MemoryPasswordHistory {
+	return &MemoryPasswordHistory{s}
+}
+
+// Returns up to "limit" latest user's password hashes, newest first
+func (p *MemoryPasswordHistory) FindLatest(ctx context.Context, userID string, limit int) ([]entity.PasswordHistory, error) {
+	p.s.mu.RLock()
+	defer p.s.mu.RUnlock()
+
+	return p.latest(userID, limit), nil
+}
+
+func (p *MemoryPasswordHistory) Create(ctx context.Context, userID, passwordHash string) error {
+	p.s.mu.Lock()
+	defer p.s.mu.Unlock()
+
+	if !p.s.userExists(userID) {
+		return ErrUserNotExists
+	}
+
+	p.s.historySeq++
+	p.s.history = append(p.s.history, entity.PasswordHistory{
+		HistoryID:    p.s.historySeq,
+		UserID:       userID,
+		PasswordHash: passwordHash,
+		CreatedAt:    memoryStamp(time.Now()),
+	})
+
+	return nil
+}
+
+// Deletes all but "keep" latest user's password hashes
+func (p *MemoryPasswordHistory) Prune(ctx context.Context, userID string, keep int) error {
+	p.s.mu.Lock()
+	defer p.s.mu.Unlock()
+
+	kept := make(map[int]bool)
+	for _, h := range p.latest(userID, keep) {
+		kept[h.HistoryID] = true
+	}
+
+	p.s.history = deleteFunc(p.s.history, func(h entity.PasswordHistory) bool {
+		return h.UserID == userID && !kept[h.HistoryID]
+	})
+
+	return nil
+}
+
+// Must be called with the lock held
+func (p *MemoryPasswordHistory) latest(userID string, limit int) []entity.PasswordHistory {
+	history := make([]entity.PasswordHistory, 0)
+	for _, h := range p.s.history {
+		if h.UserID == userID {
+			history = append(history, h)
+		}
+	}
+
+	// Ids grow with creation time
+	sort.Slice(history, func(i, j int) bool {
+		return history[i].HistoryID > history[j].HistoryID
+	})
+
+	if len(history) > limit {
+		history = history[:limit]
+	}
+
+	return history
+}
@@ -0,0 +1,187 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+	entity "wildproject/internal/app/data/entities"
+
+	"github.com/google/uuid"
+)
+
+type MemorySessions struct {
+	s *MemoryStore
+}
+
+func NewMemorySessions(s *MemoryStore) *MemorySessions {
+	return &MemorySessions{s}
+}
+
+func (m *MemorySessions) FindAllByUserID(ctx context.Context, userID string) ([]entity.RefreshSession, error) {
+	return m.find(func(s *entity.RefreshSession) bool {
+		return s.UserID == userID
+	}), nil
+}
+
+func (m *MemorySessions) FindAllByDevice(
+	ctx context.Context,
+	userID, uagent, fprint string,
+) (
+	[]entity.RefreshSession, error,
+) {
+	sessions := m.find(func(s *entity.RefreshSession) bool {
+		return s.UserID == userID && s.Uagent == uagent && s.Fprint == fprint
+	})
+
+	if len(sessions) == 0 {
+		return []entity.RefreshSession{}, sql.ErrNoRows
+	}
+
+	return sessions, nil
+}
+
+func (m *MemorySessions) FindPage(ctx context.Context, f entity.SessionsFilter) ([]entity.RefreshSession, error) {
+	all, err := m.FindAllByUserID(ctx, f.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return filterSessions(all, f), nil
+}
+
+func (m *MemorySessions) FindBySessionID(ctx context.Context, sessionID int) (entity.RefreshSession, error) {
+	m.s.mu.RLock()
+	defer m.s.mu.RUnlock()
+
+	session, ok := m.s.sessions[sessionID]
+	if !ok {
+		return entity.RefreshSession{}, sql.ErrNoRows
+	}
+
+	return *session, nil
+}
+
+func (m *MemorySessions) FindByRefreshToken(ctx context.Context, token string) (entity.RefreshSession, error) {
+	sessions := m.find(func(s *entity.RefreshSession) bool {
+		return s.RefreshToken == token
+	})
+
+	if len(sessions) == 0 {
+		return entity.RefreshSession{}, sql.ErrNoRows
+	}
+
+	return sessions[0], nil
+}
+
+// Returns "session_id" and "refresh_token"
+func (m *MemorySessions) Create(
+	ctx context.Context,
+	userID, uagent, fprint string, device entity.SessionDevice, expiresAt time.Time,
+) (
+	int, string, error,
+) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if !m.s.userExists(userID) {
+		return -1, "", ErrUserNotExists
+	}
+
+	m.s.sessionSeq++
+
+	session := &entity.RefreshSession{
+		SessionID:    m.s.sessionSeq,
+		RefreshToken: uuid.NewString(),
+		UserID:       userID,
+		Uagent:       uagent,
+		Fprint:       fprint,
+		Device:       device,
+		ExpiresAt:    memoryStamp(expiresAt),
+		CreatedAt:    memoryStamp(time.Now()),
+	}
+
+	m.s.sessions[session.SessionID] = session
+
+	return session.SessionID, session.RefreshToken, nil
+}
+
+func (m *MemorySessions) SetAccessToken(ctx context.Context, sessionID int, accessToken string) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if session, ok := m.s.sessions[sessionID]; ok {
+		session.AccessToken = accessToken
+	}
+
+	return nil
+}
+
+func (m *MemorySessions) SetDevice(ctx context.Context, sessionID int, device entity.SessionDevice) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if session, ok := m.s.sessions[sessionID]; ok {
+		session.Device = device
+	}
+
+	return nil
+}
+
+func (m *MemorySessions) Drop(ctx context.Context, sessionID int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	delete(m.s.sessions, sessionID)
+	return nil
+}
+
+func (m *MemorySessions) DropAll(ctx context.Context, userID string) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	for id, session := range m.s.sessions {
+		if session.UserID == userID {
+			delete(m.s.sessions, id)
+		}
+	}
+
+	return nil
+}
+
+func (m *MemorySessions) DropExpired(ctx context.Context) (int, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	now := time.Now()
+	dropped := 0
+
+	for id, session := range m.s.sessions {
+		expiresAt, err := time.Parse(time.RFC3339, session.ExpiresAt)
+		if err == nil && !expiresAt.After(now) {
+			delete(m.s.sessions, id)
+			dropped++
+		}
+	}
+
+	return dropped, nil
+}
+
+// Returns copies of the matching sessions sorted by id
+func (m *MemorySessions) find(match func(s *entity.RefreshSession) bool) []entity.RefreshSession {
+	m.s.mu.RLock()
+	defer m.s.mu.RUnlock()
+
+	sessions := make([]entity.RefreshSession, 0)
+	for _, session := range m.s.sessions {
+		if match(session) {
+			sessions = append(sessions, *session)
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].SessionID < sessions[j].SessionID
+	})
+
+	return sessions
+}
@@ -0,0 +1,67 @@
+package repo
+
+import (
+	"context"
+	"slices"
+	"time"
+	entity "wildproject/internal/app/data/entities"
+)
+
+type MemorySigningKeys struct {
+	s *MemoryStore
+}
+
+func NewMemorySigningKeys(s *MemoryStore) *MemorySigningKeys {
+	return &MemorySigningKeys{s}
+}
+
+func (k *MemorySigningKeys) Create(ctx context.Context, keyID string, secret []byte) error {
+	k.s.mu.Lock()
+	defer k.s.mu.Unlock()
+
+	k.s.signingKeys = append(k.s.signingKeys, memorySigningKey{
+		SigningKey: entity.SigningKey{
+			KeyID:  keyID,
+			Secret: slices.Clone(secret),
+		},
+		createdAt: time.Now(),
+	})
+
+	return nil
+}
+
+// Returns all the keys, newest first
+func (k *MemorySigningKeys) FindAll(ctx context.Context) ([]entity.SigningKey, error) {
+	k.s.mu.RLock()
+	defer k.s.mu.RUnlock()
+
+	keys := make([]entity.SigningKey, 0, len(k.s.signingKeys))
+
+	// Keys are appended in creation order
+	for i := len(k.s.signingKeys) - 1; i >= 0; i-- {
+		key := k.s.signingKeys[i]
+		key.CreatedAt = memoryStamp(key.createdAt)
+		key.Secret = slices.Clone(key.Secret)
+		keys = append(keys, key.SigningKey)
+	}
+
+	return keys, nil
+}
+
+// Deletes keys replaced by a newer one before "before", returns the
+// number of deleted keys
+func (k *MemorySigningKeys) DropSuperseded(ctx context.Context, before time.Time) (int, error) {
+	k.s.mu.Lock()
+	defer k.s.mu.Unlock()
+
+	// Keys are appended in creation order, so the key is superseded when
+	// the next one is created
+	dropped := 0
+	for dropped < len(k.s.signingKeys)-1 && k.s.signingKeys[dropped+1].createdAt.Before(before) {
+		dropped++
+	}
+
+	k.s.signingKeys = slices.Delete(k.s.signingKeys, 0, dropped)
+
+	return dropped, nil
+}
@@ -0,0 +1,386 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+	entity "wildproject/internal/app/data/entities"
+)
+
+func TestMemoryUsers(t *testing.T) {
+	ctx := context.Background()
+	repos := NewMemoryRepositories()
+
+	userID, err := repos.Users.Create(ctx, "user@example.com", "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repos.Users.Create(ctx, "user@example.com", "hash"); !errors.Is(err, ErrUserExists) {
+		t.Fatalf("expected ErrUserExists, got %v", err)
+	}
+
+	user, err := repos.Users.FindByEmail(ctx, "user@example.com")
+	if err != nil || user.ID != userID {
+		t.Fatalf("unexpected user %+v, %v", user, err)
+	}
+
+	if err := repos.Users.Anonymize(ctx, userID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repos.Users.FindByID(ctx, userID); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("anonymized user is found: %v", err)
+	}
+
+	if _, err := repos.Users.FindByEmail(ctx, "user@example.com"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("anonymized user is found by email: %v", err)
+	}
+}
+
+func TestMemoryUsersDeleteCascades(t *testing.T) {
+	ctx := context.Background()
+	repos := NewMemoryRepositories()
+
+	userID, err := repos.Users.Create(ctx, "user@example.com", "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sessionID, _, err := repos.Sessions.Create(
+		ctx, userID, "uagent", "fprint", entity.SessionDevice{}, time.Now().Add(time.Hour),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repos.PasswordHistory.Create(ctx, userID, "old"); err != nil {
+		t.Fatal(err)
+	}
+
+	changeID, err := repos.EmailChanges.Create(ctx, userID, "new@example.com", "confirm", "cancel", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repos.Users.Delete(ctx, userID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repos.Sessions.FindBySessionID(ctx, sessionID); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("session of deleted user is found: %v", err)
+	}
+
+	if history, _ := repos.PasswordHistory.FindLatest(ctx, userID, 10); len(history) != 0 {
+		t.Fatalf("history of deleted user is found: %v", history)
+	}
+
+	if change, err := repos.EmailChanges.FindByConfirmToken(ctx, "confirm"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("email change %s of deleted user is found: %+v", changeID, change)
+	}
+
+	// References to the deleted user are rejected like foreign keys
+	if err := repos.PasswordHistory.Create(ctx, userID, "hash"); !errors.Is(err, ErrUserNotExists) {
+		t.Fatalf("expected ErrUserNotExists, got %v", err)
+	}
+}
+
+func TestMemoryUsersDeletionGracePeriod(t *testing.T) {
+	ctx := context.Background()
+	repos := NewMemoryRepositories()
+
+	userID, err := repos.Users.Create(ctx, "user@example.com", "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repos.Users.CancelDeletion(ctx, userID); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected sql.ErrNoRows for unscheduled deletion, got %v", err)
+	}
+
+	// During the grace period deletion is canceled and isn't due
+	if err := repos.Users.ScheduleDeletion(ctx, userID, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repos.Users.FindDetailedDueDeletion(ctx, userID); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected sql.ErrNoRows before the period is over, got %v", err)
+	}
+
+	if err := repos.Users.CancelDeletion(ctx, userID); err != nil {
+		t.Fatal(err)
+	}
+
+	// After the grace period deletion is due and can't be canceled
+	if err := repos.Users.ScheduleDeletion(ctx, userID, time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repos.Users.CancelDeletion(ctx, userID); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("expected sql.ErrNoRows after the period is over, got %v", err)
+	}
+
+	user, err := repos.Users.FindDetailedDueDeletion(ctx, userID)
+	if err != nil || user.ID != userID {
+		t.Fatalf("unexpected user %+v, %v", user, err)
+	}
+}
+
+func TestMemoryDataExportsSingleActive(t *testing.T) {
+	ctx := context.Background()
+	repos := NewMemoryRepositories()
+
+	userID, err := repos.Users.Create(ctx, "user@example.com", "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exportID, err := repos.DataExports.Create(ctx, userID, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repos.DataExports.Create(ctx, userID, "zip"); !errors.Is(err, ErrActiveExportExists) {
+		t.Fatalf("expected ErrActiveExportExists for pending export, got %v", err)
+	}
+
+	if _, err := repos.DataExports.Claim(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repos.DataExports.Create(ctx, userID, "zip"); !errors.Is(err, ErrActiveExportExists) {
+		t.Fatalf("expected ErrActiveExportExists for processing export, got %v", err)
+	}
+
+	// Finished export doesn't block the next one
+	if err := repos.DataExports.Complete(ctx, exportID, "key", "token", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repos.DataExports.Create(ctx, userID, "zip"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMemoryUserRoles(t *testing.T) {
+	ctx := context.Background()
+	repos := NewMemoryRepositories()
+
+	userID, err := repos.Users.Create(ctx, "user@example.com", "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repos.UserRoles.Grant(ctx, "unknown", []string{"admin"}); !errors.Is(err, ErrUserNotExists) {
+		t.Fatalf("expected ErrUserNotExists, got %v", err)
+	}
+
+	if err := repos.UserRoles.Grant(ctx, userID, []string{"support"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Granted roles are kept, the new ones are added
+	if err := repos.UserRoles.Grant(ctx, userID, []string{"support", "admin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	roles, err := repos.UserRoles.FindAll(ctx, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(roles, []string{"admin", "support"}) {
+		t.Fatalf("expected [admin support], got %v", roles)
+	}
+
+	if err := repos.Users.Anonymize(ctx, userID); err != nil {
+		t.Fatal(err)
+	}
+
+	if roles, _ := repos.UserRoles.FindAll(ctx, userID); len(roles) != 0 {
+		t.Fatalf("expected roles of anonymized user to be deleted, got %v", roles)
+	}
+}
+
+func TestMemorySigningKeys(t *testing.T) {
+	ctx := context.Background()
+	repos := NewMemoryRepositories()
+
+	for _, keyID := range []string{"a", "b", "c"} {
+		if err := repos.SigningKeys.Create(ctx, keyID, []byte("secret "+keyID)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	keys, err := repos.SigningKeys.FindAll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(keys) != 3 || keys[0].KeyID != "c" || keys[2].KeyID != "a" {
+		t.Fatalf("expected keys newest first, got %+v", keys)
+	}
+
+	// Latest key is never dropped, even if it's old
+	dropped, err := repos.SigningKeys.DropSuperseded(ctx, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dropped != 2 {
+		t.Fatalf("expected 2 dropped keys, got %d", dropped)
+	}
+
+	keys, _ = repos.SigningKeys.FindAll(ctx)
+	if len(keys) != 1 || keys[0].KeyID != "c" || string(keys[0].Secret) != "secret c" {
+		t.Fatalf("expected only the latest key, got %+v", keys)
+	}
+}
+
+func TestMemoryPasswordHistoryPrune(t *testing.T) {
+	ctx := context.Background()
+	repos := NewMemoryRepositories()
+
+	userID, err := repos.Users.Create(ctx, "user@example.com", "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, hash := range []string{"first", "second", "third"} {
+		if err := repos.PasswordHistory.Create(ctx, userID, hash); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := repos.PasswordHistory.Prune(ctx, userID, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := repos.PasswordHistory.FindLatest(ctx, userID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(history) != 2 || history[0].PasswordHash != "third" || history[1].PasswordHash != "second" {
+		t.Fatalf("unexpected history %+v", history)
+	}
+}
+
+func TestMemoryAuditEvents(t *testing.T) {
+	ctx := context.Background()
+	repos := NewMemoryRepositories()
+
+	events := []entity.AuditEvent{
+		{EventType: "login", ActorID: "user", TargetID: "user", IP: "1.1.1.1", Metadata: map[string]string{"k": "v"}},
+		{EventType: "login", ActorID: "other", TargetID: "other", IP: "2.2.2.2"},
+		{EventType: "dropped", ActorID: "admin", TargetID: "user", IP: "3.3.3.3"},
+	}
+
+	for _, e := range events {
+		if err := repos.AuditEvents.Create(ctx, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	page, err := repos.AuditEvents.FindAllByUserID(ctx, "user", 1<<62, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(page) != 1 || page[0].EventType != "dropped" {
+		t.Fatalf("unexpected first page %+v", page)
+	}
+
+	page, err = repos.AuditEvents.FindAllByUserID(ctx, "user", page[0].EventID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(page) != 1 || page[0].EventType != "login" {
+		t.Fatalf("unexpected second page %+v", page)
+	}
+
+	if err := repos.AuditEvents.AnonymizeByUserID(ctx, "user"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, userID := range []string{"user", "other"} {
+		page, err := repos.AuditEvents.FindAllByUserID(ctx, userID, 1<<62, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, e := range page {
+			anonymized := e.IP == "" && e.Uagent == "" && len(e.Metadata) == 0
+			if anonymized != (userID == "user") {
+				t.Fatalf("event %+v of %s has anonymized %v", e, userID, anonymized)
+			}
+		}
+	}
+}
+
+func TestMemoryUnitOfWork(t *testing.T) {
+	ctx := context.Background()
+	errFailed := errors.New("failed")
+
+	tests := []struct {
+		name    string
+		err     error
+		applied bool
+	}{
+		{"commit", nil, true},
+		{"rollback", errFailed, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repos := NewMemoryRepositories()
+			uow := NewMemoryUnitOfWork(repos)
+
+			userID, err := repos.Users.Create(ctx, "user@example.com", "hash")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = uow.Do(ctx, func(r Repositories) error {
+				if err := r.Users.ChangeName(ctx, userID, "name"); err != nil {
+					return err
+				}
+
+				if err := r.PasswordHistory.Create(ctx, userID, "hash"); err != nil {
+					return err
+				}
+
+				_, _, err := r.Sessions.Create(
+					ctx, userID, "uagent", "fprint", entity.SessionDevice{}, time.Now().Add(time.Hour),
+				)
+				if err != nil {
+					return err
+				}
+
+				return tt.err
+			})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+
+			user, err := repos.Users.FindDetailedByID(ctx, userID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			history, _ := repos.PasswordHistory.FindLatest(ctx, userID, 10)
+			sessions, _ := repos.Sessions.FindAllByUserID(ctx, userID)
+
+			got := []bool{user.Name == "name", len(history) == 1, len(sessions) == 1}
+			for i, applied := range got {
+				if applied != tt.applied {
+					t.Fatalf("write %d applied %v, want %v", i, applied, tt.applied)
+				}
+			}
+		})
+	}
+}
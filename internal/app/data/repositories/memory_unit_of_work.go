@@ -0,0 +1,48 @@
+package repo
+
+import (
+	"context"
+)
+
+// Runs units on a copy of the store holding its lock, so units are
+// atomic and isolated: the copy replaces the data only if the unit
+// succeeds. Copying takes time linear to the size of the store, which
+// is fine for development and tests the memory driver is meant for
+type MemoryUnitOfWork struct {
+	store *MemoryStore
+	// Sessions stored outside of the memory store, nil if they are in it.
+	// Such sessions are not the part of the unit
+	sessions SessionsRepo
+}
+
+// Repos must be built by NewMemoryRepositories, sessions repo may be
+// replaced by the one storing them elsewhere
+func NewMemoryUnitOfWork(repos Repositories) *MemoryUnitOfWork {
+	u := &MemoryUnitOfWork{store: repos.Users.(*MemoryUsers).s}
+
+	if _, ok := repos.Sessions.(*MemorySessions); !ok {
+		u.sessions = repos.Sessions
+	}
+
+	return u
+}
+
+func (u *MemoryUnitOfWork) Do(ctx context.Context, fn func(r Repositories) error) error {
+	u.store.mu.Lock()
+	defer u.store.mu.Unlock()
+
+	tx := u.store.clone()
+
+	repos := newMemoryRepositories(tx)
+	if u.sessions != nil {
+		repos.Sessions = u.sessions
+	}
+
+	if err := fn(repos); err != nil {
+		return err
+	}
+
+	u.store.replace(tx)
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package repo
+
+import (
+	"context"
+	"slices"
+)
+
+type MemoryUserRoles struct {
+	s *MemoryStore
+}
+
+func NewMemoryUserRoles(s *MemoryStore) *MemoryUserRoles {
+	return &MemoryUserRoles{s}
+}
+
+// Adds the roles to the user's ones, granted roles are kept
+func (r *MemoryUserRoles) Grant(ctx context.Context, userID string, roles []string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if !r.s.userExists(userID) {
+		return ErrUserNotExists
+	}
+
+	for _, role := range roles {
+		if !slices.Contains(r.s.roles[userID], role) {
+			r.s.roles[userID] = append(r.s.roles[userID], role)
+		}
+	}
+
+	return nil
+}
+
+// Returns user's roles ordered by name
+func (r *MemoryUserRoles) FindAll(ctx context.Context, userID string) ([]string, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	roles := slices.Clone(r.s.roles[userID])
+	if roles == nil {
+		roles = make([]string, 0)
+	}
+
+	slices.Sort(roles)
+
+	return roles, nil
+}
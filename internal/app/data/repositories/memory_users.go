@@ -0,0 +1,294 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+	entity "wildproject/internal/app/data/entities"
+
+	"github.com/google/uuid"
+)
+
+// Default "sex_id" of the user_info
+const memoryDefaultSexID = 1
+
+type MemoryUsers struct {
+	s *MemoryStore
+}
+
+func NewMemoryUsers(s *MemoryStore) *MemoryUsers {
+	return &MemoryUsers{s}
+}
+
+func (u *MemoryUsers) FindByID(ctx context.Context, userID string) (entity.User, error) {
+	user, err := u.FindDetailedByID(ctx, userID)
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	return user.User, nil
+}
+
+func (u *MemoryUsers) FindByEmail(ctx context.Context, email string) (entity.User, error) {
+	u.s.mu.RLock()
+	defer u.s.mu.RUnlock()
+
+	for _, user := range u.s.users {
+		if user.Email == email && !user.deleted {
+			return user.User, nil
+		}
+	}
+
+	return entity.User{}, sql.ErrNoRows
+}
+
+func (u *MemoryUsers) FindDetailedByID(ctx context.Context, userID string) (entity.UserDetailed, error) {
+	u.s.mu.RLock()
+	defer u.s.mu.RUnlock()
+
+	user, ok := u.s.users[userID]
+	if !ok || user.deleted {
+		return entity.UserDetailed{}, sql.ErrNoRows
+	}
+
+	detailed := user.UserDetailed
+	detailed.ImageVariants = append([]entity.ImageVariant{}, user.ImageVariants...)
+
+	return detailed, nil
+}
+
+// Counts deleted users too, as their emails are still taken
+func (u *MemoryUsers) CountByEmail(ctx context.Context, email string) (int, error) {
+	u.s.mu.RLock()
+	defer u.s.mu.RUnlock()
+
+	count := 0
+	for _, user := range u.s.users {
+		if user.Email == email {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// Returns "user_id" of created user or "" in error case
+func (u *MemoryUsers) Create(ctx context.Context, email, passwordHash string) (string, error) {
+	u.s.mu.Lock()
+	defer u.s.mu.Unlock()
+
+	if u.emailTaken(email) {
+		return "", ErrUserExists
+	}
+
+	now := memoryStamp(time.Now())
+	userID := uuid.NewString()
+
+	u.s.users[userID] = &memoryUser{
+		UserDetailed: entity.UserDetailed{
+			User: entity.User{
+				ID:           userID,
+				Email:        email,
+				PasswordHash: passwordHash,
+				CreatedAt:    now,
+				UpdatetdAt:   now,
+			},
+			SexID:         memoryDefaultSexID,
+			ImageVariants: []entity.ImageVariant{},
+		},
+	}
+
+	return userID, nil
+}
+
+func (u *MemoryUsers) ChangeName(ctx context.Context, userID, value string) error {
+	return u.update(userID, func(user *memoryUser) {
+		user.Name = value
+	})
+}
+
+func (u *MemoryUsers) ChangeSex(ctx context.Context, userID string, value int) error {
+	return u.update(userID, func(user *memoryUser) {
+		user.SexID = value
+	})
+}
+
+func (u *MemoryUsers) ChangeEmail(ctx context.Context, userID, value string) error {
+	u.s.mu.Lock()
+	defer u.s.mu.Unlock()
+
+	user, ok := u.s.users[userID]
+	if !ok {
+		return nil
+	}
+
+	if user.Email != value && u.emailTaken(value) {
+		return ErrUserExists
+	}
+
+	user.Email = value
+	return nil
+}
+
+func (u *MemoryUsers) ChangePasswordHash(ctx context.Context, userID, value string) error {
+	return u.update(userID, func(user *memoryUser) {
+		user.PasswordHash = value
+	})
+}
+
+func (u *MemoryUsers) ChangeImageVariants(ctx context.Context, userID string, value []entity.ImageVariant) error {
+	return u.update(userID, func(user *memoryUser) {
+		user.ImageVariants = append([]entity.ImageVariant{}, value...)
+	})
+}
+
+func (u *MemoryUsers) ScheduleDeletion(ctx context.Context, userID string, at time.Time) error {
+	return u.update(userID, func(user *memoryUser) {
+		user.DeleteAfter = sql.NullString{String: memoryStamp(at), Valid: true}
+	})
+}
+
+// Cancels deletion only during the grace period.
+//
+// Returns sql.ErrNoRows if the period is over or deletion isn't scheduled
+func (u *MemoryUsers) CancelDeletion(ctx context.Context, userID string) error {
+	u.s.mu.Lock()
+	defer u.s.mu.Unlock()
+
+	user, ok := u.s.users[userID]
+	if !ok || user.deleted {
+		return sql.ErrNoRows
+	}
+
+	after, ok := user.deleteAfter()
+	if !ok || !after.After(time.Now()) {
+		return sql.ErrNoRows
+	}
+
+	user.DeleteAfter = sql.NullString{}
+
+	return nil
+}
+
+// Finds user only if their deletion grace period is over
+func (u *MemoryUsers) FindDetailedDueDeletion(ctx context.Context, userID string) (entity.UserDetailed, error) {
+	u.s.mu.RLock()
+	defer u.s.mu.RUnlock()
+
+	user, ok := u.s.users[userID]
+	if !ok || user.deleted {
+		return entity.UserDetailed{}, sql.ErrNoRows
+	}
+
+	after, ok := user.deleteAfter()
+	if !ok || after.After(time.Now()) {
+		return entity.UserDetailed{}, sql.ErrNoRows
+	}
+
+	detailed := user.UserDetailed
+	detailed.ImageVariants = append([]entity.ImageVariant{}, user.ImageVariants...)
+
+	return detailed, nil
+}
+
+// Returns up to "limit" ids of users whose deletion grace period is over
+func (u *MemoryUsers) FindDueDeletion(ctx context.Context, limit int) ([]string, error) {
+	u.s.mu.RLock()
+	defer u.s.mu.RUnlock()
+
+	type due struct {
+		id    string
+		after time.Time
+	}
+
+	now := time.Now()
+	users := make([]due, 0)
+
+	for _, user := range u.s.users {
+		if user.deleted {
+			continue
+		}
+
+		after, ok := user.deleteAfter()
+		if !ok || after.After(now) {
+			continue
+		}
+
+		users = append(users, due{user.ID, after})
+	}
+
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].after.Before(users[j].after)
+	})
+
+	ids := make([]string, 0, limit)
+	for _, user := range users {
+		if len(ids) == limit {
+			break
+		}
+
+		ids = append(ids, user.id)
+	}
+
+	return ids, nil
+}
+
+// Erases user's personal data keeping the user to preserve references
+func (u *MemoryUsers) Anonymize(ctx context.Context, userID string) error {
+	u.s.mu.Lock()
+	defer u.s.mu.Unlock()
+
+	user, ok := u.s.users[userID]
+	if !ok {
+		return nil
+	}
+
+	user.Name = ""
+	user.ImageVariants = []entity.ImageVariant{}
+	user.SexID = memoryDefaultSexID
+
+	// Pending email changes hold the new address
+	u.s.deleteEmailChanges(userID)
+	// Anonymized account keeps no privileges
+	delete(u.s.roles, userID)
+
+	user.Email = userID + "@deleted.invalid"
+	user.PasswordHash = ""
+	user.DeleteAfter = sql.NullString{}
+	user.deleted = true
+
+	return nil
+}
+
+// Deletes user with all the related data
+func (u *MemoryUsers) Delete(ctx context.Context, userID string) error {
+	u.s.mu.Lock()
+	defer u.s.mu.Unlock()
+
+	u.s.deleteUser(userID)
+	return nil
+}
+
+// Updates are no-op for unknown users, like UPDATE matching no rows
+func (u *MemoryUsers) update(userID string, fn func(user *memoryUser)) error {
+	u.s.mu.Lock()
+	defer u.s.mu.Unlock()
+
+	if user, ok := u.s.users[userID]; ok {
+		fn(user)
+	}
+
+	return nil
+}
+
+// Must be called with the lock held
+func (u *MemoryUsers) emailTaken(email string) bool {
+	for _, user := range u.s.users {
+		if user.Email == email {
+			return true
+		}
+	}
+
+	return false
+}
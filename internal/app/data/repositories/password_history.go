@@ -0,0 +1,62 @@
+package repo
+
+import (
+	"context"
+	"time"
+	"wildproject/internal/app/data/database"
+	entity "wildproject/internal/app/data/entities"
+	query "wildproject/internal/app/data/queries"
+)
+
+type PasswordHistory struct {
+	db      database.Executor
+	timeout time.Duration
+}
+
+func NewPasswordHistory(db database.Executor, timeout time.Duration) *PasswordHistory {
+	return &PasswordHistory{db, timeout}
+}
+
+// Returns up to "limit" of the latest user's password hashes, newest first
+func (p *PasswordHistory) FindLatest(ctx context.Context, userID string, limit int) ([]entity.PasswordHistory, error) {
+	ctx, cancel := withTimeout(ctx, p.timeout)
+	defer cancel()
+
+	rows, err := p.db.QueryContext(ctx, query.FindPasswordHistory, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := make([]entity.PasswordHistory, 0)
+
+	for rows.Next() {
+		var h entity.PasswordHistory
+
+		err := rows.Scan(&h.HistoryID, &h.UserID, &h.PasswordHash, &h.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		history = append(history, h)
+	}
+
+	return history, rows.Err()
+}
+
+func (p *PasswordHistory) Create(ctx context.Context, userID, passwordHash string) error {
+	ctx, cancel := withTimeout(ctx, p.timeout)
+	defer cancel()
+
+	_, err := p.db.ExecContext(ctx, query.CreatePasswordHistory, userID, passwordHash)
+	return err
+}
+
+// Deletes all user's password hashes except of "keep" latest ones
+func (p *PasswordHistory) Prune(ctx context.Context, userID string, keep int) error {
+	ctx, cancel := withTimeout(ctx, p.timeout)
+	defer cancel()
+
+	_, err := p.db.ExecContext(ctx, query.PrunePasswordHistory, userID, keep)
+	return err
+}
@@ -0,0 +1,311 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+	entity "wildproject/internal/app/data/entities"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Sets session's fields given as name and value pairs only if the session
+// still exists, otherwise HSET would create a hash without expiration
+var setSessionFieldsScript = redis.NewScript(`
+	if redis.call("EXISTS", KEYS[1]) == 0 then
+		return 0
+	end
+	return redis.call("HSET", KEYS[1], unpack(ARGV))
+`)
+
+// Refresh sessions stored in the Redis protocol compatible server.
+// Every session is a hash indexed by refresh token and by the set of
+// user's sessions.
+//
+// Expired sessions are kept for "retention" after their "expires_at" and
+// then removed by the server, so like in the database they are listed
+// and refreshing them is rejected as expired, not as unknown
+type RedisSessions struct {
+	rdb       *redis.Client
+	prefix    string
+	retention time.Duration
+}
+
+func NewRedisSessions(rdb *redis.Client, prefix string, retention time.Duration) *RedisSessions {
+	return &RedisSessions{rdb, prefix, retention}
+}
+
+func (r *RedisSessions) FindAllByUserID(ctx context.Context, userID string) ([]entity.RefreshSession, error) {
+	ids, err := r.rdb.SMembers(ctx, r.userKey(userID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	cmds, err := r.rdb.Pipelined(ctx, func(p redis.Pipeliner) error {
+		for _, id := range ids {
+			p.HGetAll(ctx, r.sessionKey(id))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]entity.RefreshSession, 0, len(ids))
+	stale := make([]any, 0)
+
+	for i, cmd := range cmds {
+		fields, err := cmd.(*redis.MapStringStringCmd).Result()
+		if err != nil {
+			return nil, err
+		}
+
+		// Session expired, but the index still refers to it
+		if len(fields) == 0 {
+			stale = append(stale, ids[i])
+			continue
+		}
+
+		session, err := r.parse(fields)
+		if err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	if len(stale) > 0 {
+		r.rdb.SRem(ctx, r.userKey(userID), stale...)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].SessionID < sessions[j].SessionID
+	})
+
+	return sessions, nil
+}
+
+func (r *RedisSessions) FindAllByDevice(
+	ctx context.Context,
+	userID, uagent, fprint string,
+) (
+	[]entity.RefreshSession, error,
+) {
+	all, err := r.FindAllByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]entity.RefreshSession, 0)
+	for _, s := range all {
+		if s.Uagent == uagent && s.Fprint == fprint {
+			sessions = append(sessions, s)
+		}
+	}
+
+	if len(sessions) == 0 {
+		return []entity.RefreshSession{}, sql.ErrNoRows
+	}
+
+	return sessions, nil
+}
+
+// Filters user's sessions in memory, as there are only a few of them
+func (r *RedisSessions) FindPage(ctx context.Context, f entity.SessionsFilter) ([]entity.RefreshSession, error) {
+	all, err := r.FindAllByUserID(ctx, f.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return filterSessions(all, f), nil
+}
+
+func (r *RedisSessions) FindBySessionID(ctx context.Context, sessionID int) (entity.RefreshSession, error) {
+	fields, err := r.rdb.HGetAll(ctx, r.sessionKey(sessionID)).Result()
+	if err != nil {
+		return entity.RefreshSession{}, err
+	}
+
+	if len(fields) == 0 {
+		return entity.RefreshSession{}, sql.ErrNoRows
+	}
+
+	return r.parse(fields)
+}
+
+func (r *RedisSessions) FindByRefreshToken(ctx context.Context, token string) (entity.RefreshSession, error) {
+	sessionID, err := r.rdb.Get(ctx, r.tokenKey(token)).Int()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return entity.RefreshSession{}, sql.ErrNoRows
+		}
+
+		return entity.RefreshSession{}, err
+	}
+
+	return r.FindBySessionID(ctx, sessionID)
+}
+
+// Returns "session_id" and "refresh_token"
+func (r *RedisSessions) Create(
+	ctx context.Context,
+	userID, uagent, fprint string, device entity.SessionDevice, expiresAt time.Time,
+) (
+	int, string, error,
+) {
+	id, err := r.rdb.Incr(ctx, r.key("session_seq")).Result()
+	if err != nil {
+		return -1, "", err
+	}
+
+	sessionID := int(id)
+	refreshToken := uuid.NewString()
+	removeAt := expiresAt.Add(r.retention)
+
+	_, err = r.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.HSet(ctx, r.sessionKey(sessionID), map[string]any{
+			"session_id":      sessionID,
+			"refresh_token":   refreshToken,
+			"access_token":    "",
+			"user_id":         userID,
+			"user_agent":      uagent,
+			"fingerprint":     fprint,
+			"browser":         device.Browser,
+			"browser_version": device.BrowserVersion,
+			"os":              device.OS,
+			"os_version":      device.OSVersion,
+			"device_type":     device.Type,
+			"device_model":    device.Model,
+			"app_version":     device.AppVersion,
+			"expires_at":      expiresAt.UTC().Format(time.RFC3339Nano),
+			"created_at":      time.Now().UTC().Format(time.RFC3339Nano),
+		})
+		p.ExpireAt(ctx, r.sessionKey(sessionID), removeAt)
+
+		p.Set(ctx, r.tokenKey(refreshToken), sessionID, 0)
+		p.ExpireAt(ctx, r.tokenKey(refreshToken), removeAt)
+
+		// Sessions have the same TTL, so the index lives as long as
+		// the latest created session
+		p.SAdd(ctx, r.userKey(userID), sessionID)
+		p.ExpireAt(ctx, r.userKey(userID), removeAt)
+
+		return nil
+	})
+	if err != nil {
+		return -1, "", err
+	}
+
+	return sessionID, refreshToken, nil
+}
+
+func (r *RedisSessions) SetAccessToken(ctx context.Context, sessionID int, accessToken string) error {
+	keys := []string{r.sessionKey(sessionID)}
+
+	return setSessionFieldsScript.Run(ctx, r.rdb, keys, "access_token", accessToken).Err()
+}
+
+func (r *RedisSessions) SetDevice(ctx context.Context, sessionID int, device entity.SessionDevice) error {
+	keys := []string{r.sessionKey(sessionID)}
+
+	return setSessionFieldsScript.Run(
+		ctx, r.rdb, keys,
+		"browser", device.Browser,
+		"browser_version", device.BrowserVersion,
+		"os", device.OS,
+		"os_version", device.OSVersion,
+		"device_type", device.Type,
+		"device_model", device.Model,
+		"app_version", device.AppVersion,
+	).Err()
+}
+
+func (r *RedisSessions) Drop(ctx context.Context, sessionID int) error {
+	session, err := r.FindBySessionID(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+
+		return err
+	}
+
+	return r.drop(ctx, session.UserID, []entity.RefreshSession{session})
+}
+
+func (r *RedisSessions) DropAll(ctx context.Context, userID string) error {
+	sessions, err := r.FindAllByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	return r.drop(ctx, userID, sessions)
+}
+
+// Expired sessions are removed by the server once retained, so there is
+// nothing to drop
+func (r *RedisSessions) DropExpired(ctx context.Context) (int, error) {
+	return 0, nil
+}
+
+func (r *RedisSessions) drop(ctx context.Context, userID string, sessions []entity.RefreshSession) error {
+	_, err := r.rdb.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		for _, s := range sessions {
+			p.Del(ctx, r.sessionKey(s.SessionID), r.tokenKey(s.RefreshToken))
+			p.SRem(ctx, r.userKey(userID), s.SessionID)
+		}
+
+		return nil
+	})
+
+	return err
+}
+
+func (r *RedisSessions) parse(fields map[string]string) (entity.RefreshSession, error) {
+	sessionID, err := strconv.Atoi(fields["session_id"])
+	if err != nil {
+		return entity.RefreshSession{}, fmt.Errorf("invalid session_id: %w", err)
+	}
+
+	return entity.RefreshSession{
+		SessionID:    sessionID,
+		RefreshToken: fields["refresh_token"],
+		AccessToken:  fields["access_token"],
+		UserID:       fields["user_id"],
+		Uagent:       fields["user_agent"],
+		Fprint:       fields["fingerprint"],
+		Device: entity.SessionDevice{
+			Browser:        fields["browser"],
+			BrowserVersion: fields["browser_version"],
+			OS:             fields["os"],
+			OSVersion:      fields["os_version"],
+			Type:           fields["device_type"],
+			Model:          fields["device_model"],
+			AppVersion:     fields["app_version"],
+		},
+		ExpiresAt: fields["expires_at"],
+		CreatedAt: fields["created_at"],
+	}, nil
+}
+
+func (r *RedisSessions) key(name string) string {
+	return r.prefix + name
+}
+
+func (r *RedisSessions) sessionKey(sessionID any) string {
+	return r.key(fmt.Sprintf("session:%v", sessionID))
+}
+
+func (r *RedisSessions) tokenKey(token string) string {
+	return r.key("session_token:" + token)
+}
+
+func (r *RedisSessions) userKey(userID string) string {
+	return r.key("user_sessions:" + userID)
+}
@@ -0,0 +1,82 @@
+package repo
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"time"
+	"wildproject/internal/app/data/database"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Postgres error code of the unique constraint violation
+const pqUniqueViolation = "23505"
+
+// All the repos of the app backed by the same storage
+type Repositories struct {
+	Users           UsersRepo
+	Sessions        SessionsRepo
+	PasswordHistory PasswordHistoryRepo
+	DataExports     DataExportsRepo
+	AuditEvents     AuditEventsRepo
+	EmailChanges    EmailChangesRepo
+	UserRoles       UserRolesRepo
+	SigningKeys     SigningKeysRepo
+}
+
+// Builds repos on top of the database, "timeout" limits every query
+func NewPostgresRepositories(db database.Executor, timeout time.Duration) Repositories {
+	return Repositories{
+		Users:           NewUsers(db, timeout),
+		Sessions:        NewSessions(db, timeout),
+		PasswordHistory: NewPasswordHistory(db, timeout),
+		DataExports:     NewDataExports(db, timeout),
+		AuditEvents:     NewAuditEvents(db, timeout),
+		EmailChanges:    NewEmailChanges(db, timeout),
+		UserRoles:       NewUserRoles(db, timeout),
+		SigningKeys:     NewSigningKeys(db, timeout),
+	}
+}
+
+// Limits duration of the query, "timeout" of 0 means no limit. Queries
+// of traced requests are reported as spans named by the calling repo
+// method, e.g. "repo.(*Users).FindByID"
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	var span *sentry.Span
+
+	// Span without transaction would start a new one
+	if sentry.TransactionFromContext(ctx) != nil {
+		span = sentry.StartSpan(ctx, "db.query", sentry.WithDescription(callerName()))
+		ctx = span.Context()
+	}
+
+	var cancel context.CancelFunc
+	if timeout <= 0 {
+		ctx, cancel = context.WithCancel(ctx)
+	} else {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+
+	if span == nil {
+		return ctx, cancel
+	}
+
+	return ctx, func() {
+		cancel()
+		span.Finish()
+	}
+}
+
+// Returns name of the function which called the caller
+func callerName() string {
+	pc, _, _, ok := runtime.Caller(2)
+	if !ok {
+		return "unknown"
+	}
+
+	name := runtime.FuncForPC(pc).Name()
+
+	// Package path is the same for all the repos
+	return name[strings.LastIndex(name, "/")+1:]
+}
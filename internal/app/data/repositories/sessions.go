@@ -1,24 +1,41 @@
 package repo
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"sort"
+	"strings"
 	"time"
 	"wildproject/internal/app/data/database"
 	entity "wildproject/internal/app/data/entities"
 	query "wildproject/internal/app/data/queries"
 )
 
+const (
+	// Sessions are stored in the database selected by DATABASE_DRIVER
+	SessionsDriverDatabase = "database"
+	SessionsDriverRedis    = "redis"
+)
+
+var (
+	ErrUnknownSessionsDriver = errors.New("unknown sessions driver")
+)
+
 type Sessions struct {
-	db database.Instance
+	db      database.Executor
+	timeout time.Duration
 }
 
-func NewSessions(db database.Instance) *Sessions {
-	return &Sessions{db}
+func NewSessions(db database.Executor, timeout time.Duration) *Sessions {
+	return &Sessions{db, timeout}
 }
 
-func (s *Sessions) FindAllByUserID(userID string) ([]entity.RefreshSession, error) {
-	rows, err := s.db.Query(query.FindSessionsByUserID, userID)
+func (s *Sessions) FindAllByUserID(ctx context.Context, userID string) ([]entity.RefreshSession, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query.FindSessionsByUserID, userID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return []entity.RefreshSession{}, nil
@@ -32,11 +49,7 @@ func (s *Sessions) FindAllByUserID(userID string) ([]entity.RefreshSession, erro
 	for rows.Next() {
 		var session entity.RefreshSession
 
-		err := rows.Scan(
-			&session.SessionID, &session.RefreshToken, &session.AccessToken,
-			&session.UserID, &session.Uagent, &session.Fprint, &session.ExpiresAt,
-			&session.CreatedAt,
-		)
+		err := rows.Scan(s.fields(&session)...)
 
 		if err != nil {
 			continue
@@ -48,14 +61,61 @@ func (s *Sessions) FindAllByUserID(userID string) ([]entity.RefreshSession, erro
 	return sessions, nil
 }
 
+func (s *Sessions) FindPage(ctx context.Context, f entity.SessionsFilter) ([]entity.RefreshSession, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	var createdAfter sql.NullTime
+	if f.CreatedAfter != nil {
+		createdAfter = sql.NullTime{Time: *f.CreatedAfter, Valid: true}
+	}
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		query.FindSessionsPage,
+		f.UserID,
+		f.CurrentSessionID,
+		f.Status,
+		f.Device,
+		createdAfter,
+		f.Cursor,
+		f.Desc,
+		f.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := make([]entity.RefreshSession, 0)
+
+	for rows.Next() {
+		var session entity.RefreshSession
+
+		err := rows.Scan(append(s.fields(&session), &session.IsCurrent)...)
+
+		if err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
 func (s *Sessions) FindAllByDevice(
+	ctx context.Context,
 	userID, uagent, fprint string,
 ) (
 	[]entity.RefreshSession, error,
 ) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
 	sessions := make([]entity.RefreshSession, 0)
 
-	rows, err := s.db.Query(query.FindSessionsByUserDevice, userID, uagent, fprint)
+	rows, err := s.db.QueryContext(ctx, query.FindSessionsByUserDevice, userID, uagent, fprint)
 	if err != nil {
 		return nil, err
 	}
@@ -64,11 +124,7 @@ func (s *Sessions) FindAllByDevice(
 	for rows.Next() {
 		var session entity.RefreshSession
 
-		err := rows.Scan(
-			&session.SessionID, &session.RefreshToken, &session.AccessToken,
-			&session.UserID, &session.Uagent, &session.Fprint, &session.ExpiresAt,
-			&session.CreatedAt,
-		)
+		err := rows.Scan(s.fields(&session)...)
 
 		if err != nil {
 			continue
@@ -84,14 +140,13 @@ func (s *Sessions) FindAllByDevice(
 	return sessions, nil
 }
 
-func (s *Sessions) FindBySessionID(sessionID int) (entity.RefreshSession, error) {
+func (s *Sessions) FindBySessionID(ctx context.Context, sessionID int) (entity.RefreshSession, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
 	var session entity.RefreshSession
 
-	err := s.db.QueryRow(query.FindSessionByID, sessionID).Scan(
-		&session.SessionID, &session.RefreshToken, &session.AccessToken,
-		&session.UserID, &session.Uagent, &session.Fprint, &session.ExpiresAt,
-		&session.CreatedAt,
-	)
+	err := s.db.QueryRowContext(ctx, query.FindSessionByID, sessionID).Scan(s.fields(&session)...)
 
 	if err != nil {
 		return entity.RefreshSession{}, err
@@ -100,14 +155,13 @@ func (s *Sessions) FindBySessionID(sessionID int) (entity.RefreshSession, error)
 	return session, nil
 }
 
-func (s *Sessions) FindByRefreshToken(token string) (entity.RefreshSession, error) {
+func (s *Sessions) FindByRefreshToken(ctx context.Context, token string) (entity.RefreshSession, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
 	var session entity.RefreshSession
 
-	err := s.db.QueryRow(query.FindSessionByRefreshToken, token).Scan(
-		&session.SessionID, &session.RefreshToken, &session.AccessToken,
-		&session.UserID, &session.Uagent, &session.Fprint, &session.ExpiresAt,
-		&session.CreatedAt,
-	)
+	err := s.db.QueryRowContext(ctx, query.FindSessionByRefreshToken, token).Scan(s.fields(&session)...)
 
 	if err != nil {
 		return entity.RefreshSession{}, err
@@ -118,15 +172,31 @@ func (s *Sessions) FindByRefreshToken(token string) (entity.RefreshSession, erro
 
 // Returns "session_id" and "refresh_token"
 func (s *Sessions) Create(
-	userID, uagent, fprint string, expriresAt time.Time,
+	ctx context.Context,
+	userID, uagent, fprint string, device entity.SessionDevice, expiresAt time.Time,
 ) (
 	int, string, error,
 ) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
 	var sessionID int
 	var refreshToken string
 
-	err := s.db.QueryRow(
-		query.CreateSession, userID, uagent, fprint, expriresAt,
+	err := s.db.QueryRowContext(
+		ctx,
+		query.CreateSession,
+		userID,
+		uagent,
+		fprint,
+		device.Browser,
+		device.BrowserVersion,
+		device.OS,
+		device.OSVersion,
+		device.Type,
+		device.Model,
+		device.AppVersion,
+		expiresAt,
 	).Scan(&sessionID, &refreshToken)
 
 	if err != nil {
@@ -136,14 +206,117 @@ func (s *Sessions) Create(
 	return sessionID, refreshToken, nil
 }
 
-func (s *Sessions) SetAccessToken(sessionID int, accessToken string) error {
-	return s.db.QueryRow(query.SetSessionAccessToken, accessToken, sessionID).Err()
+func (s *Sessions) SetAccessToken(ctx context.Context, sessionID int, accessToken string) error {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query.SetSessionAccessToken, accessToken, sessionID)
+	return err
+}
+
+func (s *Sessions) SetDevice(ctx context.Context, sessionID int, device entity.SessionDevice) error {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	_, err := s.db.ExecContext(
+		ctx, query.SetSessionDevice,
+		device.Browser, device.BrowserVersion, device.OS, device.OSVersion,
+		device.Type, device.Model, device.AppVersion, sessionID,
+	)
+	return err
+}
+
+func (s *Sessions) Drop(ctx context.Context, sessionID int) error {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query.DropSession, sessionID)
+	return err
+}
+
+func (s *Sessions) DropAll(ctx context.Context, userID string) error {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query.DropAllSessions, userID)
+	return err
+}
+
+func (s *Sessions) DropExpired(ctx context.Context) (int, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query.DropExpiredSessions)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// Scan destinations in the order of the selected columns
+func (s *Sessions) fields(session *entity.RefreshSession) []any {
+	return []any{
+		&session.SessionID,
+		&session.RefreshToken,
+		&session.AccessToken,
+		&session.UserID,
+		&session.Uagent,
+		&session.Fprint,
+		&session.Device.Browser,
+		&session.Device.BrowserVersion,
+		&session.Device.OS,
+		&session.Device.OSVersion,
+		&session.Device.Type,
+		&session.Device.Model,
+		&session.Device.AppVersion,
+		&session.ExpiresAt,
+		&session.CreatedAt,
+	}
 }
 
-func (s *Sessions) Drop(sessionID int) error {
-	return s.db.QueryRow(query.DropSession, sessionID).Err()
+// Applies the page filter to the sessions sorted by id ascending,
+// for the stores without a query language
+func filterSessions(all []entity.RefreshSession, f entity.SessionsFilter) []entity.RefreshSession {
+	if f.Desc {
+		sort.Slice(all, func(i, j int) bool {
+			return all[i].SessionID > all[j].SessionID
+		})
+	}
+
+	now := time.Now()
+	sessions := make([]entity.RefreshSession, 0)
+
+	for _, s := range all {
+		if f.Limit > 0 && len(sessions) == f.Limit {
+			break
+		}
+
+		expiresAt, _ := time.Parse(time.RFC3339, s.ExpiresAt)
+		createdAt, _ := time.Parse(time.RFC3339, s.CreatedAt)
+
+		switch {
+		case f.Status == "active" && !expiresAt.After(now),
+			f.Status == "expired" && expiresAt.After(now),
+			f.Device != "" && !matchesDevice(s.Device, f.Device),
+			f.CreatedAfter != nil && !createdAt.After(*f.CreatedAfter),
+			f.Cursor != 0 && f.Desc && s.SessionID >= f.Cursor,
+			f.Cursor != 0 && !f.Desc && s.SessionID <= f.Cursor:
+			continue
+		}
+
+		s.IsCurrent = s.SessionID == f.CurrentSessionID
+		sessions = append(sessions, s)
+	}
+
+	return sessions
 }
 
-func (s *Sessions) DropAll(userID string) error {
-	return s.db.QueryRow(query.DropAllSessions, userID).Err()
+// Tells if device is either the browser, os or type of the session's
+// device, ignoring case
+func matchesDevice(d entity.SessionDevice, device string) bool {
+	return strings.EqualFold(d.Browser, device) ||
+		strings.EqualFold(d.OS, device) ||
+		strings.EqualFold(d.Type, device)
 }
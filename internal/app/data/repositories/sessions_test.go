@@ -0,0 +1,490 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"testing"
+	"time"
+	"wildproject/internal/app/data/database"
+	entity "wildproject/internal/app/data/entities"
+	migration "wildproject/internal/app/data/migrations"
+	query "wildproject/internal/app/data/queries"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Postgres contract tests run only if the variable holds connection
+// string of a disposable database, migrations are applied to it
+const testDatabaseEnv = "TEST_DATABASE_CONN_STRING"
+
+type sessionsFactory struct {
+	name string
+	// Returns the repo and a function creating user sessions can belong to
+	new func(t *testing.T) (SessionsRepo, func() string)
+}
+
+var sessionsFactories = []sessionsFactory{
+	{"memory", newMemoryTestSessions},
+	{"redis", newRedisTestSessions},
+	{"postgres", newPostgresTestSessions},
+}
+
+func newMemoryTestSessions(t *testing.T) (SessionsRepo, func() string) {
+	s := NewMemoryStore()
+
+	return NewMemorySessions(s), testUserCreator(t, NewMemoryUsers(s))
+}
+
+func newRedisTestSessions(t *testing.T) (SessionsRepo, func() string) {
+	mr := miniredis.RunT(t)
+
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+
+	// Redis doesn't know about users, any id will do
+	return NewRedisSessions(rdb, "test:", time.Hour), uuid.NewString
+}
+
+func newPostgresTestSessions(t *testing.T) (SessionsRepo, func() string) {
+	conn := os.Getenv(testDatabaseEnv)
+	if conn == "" {
+		t.Skipf("%s is not set", testDatabaseEnv)
+	}
+
+	pg := database.NewPostgres()
+	if err := pg.Open(conn); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pg.Close() })
+
+	db, err := pg.Instance()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrations, err := migration.Embedded()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := migration.NewMigrator(db, migrations).Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	users := NewUsers(db, 5*time.Second)
+
+	return NewSessions(db, 5*time.Second), testUserCreator(t, users)
+}
+
+// Users are deleted after the test with their sessions
+func testUserCreator(t *testing.T, users UsersRepo) func() string {
+	return func() string {
+		ctx := context.Background()
+
+		userID, err := users.Create(ctx, uuid.NewString()+"@example.com", "hash")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		t.Cleanup(func() { users.Delete(ctx, userID) })
+
+		return userID
+	}
+}
+
+func forEachSessionsRepo(t *testing.T, test func(t *testing.T, r SessionsRepo, newUser func() string)) {
+	for _, f := range sessionsFactories {
+		t.Run(f.name, func(t *testing.T) {
+			r, newUser := f.new(t)
+			test(t, r, newUser)
+		})
+	}
+}
+
+func createTestSession(t *testing.T, r SessionsRepo, userID, uagent string) (int, string) {
+	sessionID, token, err := r.Create(
+		context.Background(), userID, uagent, "fprint",
+		entity.SessionDevice{Browser: "Firefox", OS: "Linux", Type: "desktop"},
+		time.Now().Add(time.Hour),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return sessionID, token
+}
+
+func TestSessionsCreateAndFind(t *testing.T) {
+	forEachSessionsRepo(t, func(t *testing.T, r SessionsRepo, newUser func() string) {
+		ctx := context.Background()
+		userID := newUser()
+
+		sessionID, token := createTestSession(t, r, userID, "uagent")
+
+		if err := r.SetAccessToken(ctx, sessionID, "access"); err != nil {
+			t.Fatal(err)
+		}
+
+		byID, err := r.FindBySessionID(ctx, sessionID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		byToken, err := r.FindByRefreshToken(ctx, token)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, s := range []entity.RefreshSession{byID, byToken} {
+			if s.SessionID != sessionID || s.UserID != userID || s.RefreshToken != token {
+				t.Fatalf("unexpected session %+v", s)
+			}
+
+			if s.AccessToken != "access" || s.Uagent != "uagent" || s.Fprint != "fprint" {
+				t.Fatalf("unexpected session %+v", s)
+			}
+
+			if s.Device.Browser != "Firefox" || s.Device.OS != "Linux" || s.Device.Type != "desktop" {
+				t.Fatalf("unexpected device %+v", s.Device)
+			}
+		}
+	})
+}
+
+func TestSessionsNotFound(t *testing.T) {
+	forEachSessionsRepo(t, func(t *testing.T, r SessionsRepo, newUser func() string) {
+		ctx := context.Background()
+		userID := newUser()
+
+		if _, err := r.FindBySessionID(ctx, 1<<30); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("FindBySessionID: expected sql.ErrNoRows, got %v", err)
+		}
+
+		if _, err := r.FindByRefreshToken(ctx, uuid.NewString()); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("FindByRefreshToken: expected sql.ErrNoRows, got %v", err)
+		}
+
+		if _, err := r.FindAllByDevice(ctx, userID, "uagent", "fprint"); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("FindAllByDevice: expected sql.ErrNoRows, got %v", err)
+		}
+
+		sessions, err := r.FindAllByUserID(ctx, userID)
+		if err != nil || len(sessions) != 0 {
+			t.Fatalf("FindAllByUserID: expected no sessions, got %v, %v", sessions, err)
+		}
+	})
+}
+
+func TestSessionsFindAll(t *testing.T) {
+	forEachSessionsRepo(t, func(t *testing.T, r SessionsRepo, newUser func() string) {
+		ctx := context.Background()
+		userID, otherID := newUser(), newUser()
+
+		createTestSession(t, r, userID, "phone")
+		createTestSession(t, r, userID, "laptop")
+		createTestSession(t, r, otherID, "phone")
+
+		all, err := r.FindAllByUserID(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(all) != 2 {
+			t.Fatalf("expected 2 sessions of the user, got %d", len(all))
+		}
+
+		device, err := r.FindAllByDevice(ctx, userID, "phone", "fprint")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(device) != 1 || device[0].UserID != userID || device[0].Uagent != "phone" {
+			t.Fatalf("unexpected device sessions %+v", device)
+		}
+	})
+}
+
+func TestSessionsFindPage(t *testing.T) {
+	forEachSessionsRepo(t, func(t *testing.T, r SessionsRepo, newUser func() string) {
+		ctx := context.Background()
+		userID := newUser()
+
+		ids := make([]int, 3)
+		for i := range ids {
+			ids[i], _ = createTestSession(t, r, userID, "uagent")
+		}
+
+		tests := []struct {
+			name   string
+			filter entity.SessionsFilter
+			want   []int
+		}{
+			{"desc first page", entity.SessionsFilter{Desc: true, Limit: 2}, []int{ids[2], ids[1]}},
+			{"desc next page", entity.SessionsFilter{Desc: true, Cursor: ids[1], Limit: 2}, []int{ids[0]}},
+			{"asc first page", entity.SessionsFilter{Limit: 2}, []int{ids[0], ids[1]}},
+			{"asc next page", entity.SessionsFilter{Cursor: ids[1], Limit: 2}, []int{ids[2]}},
+			{"no limit", entity.SessionsFilter{}, []int{ids[0], ids[1], ids[2]}},
+			{"active", entity.SessionsFilter{Status: "active", Limit: 10}, []int{ids[0], ids[1], ids[2]}},
+			{"expired", entity.SessionsFilter{Status: "expired", Limit: 10}, []int{}},
+			{"browser", entity.SessionsFilter{Device: "firefox", Limit: 10}, []int{ids[0], ids[1], ids[2]}},
+			{"os", entity.SessionsFilter{Device: "LINUX", Limit: 10}, []int{ids[0], ids[1], ids[2]}},
+			{"device type", entity.SessionsFilter{Device: "desktop", Limit: 10}, []int{ids[0], ids[1], ids[2]}},
+			{"unknown device", entity.SessionsFilter{Device: "phone", Limit: 10}, []int{}},
+			{"user agent is not matched", entity.SessionsFilter{Device: "uagent", Limit: 10}, []int{}},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				tt.filter.UserID = userID
+				tt.filter.CurrentSessionID = ids[0]
+
+				page, err := r.FindPage(ctx, tt.filter)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				got := make([]int, 0, len(page))
+				for _, s := range page {
+					got = append(got, s.SessionID)
+
+					if s.IsCurrent != (s.SessionID == ids[0]) {
+						t.Fatalf("session %d has is_current %v", s.SessionID, s.IsCurrent)
+					}
+				}
+
+				if !equalInts(got, tt.want) {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			})
+		}
+	})
+}
+
+// Sessions created before devices were parsed get them stored later
+func TestSessionsSetDevice(t *testing.T) {
+	forEachSessionsRepo(t, func(t *testing.T, r SessionsRepo, newUser func() string) {
+		ctx := context.Background()
+		userID := newUser()
+
+		sessionID, _, err := r.Create(ctx, userID, "uagent", "fprint", entity.SessionDevice{}, time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		filter := entity.SessionsFilter{UserID: userID, Device: "firefox"}
+
+		if page, _ := r.FindPage(ctx, filter); len(page) != 0 {
+			t.Fatalf("expected session without device not to match, got %+v", page)
+		}
+
+		device := entity.SessionDevice{Browser: "Firefox", BrowserVersion: "120", OS: "Linux", Type: "desktop"}
+		if err := r.SetDevice(ctx, sessionID, device); err != nil {
+			t.Fatal(err)
+		}
+
+		page, err := r.FindPage(ctx, filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(page) != 1 || page[0].Device != device {
+			t.Fatalf("expected session with the stored device, got %+v", page)
+		}
+
+		// Dropped sessions are not brought back
+		if err := r.Drop(ctx, sessionID); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := r.SetDevice(ctx, sessionID, device); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := r.FindBySessionID(ctx, sessionID); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("expected dropped session to stay dropped, got %v", err)
+		}
+	})
+}
+
+func TestSessionsDrop(t *testing.T) {
+	forEachSessionsRepo(t, func(t *testing.T, r SessionsRepo, newUser func() string) {
+		ctx := context.Background()
+		userID, otherID := newUser(), newUser()
+
+		dropped, token := createTestSession(t, r, userID, "uagent")
+		kept, _ := createTestSession(t, r, userID, "uagent")
+		other, _ := createTestSession(t, r, otherID, "uagent")
+
+		if err := r.Drop(ctx, dropped); err != nil {
+			t.Fatal(err)
+		}
+
+		// Dropping twice is not an error
+		if err := r.Drop(ctx, dropped); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := r.FindBySessionID(ctx, dropped); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("dropped session is found by id: %v", err)
+		}
+
+		if _, err := r.FindByRefreshToken(ctx, token); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("dropped session is found by token: %v", err)
+		}
+
+		if _, err := r.FindBySessionID(ctx, kept); err != nil {
+			t.Fatalf("kept session is not found: %v", err)
+		}
+
+		if err := r.DropAll(ctx, userID); err != nil {
+			t.Fatal(err)
+		}
+
+		sessions, err := r.FindAllByUserID(ctx, userID)
+		if err != nil || len(sessions) != 0 {
+			t.Fatalf("expected no sessions after DropAll, got %v, %v", sessions, err)
+		}
+
+		if _, err := r.FindBySessionID(ctx, other); err != nil {
+			t.Fatalf("session of another user is dropped: %v", err)
+		}
+	})
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Expired sessions are kept by every driver (Redis for the retention
+// window), so they are listed and refreshing them is told apart from
+// using an unknown token. The refresh drops them then
+func TestSessionsExpiredAreKept(t *testing.T) {
+	forEachSessionsRepo(t, func(t *testing.T, r SessionsRepo, newUser func() string) {
+		ctx := context.Background()
+		userID := newUser()
+
+		activeID, _ := createTestSession(t, r, userID, "uagent")
+
+		expiredID, token, err := r.Create(
+			ctx, userID, "uagent", "fprint",
+			entity.SessionDevice{Browser: "Firefox", OS: "Linux", Type: "desktop"},
+			time.Now().Add(-time.Minute),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		session, err := r.FindByRefreshToken(ctx, token)
+		if err != nil {
+			t.Fatalf("expected expired session to be found, got %v", err)
+		}
+
+		if session.SessionID != expiredID {
+			t.Fatalf("expected session %d, got %d", expiredID, session.SessionID)
+		}
+
+		statuses := []struct {
+			status string
+			want   []int
+		}{
+			{"expired", []int{expiredID}},
+			{"active", []int{activeID}},
+			{"", []int{activeID, expiredID}},
+		}
+
+		for _, tt := range statuses {
+			page, err := r.FindPage(ctx, entity.SessionsFilter{UserID: userID, Status: tt.status, Limit: 10})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make([]int, 0, len(page))
+			for _, s := range page {
+				got = append(got, s.SessionID)
+			}
+
+			if !equalInts(got, tt.want) {
+				t.Fatalf("status %q: got %v, want %v", tt.status, got, tt.want)
+			}
+		}
+
+		if err := r.Drop(ctx, expiredID); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := r.FindByRefreshToken(ctx, token); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("expected dropped expired session to be gone, got %v", err)
+		}
+	})
+}
+
+// Page query runs in every environment, the SQL itself is checked by
+// the contract tests above when Postgres is available
+func TestSessionsFindPageQuery(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	createdAfter := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	filter := entity.SessionsFilter{
+		UserID:           "user",
+		CurrentSessionID: 7,
+		Status:           "active",
+		Device:           "Firefox",
+		CreatedAfter:     &createdAfter,
+		Cursor:           9,
+		Desc:             true,
+		Limit:            2,
+	}
+
+	columns := []string{
+		"session_id", "refresh_token", "access_token", "user_id", "user_agent", "fingerprint",
+		"browser", "browser_version", "os", "os_version", "device_type", "device_model", "app_version",
+		"expires_at", "created_at", "is_current",
+	}
+
+	mock.ExpectQuery(query.FindSessionsPage).
+		WithArgs("user", 7, "active", "Firefox", createdAfter, 9, true, 2).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(8, "rt8", "at8", "user", "uagent", "fprint", "Firefox", "120", "Linux", "", "desktop", "", "", "exp", "created", false).
+			AddRow(7, "rt7", "at7", "user", "uagent", "fprint", "Firefox", "120", "Linux", "", "desktop", "", "", "exp", "created", true))
+
+	page, err := NewSessions(db, time.Second).FindPage(context.Background(), filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(page) != 2 || page[0].SessionID != 8 || page[1].SessionID != 7 {
+		t.Fatalf("unexpected page %+v", page)
+	}
+
+	if page[0].IsCurrent || !page[1].IsCurrent {
+		t.Fatal("expected only the second session to be current")
+	}
+
+	want := entity.SessionDevice{Browser: "Firefox", BrowserVersion: "120", OS: "Linux", Type: "desktop"}
+	if page[0].Device != want || page[0].RefreshToken != "rt8" || page[0].Uagent != "uagent" {
+		t.Fatalf("unexpected session %+v", page[0])
+	}
+}
@@ -0,0 +1,67 @@
+package repo
+
+import (
+	"context"
+	"time"
+	"wildproject/internal/app/data/database"
+	entity "wildproject/internal/app/data/entities"
+	query "wildproject/internal/app/data/queries"
+)
+
+type SigningKeys struct {
+	db      database.Executor
+	timeout time.Duration
+}
+
+func NewSigningKeys(db database.Executor, timeout time.Duration) *SigningKeys {
+	return &SigningKeys{db, timeout}
+}
+
+func (k *SigningKeys) Create(ctx context.Context, keyID string, secret []byte) error {
+	ctx, cancel := withTimeout(ctx, k.timeout)
+	defer cancel()
+
+	_, err := k.db.ExecContext(ctx, query.CreateSigningKey, keyID, secret)
+	return err
+}
+
+// Returns all the keys, newest first
+func (k *SigningKeys) FindAll(ctx context.Context) ([]entity.SigningKey, error) {
+	ctx, cancel := withTimeout(ctx, k.timeout)
+	defer cancel()
+
+	rows, err := k.db.QueryContext(ctx, query.FindSigningKeys)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]entity.SigningKey, 0)
+
+	for rows.Next() {
+		var key entity.SigningKey
+
+		if err := rows.Scan(&key.KeyID, &key.Secret, &key.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// Deletes keys replaced by a newer one before "before", returns the
+// number of deleted keys
+func (k *SigningKeys) DropSuperseded(ctx context.Context, before time.Time) (int, error) {
+	ctx, cancel := withTimeout(ctx, k.timeout)
+	defer cancel()
+
+	res, err := k.db.ExecContext(ctx, query.DropSupersededSigningKeys, before)
+	if err != nil {
+		return 0, err
+	}
+
+	dropped, err := res.RowsAffected()
+	return int(dropped), err
+}
@@ -1,30 +1,102 @@
 package repo
 
 import (
+	"context"
 	"time"
 	entity "wildproject/internal/app/data/entities"
 )
 
 type SessionsRepo interface {
-	FindAllByUserID(userID string) ([]entity.RefreshSession, error)
-	FindAllByDevice(userID, uagent, fprint string) ([]entity.RefreshSession, error)
-	FindBySessionID(sessionID int) (entity.RefreshSession, error)
-	FindByRefreshToken(token string) (entity.RefreshSession, error)
-	Create(userID, uagent, fprint string, expriresAt time.Time) (int, string, error)
-	SetAccessToken(sessionID int, accessToken string) error
-	Drop(sessionID int) error
-	DropAll(userID string) error
+	FindAllByUserID(ctx context.Context, userID string) ([]entity.RefreshSession, error)
+	FindAllByDevice(ctx context.Context, userID, uagent, fprint string) ([]entity.RefreshSession, error)
+	FindPage(ctx context.Context, f entity.SessionsFilter) ([]entity.RefreshSession, error)
+	FindBySessionID(ctx context.Context, sessionID int) (entity.RefreshSession, error)
+	FindByRefreshToken(ctx context.Context, token string) (entity.RefreshSession, error)
+	Create(
+		ctx context.Context,
+		userID, uagent, fprint string,
+		device entity.SessionDevice,
+		expiresAt time.Time,
+	) (int, string, error)
+	SetAccessToken(ctx context.Context, sessionID int, accessToken string) error
+	SetDevice(ctx context.Context, sessionID int, device entity.SessionDevice) error
+	Drop(ctx context.Context, sessionID int) error
+	DropAll(ctx context.Context, userID string) error
+	// Returns number of dropped sessions
+	DropExpired(ctx context.Context) (int, error)
 }
 
 type UsersRepo interface {
-	FindByID(userID string) (entity.User, error)
-	FindByEmail(email string) (entity.User, error)
-	FindDetailedByID(userID string) (entity.UserDetailed, error)
-	CountByEmail(email string) (int, error)
-	Create(email, passwordHash string) (string, error)
-	ChangeName(userID, value string) error
-	ChangeSex(userID string, value int) error
-	ChangeEmail(userID, value string) error
-	ChangePasswordHash(userID, value string) error
-	ChangeImageURL(userID, value string) error
+	FindByID(ctx context.Context, userID string) (entity.User, error)
+	FindByEmail(ctx context.Context, email string) (entity.User, error)
+	FindDetailedByID(ctx context.Context, userID string) (entity.UserDetailed, error)
+	CountByEmail(ctx context.Context, email string) (int, error)
+	Create(ctx context.Context, email, passwordHash string) (string, error)
+	ChangeName(ctx context.Context, userID, value string) error
+	ChangeSex(ctx context.Context, userID string, value int) error
+	ChangeEmail(ctx context.Context, userID, value string) error
+	ChangePasswordHash(ctx context.Context, userID, value string) error
+	ChangeImageVariants(ctx context.Context, userID string, value []entity.ImageVariant) error
+	ScheduleDeletion(ctx context.Context, userID string, at time.Time) error
+	CancelDeletion(ctx context.Context, userID string) error
+	FindDueDeletion(ctx context.Context, limit int) ([]string, error)
+	FindDetailedDueDeletion(ctx context.Context, userID string) (entity.UserDetailed, error)
+	Anonymize(ctx context.Context, userID string) error
+	Delete(ctx context.Context, userID string) error
+}
+
+type PasswordHistoryRepo interface {
+	FindLatest(ctx context.Context, userID string, limit int) ([]entity.PasswordHistory, error)
+	Create(ctx context.Context, userID, passwordHash string) error
+	Prune(ctx context.Context, userID string, keep int) error
+}
+
+type DataExportsRepo interface {
+	FindByID(ctx context.Context, exportID string) (entity.DataExport, error)
+	FindByToken(ctx context.Context, token string) (entity.DataExport, error)
+	FindActive(ctx context.Context, userID string) (entity.DataExport, error)
+	Create(ctx context.Context, userID, format string) (string, error)
+	Claim(ctx context.Context) (entity.DataExport, error)
+	RequeueStale(ctx context.Context, startedBefore time.Time) error
+	Complete(ctx context.Context, exportID, objectKey, token string, expiresAt time.Time) error
+	Fail(ctx context.Context, exportID, reason string) error
+	FindReadyByUserID(ctx context.Context, userID string) ([]entity.DataExport, error)
+	FindExpired(ctx context.Context, limit int) ([]entity.DataExport, error)
+	Expire(ctx context.Context, exportID string) error
+}
+
+type AuditEventsRepo interface {
+	Create(ctx context.Context, e entity.AuditEvent) error
+	FindAllByUserID(ctx context.Context, userID string, before int64, limit int) ([]entity.AuditEvent, error)
+	AnonymizeByUserID(ctx context.Context, userID string) error
+}
+
+type EmailChangesRepo interface {
+	FindByConfirmToken(ctx context.Context, token string) (entity.EmailChange, error)
+	FindByCancelToken(ctx context.Context, token string) (entity.EmailChange, error)
+	Create(
+		ctx context.Context,
+		userID, email, confirmToken, cancelToken string,
+		expiresAt time.Time,
+	) (string, error)
+	Delete(ctx context.Context, changeID string) error
+	DeleteAllByUserID(ctx context.Context, userID string) error
+}
+
+type SigningKeysRepo interface {
+	Create(ctx context.Context, keyID string, secret []byte) error
+	FindAll(ctx context.Context) ([]entity.SigningKey, error)
+	DropSuperseded(ctx context.Context, before time.Time) (int, error)
+}
+
+type UserRolesRepo interface {
+	Grant(ctx context.Context, userID string, roles []string) error
+	FindAll(ctx context.Context, userID string) ([]string, error)
+}
+
+// Runs operations spanning several repos atomically
+type UnitOfWork interface {
+	// Runs "fn" with the repos bound to a single transaction, which is
+	// committed if "fn" returns nil and rolled back otherwise
+	Do(ctx context.Context, fn func(r Repositories) error) error
 }
@@ -0,0 +1,32 @@
+package repo
+
+import (
+	"context"
+	"time"
+	"wildproject/internal/app/data/database"
+)
+
+type PostgresUnitOfWork struct {
+	db      database.Instance
+	timeout time.Duration
+	// Sessions stored outside of the database, nil if they are in it.
+	// Such sessions are not the part of the transaction
+	sessions SessionsRepo
+}
+
+func NewPostgresUnitOfWork(
+	db database.Instance, timeout time.Duration, sessions SessionsRepo,
+) *PostgresUnitOfWork {
+	return &PostgresUnitOfWork{db, timeout, sessions}
+}
+
+func (u *PostgresUnitOfWork) Do(ctx context.Context, fn func(r Repositories) error) error {
+	return database.InTx(ctx, u.db, func(tx database.Executor) error {
+		repos := NewPostgresRepositories(tx, u.timeout)
+		if u.sessions != nil {
+			repos.Sessions = u.sessions
+		}
+
+		return fn(repos)
+	})
+}
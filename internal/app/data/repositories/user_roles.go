@@ -0,0 +1,53 @@
+package repo
+
+import (
+	"context"
+	"time"
+	"wildproject/internal/app/data/database"
+	query "wildproject/internal/app/data/queries"
+
+	"github.com/lib/pq"
+)
+
+type UserRoles struct {
+	db      database.Executor
+	timeout time.Duration
+}
+
+func NewUserRoles(db database.Executor, timeout time.Duration) *UserRoles {
+	return &UserRoles{db, timeout}
+}
+
+// Adds the roles to the user's ones, granted roles are kept
+func (r *UserRoles) Grant(ctx context.Context, userID string, roles []string) error {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	_, err := r.db.ExecContext(ctx, query.GrantUserRoles, userID, pq.Array(roles))
+	return err
+}
+
+// Returns user's roles ordered by name
+func (r *UserRoles) FindAll(ctx context.Context, userID string) ([]string, error) {
+	ctx, cancel := withTimeout(ctx, r.timeout)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx, query.FindUserRoles, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := make([]string, 0)
+
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return nil, err
+		}
+
+		roles = append(roles, role)
+	}
+
+	return roles, rows.Err()
+}
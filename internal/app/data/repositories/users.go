@@ -1,7 +1,11 @@
 package repo
 
 import (
+	"context"
+	"database/sql"
+	"encoding/json"
 	"errors"
+	"time"
 	"wildproject/internal/app/data/database"
 	entity "wildproject/internal/app/data/entities"
 	query "wildproject/internal/app/data/queries"
@@ -12,18 +16,23 @@ var (
 )
 
 type Users struct {
-	db database.Instance
+	db      database.Executor
+	timeout time.Duration
 }
 
-func NewUsers(db database.Instance) *Users {
-	return &Users{db}
+func NewUsers(db database.Executor, timeout time.Duration) *Users {
+	return &Users{db, timeout}
 }
 
-func (u *Users) FindByID(userID string) (entity.User, error) {
+func (u *Users) FindByID(ctx context.Context, userID string) (entity.User, error) {
+	ctx, cancel := withTimeout(ctx, u.timeout)
+	defer cancel()
+
 	var user entity.User
 
-	err := u.db.QueryRow(query.FindUserByID, userID).Scan(
+	err := u.db.QueryRowContext(ctx, query.FindUserByID, userID).Scan(
 		&user.ID, &user.Email, &user.PasswordHash, &user.CreatedAt, &user.UpdatetdAt,
+		&user.DeleteAfter,
 	)
 
 	if err != nil {
@@ -33,11 +42,15 @@ func (u *Users) FindByID(userID string) (entity.User, error) {
 	return user, nil
 }
 
-func (u *Users) FindByEmail(email string) (entity.User, error) {
+func (u *Users) FindByEmail(ctx context.Context, email string) (entity.User, error) {
+	ctx, cancel := withTimeout(ctx, u.timeout)
+	defer cancel()
+
 	var user entity.User
 
-	err := u.db.QueryRow(query.FindUserByEmail, email).Scan(
+	err := u.db.QueryRowContext(ctx, query.FindUserByEmail, email).Scan(
 		&user.ID, &user.Email, &user.PasswordHash, &user.CreatedAt, &user.UpdatetdAt,
+		&user.DeleteAfter,
 	)
 
 	if err != nil {
@@ -47,25 +60,50 @@ func (u *Users) FindByEmail(email string) (entity.User, error) {
 	return user, nil
 }
 
-func (u *Users) FindDetailedByID(userID string) (entity.UserDetailed, error) {
+func (u *Users) FindDetailedByID(ctx context.Context, userID string) (entity.UserDetailed, error) {
+	ctx, cancel := withTimeout(ctx, u.timeout)
+	defer cancel()
+
+	return scanDetailedUser(u.db.QueryRowContext(ctx, query.FindDetailedUserByID, userID))
+}
+
+// Finds user only if their deletion grace period is over. The user row
+// is locked until the transaction ends, so the deletion can't be canceled
+// meanwhile
+func (u *Users) FindDetailedDueDeletion(ctx context.Context, userID string) (entity.UserDetailed, error) {
+	ctx, cancel := withTimeout(ctx, u.timeout)
+	defer cancel()
+
+	return scanDetailedUser(u.db.QueryRowContext(ctx, query.FindDetailedUserDueDeletion, userID))
+}
+
+func scanDetailedUser(row *sql.Row) (entity.UserDetailed, error) {
 	var user entity.UserDetailed
+	var variants []byte
 
-	err := u.db.QueryRow(query.FindDetailedUserByID, userID).Scan(
+	err := row.Scan(
 		&user.ID, &user.Email, &user.PasswordHash, &user.CreatedAt,
-		&user.UpdatetdAt, &user.SexID, &user.Name, &user.ImageURL,
+		&user.UpdatetdAt, &user.DeleteAfter, &user.SexID, &user.Name, &variants,
 	)
 
 	if err != nil {
 		return entity.UserDetailed{}, err
 	}
 
+	if err := json.Unmarshal(variants, &user.ImageVariants); err != nil {
+		return entity.UserDetailed{}, err
+	}
+
 	return user, nil
 }
 
-func (u *Users) CountByEmail(email string) (int, error) {
+func (u *Users) CountByEmail(ctx context.Context, email string) (int, error) {
+	ctx, cancel := withTimeout(ctx, u.timeout)
+	defer cancel()
+
 	var count int
 
-	err := u.db.QueryRow(query.CountUsersByEmail, email).Scan(&count)
+	err := u.db.QueryRowContext(ctx, query.CountUsersByEmail, email).Scan(&count)
 	if err != nil {
 		return 0, err
 	}
@@ -73,43 +111,164 @@ func (u *Users) CountByEmail(email string) (int, error) {
 	return count, nil
 }
 
-// Creates user with corresponding user_info
+// Creates user with corresponding user_info in a single transaction
 //
 // Returns "user_id" of created user or "" in error case
-func (u *Users) Create(email, passwordHash string) (string, error) {
+func (u *Users) Create(ctx context.Context, email, passwordHash string) (string, error) {
+	ctx, cancel := withTimeout(ctx, u.timeout)
+	defer cancel()
+
 	var userID string
 
-	row := u.db.QueryRow(query.CreateUser, email, passwordHash)
-	err := row.Scan(&userID)
+	err := database.InTx(ctx, u.db, func(tx database.Executor) error {
+		err := tx.QueryRowContext(ctx, query.CreateUser, email, passwordHash).Scan(&userID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, query.CreateUserInfo, userID)
+		return err
+	})
 
 	if err != nil {
 		return "", err
 	}
 
-	err = u.db.QueryRow(query.CreateUserInfo, userID).Err()
+	return userID, nil
+}
+
+func (u *Users) ChangeName(ctx context.Context, userID, value string) error {
+	ctx, cancel := withTimeout(ctx, u.timeout)
+	defer cancel()
+
+	_, err := u.db.ExecContext(ctx, query.UpdateUserName, value, userID)
+	return err
+}
+
+func (u *Users) ChangeSex(ctx context.Context, userID string, value int) error {
+	ctx, cancel := withTimeout(ctx, u.timeout)
+	defer cancel()
+
+	_, err := u.db.ExecContext(ctx, query.UpdateUserSex, value, userID)
+	return err
+}
+
+func (u *Users) ChangeEmail(ctx context.Context, userID, value string) error {
+	ctx, cancel := withTimeout(ctx, u.timeout)
+	defer cancel()
+
+	_, err := u.db.ExecContext(ctx, query.UpdateUserEmail, value, userID)
+	return err
+}
+
+func (u *Users) ChangePasswordHash(ctx context.Context, userID, value string) error {
+	ctx, cancel := withTimeout(ctx, u.timeout)
+	defer cancel()
+
+	_, err := u.db.ExecContext(ctx, query.UpdateUserPasswordHash, value, userID)
+	return err
+}
+
+func (u *Users) ChangeImageVariants(ctx context.Context, userID string, value []entity.ImageVariant) error {
+	ctx, cancel := withTimeout(ctx, u.timeout)
+	defer cancel()
+
+	variants, err := json.Marshal(value)
 	if err != nil {
-		return "", err
+		return err
 	}
 
-	return userID, nil
+	_, err = u.db.ExecContext(ctx, query.UpdateUserImgVariants, variants, userID)
+	return err
 }
 
-func (u *Users) ChangeName(userID, value string) error {
-	return u.db.QueryRow(query.UpdateUserName, value, userID).Err()
+func (u *Users) ScheduleDeletion(ctx context.Context, userID string, at time.Time) error {
+	ctx, cancel := withTimeout(ctx, u.timeout)
+	defer cancel()
+
+	_, err := u.db.ExecContext(ctx, query.ScheduleUserDeletion, at, userID)
+	return err
 }
 
-func (u *Users) ChangeSex(userID string, value int) error {
-	return u.db.QueryRow(query.UpdateUserSex, value, userID).Err()
+// Cancels deletion only during the grace period.
+//
+// Returns sql.ErrNoRows if the period is over or deletion isn't scheduled
+func (u *Users) CancelDeletion(ctx context.Context, userID string) error {
+	ctx, cancel := withTimeout(ctx, u.timeout)
+	defer cancel()
+
+	res, err := u.db.ExecContext(ctx, query.CancelUserDeletion, userID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
 }
 
-func (u *Users) ChangeEmail(userID, value string) error {
-	return u.db.QueryRow(query.UpdateUserEmail, value, userID).Err()
+// Returns up to "limit" ids of users whose deletion grace period is over
+func (u *Users) FindDueDeletion(ctx context.Context, limit int) ([]string, error) {
+	ctx, cancel := withTimeout(ctx, u.timeout)
+	defer cancel()
+
+	rows, err := u.db.QueryContext(ctx, query.FindUsersDueDeletion, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]string, 0)
+
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
 }
 
-func (u *Users) ChangePasswordHash(userID, value string) error {
-	return u.db.QueryRow(query.UpdateUserPasswordHash, value, userID).Err()
+// Erases user's personal data keeping the row to preserve references
+func (u *Users) Anonymize(ctx context.Context, userID string) error {
+	ctx, cancel := withTimeout(ctx, u.timeout)
+	defer cancel()
+
+	return database.InTx(ctx, u.db, func(tx database.Executor) error {
+		if _, err := tx.ExecContext(ctx, query.AnonymizeUserInfo, userID); err != nil {
+			return err
+		}
+
+		// Pending email changes hold the new address
+		if _, err := tx.ExecContext(ctx, query.DeleteEmailChangesByUserID, userID); err != nil {
+			return err
+		}
+
+		// Anonymized account keeps no privileges
+		if _, err := tx.ExecContext(ctx, query.DeleteUserRolesByUserID, userID); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, query.AnonymizeUser, userID)
+		return err
+	})
 }
 
-func (u *Users) ChangeImageURL(userID, value string) error {
-	return u.db.QueryRow(query.UpdateUserImgURL, value, userID).Err()
+// Deletes user with all the related data
+func (u *Users) Delete(ctx context.Context, userID string) error {
+	ctx, cancel := withTimeout(ctx, u.timeout)
+	defer cancel()
+
+	_, err := u.db.ExecContext(ctx, query.DeleteUser, userID)
+	return err
 }
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"strings"
+)
+
+const (
+	DriverLocal = "local"
+	DriverS3    = "s3"
+)
+
+var (
+	ErrInvalidKey    = errors.New("invalid object key")
+	ErrNotFound      = errors.New("object not found")
+	ErrUnknownDriver = errors.New("unknown storage driver")
+)
+
+// Object storage, where objects are addressed by slash separated keys.
+// Objects are not publicly available, so they can be read only through
+// the app, e.g. data export archives
+type Objects interface {
+	Put(key string, r io.Reader, size int64, contentType string) error
+	// Returns ErrNotFound if there is no such object
+	Get(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
+
+// Object storage, where objects are publicly available by URL
+type Storage interface {
+	Objects
+	// Returns public URL of the object
+	URL(key string) string
+	// Returns key of the object by its public URL, if URL belongs to storage
+	Key(url string) (string, bool)
+}
+
+// Base for the implementations resolving keys against public base URL
+type publicURL string
+
+func (p publicURL) URL(key string) string {
+	return strings.TrimRight(string(p), "/") + "/" + key
+}
+
+func (p publicURL) Key(url string) (string, bool) {
+	prefix := strings.TrimRight(string(p), "/") + "/"
+	if !strings.HasPrefix(url, prefix) {
+		return "", false
+	}
+
+	key := strings.TrimPrefix(url, prefix)
+	if validateKey(key) != nil {
+		return "", false
+	}
+
+	return key, true
+}
+
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") {
+		return ErrInvalidKey
+	}
+
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return ErrInvalidKey
+		}
+	}
+
+	return nil
+}
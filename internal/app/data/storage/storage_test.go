@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/johannesboyne/gofakes3"
+	"github.com/johannesboyne/gofakes3/backend/s3mem"
+)
+
+const testBucket = "avatars"
+
+func newTestS3(t *testing.T) *S3 {
+	t.Helper()
+
+	backend := s3mem.New()
+	if err := backend.CreateBucket(testBucket); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(gofakes3.New(backend).Server())
+	t.Cleanup(server.Close)
+
+	return NewS3(S3Options{
+		Endpoint:  server.URL,
+		Region:    "us-east-1",
+		Bucket:    testBucket,
+		AccessKey: "access",
+		SecretKey: "secret",
+		PathStyle: true,
+		PublicURL: "https://cdn.example.com",
+	})
+}
+
+func TestStorageRoundTrip(t *testing.T) {
+	storages := map[string]func(t *testing.T) Storage{
+		"local": func(t *testing.T) Storage {
+			return NewLocal(t.TempDir(), "https://cdn.example.com")
+		},
+		"s3": func(t *testing.T) Storage {
+			return newTestS3(t)
+		},
+	}
+
+	for name, newStorage := range storages {
+		t.Run(name, func(t *testing.T) {
+			st := newStorage(t)
+			key := "avatars/user/64.jpg"
+			data := []byte("not really a jpeg")
+
+			if err := st.Put(key, bytes.NewReader(data), int64(len(data)), "image/jpeg"); err != nil {
+				t.Fatalf("put: %s", err)
+			}
+
+			r, err := st.Get(key)
+			if err != nil {
+				t.Fatalf("get: %s", err)
+			}
+
+			got, err := io.ReadAll(r)
+			r.Close()
+			if err != nil {
+				t.Fatalf("read: %s", err)
+			}
+
+			if !bytes.Equal(got, data) {
+				t.Fatalf("got %q, want %q", got, data)
+			}
+
+			if err := st.Delete(key); err != nil {
+				t.Fatalf("delete: %s", err)
+			}
+
+			if _, err := st.Get(key); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("get deleted: expected ErrNotFound, got %v", err)
+			}
+
+			// Deleting missing object is not an error
+			if err := st.Delete(key); err != nil {
+				t.Fatalf("delete missing: %s", err)
+			}
+		})
+	}
+}
+
+func TestStorageRejectsInvalidKeys(t *testing.T) {
+	st := NewLocal(t.TempDir(), "https://cdn.example.com")
+
+	for _, key := range []string{"", "/abs", "a//b", "../escape", "a/./b"} {
+		if err := st.Put(key, bytes.NewReader(nil), 0, "text/plain"); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("key %q: expected ErrInvalidKey, got %v", key, err)
+		}
+	}
+}
+
+func TestPublicURLKey(t *testing.T) {
+	p := publicURL("https://cdn.example.com/")
+
+	if url := p.URL("a/b.jpg"); url != "https://cdn.example.com/a/b.jpg" {
+		t.Fatalf("unexpected url %q", url)
+	}
+
+	if key, ok := p.Key("https://cdn.example.com/a/b.jpg"); !ok || key != "a/b.jpg" {
+		t.Fatalf("unexpected key %q, %v", key, ok)
+	}
+
+	for _, url := range []string{"https://other.com/a.jpg", "https://cdn.example.com/../a.jpg"} {
+		if _, ok := p.Key(url); ok {
+			t.Errorf("url %q resolved to a key", url)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package app
+
+import (
+	"time"
+	"wildproject/internal/app/data/database"
+	repo "wildproject/internal/app/data/repositories"
+	"wildproject/internal/app/data/storage"
+	event "wildproject/internal/app/domain/events"
+	manager "wildproject/internal/app/domain/managers"
+	model "wildproject/internal/app/domain/models"
+	service "wildproject/internal/app/domain/services"
+	job "wildproject/internal/app/jobs"
+	"wildproject/internal/app/mail"
+)
+
+// Config the file and env are applied on top of. Secrets and public
+// URLs have no sensible defaults and must be set explicitly
+func defaultConfig() model.Config {
+	return model.Config{
+		Name: "wildproject",
+		Env:  "development",
+		Database: model.DatabaseConfig{
+			Driver:       database.DriverPostgres,
+			QueryTimeout: 5 * time.Second,
+		},
+		Server: model.ServerConfig{
+			Addr:            ":8000",
+			ReadTimeout:     10 * time.Second,
+			WriteTimeout:    10 * time.Second,
+			IdleTimeout:     60 * time.Second,
+			ShutdownDelay:   5 * time.Second,
+			ShutdownTimeout: 20 * time.Second,
+		},
+		Auth: model.AuthConfig{
+			AccessTokenTTL:   15 * time.Minute,
+			RefreshTokenTTL:  30 * 24 * time.Hour,
+			SessionCacheSize: 10000,
+			SessionCacheTTL:  30 * time.Second,
+		},
+		Sessions: model.SessionsConfig{
+			Driver:                repo.SessionsDriverDatabase,
+			RedisExpiredRetention: 7 * 24 * time.Hour,
+		},
+		Password: model.PasswordConfig{
+			MinLength:     8,
+			MaxLength:     72,
+			RequireLower:  true,
+			RequireUpper:  true,
+			RequireDigit:  true,
+			MaxRepeated:   3,
+			DenyPersonal:  true,
+			MinStrength:   2,
+			HistorySize:   5,
+			HashAlgorithm: manager.HashAlgorithmArgon2id,
+			Argon2Memory:  64 * 1024,
+			Argon2Time:    3,
+			Argon2Threads: 4,
+			BcryptCost:    12,
+		},
+		Users: model.UsersConfig{
+			DeletionGracePeriod: 30 * 24 * time.Hour,
+			DeletionMode:        job.DeletionModeAnonymize,
+			DeletionInterval:    time.Hour,
+			EmailChangeTTL:      24 * time.Hour,
+			Roles:               []string{service.RoleAdmin, "support"},
+		},
+		Storage: model.StorageConfig{
+			Driver:     storage.DriverLocal,
+			LocalDir:   "./storage",
+			PrivateDir: "./storage-private",
+		},
+		Avatar: model.AvatarConfig{
+			MaxSize:      5 * 1024 * 1024,
+			AllowedTypes: []string{"image/jpeg", "image/png"},
+			MaxPixels:    25_000_000,
+			Sizes:        []int{64, 128, 256},
+			Quality:      85,
+		},
+		Export: model.ExportConfig{
+			LinkTTL:      24 * time.Hour,
+			PollInterval: 10 * time.Second,
+		},
+		Mail: model.MailConfig{
+			Driver: mail.DriverLog,
+		},
+		Events: model.EventsConfig{
+			Driver:  event.DriverLocal,
+			Channel: "events",
+		},
+		Sentry: model.SentryConfig{
+			AttachStackTrace: true,
+		},
+	}
+}
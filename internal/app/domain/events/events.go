@@ -0,0 +1,100 @@
+package event
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	DriverLocal    = "local"
+	DriverPostgres = "postgres"
+)
+
+const (
+	UserDeleted         = "user.deleted"
+	DataExportRequested = "data_export.requested"
+	SessionRevoked      = "session.revoked"
+	// Session is replaced by the refreshed one, not shown to the clients
+	SessionInvalidated = "session.invalidated"
+	PasswordChanged    = "password.changed"
+	EmailChanged       = "email.changed"
+	// Access tokens are signed with a new key. Has no user
+	SigningKeysRotated = "signing_keys.rotated"
+	// Events might have been lost, e.g. while the bus was reconnecting,
+	// so state derived from them must be reloaded. Has no user
+	Resync = "events.resync"
+)
+
+var (
+	ErrUnknownDriver = errors.New("unknown events driver")
+)
+
+type Event struct {
+	Name       string            `json:"name"`
+	UserID     string            `json:"user_id"`
+	Data       map[string]string `json:"data,omitempty"`
+	OccurredAt time.Time         `json:"occurred_at"`
+}
+
+func New(name, userID string, data map[string]string) Event {
+	return Event{
+		Name:       name,
+		UserID:     userID,
+		Data:       data,
+		OccurredAt: time.Now().UTC(),
+	}
+}
+
+type Handler func(e Event)
+
+type Publisher interface {
+	Publish(e Event)
+}
+
+type Subscriber interface {
+	// Returns function to unsubscribe the handler
+	Subscribe(h Handler) func()
+}
+
+type PubSub interface {
+	Publisher
+	Subscriber
+}
+
+// In-process publish/subscribe bus, handlers are called synchronously
+// in the publisher's goroutine, so they must not block
+type Bus struct {
+	mu       sync.RWMutex
+	nextID   int
+	handlers map[int]Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{handlers: make(map[int]Handler)}
+}
+
+func (b *Bus) Publish(e Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, h := range b.handlers {
+		h(e)
+	}
+}
+
+func (b *Bus) Subscribe(h Handler) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.nextID
+	b.nextID++
+	b.handlers[id] = h
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		delete(b.handlers, id)
+	}
+}
@@ -0,0 +1,122 @@
+package event
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+	"wildproject/internal/app/data/database"
+
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/lib/pq"
+)
+
+const (
+	listenerMinReconnect = 10 * time.Second
+	listenerMaxReconnect = time.Minute
+	// Checks the connection when there were no notifications for a while,
+	// as a broken connection is noticed only on the next read
+	listenerPingInterval = 90 * time.Second
+	// Publishing runs on the request path, so a slow database must not
+	// hold the request for long. The event is still delivered locally
+	notifyTimeout = 5 * time.Second
+)
+
+// Bus shared by all the app instances through Postgres LISTEN/NOTIFY.
+// Events are delivered to the local handlers right away and are fanned out
+// to the other instances, which skip their own notifications
+type PostgresBus struct {
+	*Bus
+	db         database.Instance
+	listener   *pq.Listener
+	channel    string
+	instanceID string
+}
+
+type notification struct {
+	InstanceID string `json:"instance_id"`
+	Event      Event  `json:"event"`
+}
+
+func NewPostgresBus(db database.Instance, conn, channel string) (*PostgresBus, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	listener := pq.NewListener(
+		conn,
+		listenerMinReconnect,
+		listenerMaxReconnect,
+		func(ev pq.ListenerEventType, err error) {
+			if err != nil {
+				log.Errorf("events listener error: %s", err)
+			}
+		},
+	)
+
+	if err := listener.Listen(channel); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	return &PostgresBus{NewBus(), db, listener, channel, hex.EncodeToString(b)}, nil
+}
+
+func (p *PostgresBus) Publish(e Event) {
+	p.Bus.Publish(e)
+
+	payload, err := json.Marshal(notification{p.instanceID, e})
+	if err != nil {
+		log.Errorf("cannot encode event %s: %s", e.Name, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+
+	// Row is scanned to release the connection, pg_notify returns void
+	var void string
+
+	err = p.db.QueryRowContext(ctx, "SELECT pg_notify($1, $2);", p.channel, string(payload)).Scan(&void)
+	if err != nil {
+		log.Errorf("cannot notify about event %s: %s", e.Name, err)
+	}
+}
+
+// Blocks delivering events from the other instances until context is canceled
+func (p *PostgresBus) Run(ctx context.Context) {
+	defer p.listener.Close()
+
+	ticker := time.NewTicker(listenerPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			go p.listener.Ping()
+		case n := <-p.listener.Notify:
+			// Nil is sent after reconnect, events sent meanwhile are lost
+			if n == nil {
+				log.Warn("events listener reconnected")
+				p.Bus.Publish(New(Resync, "", nil))
+				continue
+			}
+
+			var msg notification
+			if err := json.Unmarshal([]byte(n.Extra), &msg); err != nil {
+				log.Errorf("cannot decode event notification: %s", err)
+				continue
+			}
+
+			if msg.InstanceID == p.instanceID {
+				continue
+			}
+
+			p.Bus.Publish(msg.Event)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package manager
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image/png"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+	"wildproject/internal/app/data/storage"
+	model "wildproject/internal/app/domain/models"
+	"wildproject/internal/imaging"
+)
+
+const (
+	AvatarFormatSVG = "svg"
+	AvatarFormatPNG = "png"
+)
+
+// Placeholder of the default avatar URL replaced with user's id
+const avatarURLUserID = "{user_id}"
+
+var (
+	ErrAvatarTooLarge          = errors.New("avatar is too large")
+	ErrAvatarUnsupportedSize   = errors.New("unsupported avatar size")
+	ErrAvatarUnsupportedFormat = errors.New("unsupported avatar format")
+)
+
+// Never serves uploaded avatars as is: they are decoded, stripped of any
+// metadata (EXIF, GPS, etc.), cropped to square and re-encoded into
+// a few sizes
+type AvatarProcessor struct {
+	cfg     *model.AvatarConfig
+	storage storage.Storage
+}
+
+func NewAvatarProcessor(cfg *model.AvatarConfig, st storage.Storage) *AvatarProcessor {
+	return &AvatarProcessor{cfg, st}
+}
+
+// Processes avatar and uploads all its variants under a new unique prefix,
+// so the old cached avatars are never served
+func (ap *AvatarProcessor) Upload(userID string, r io.Reader) ([]model.ImageVariant, error) {
+	// Read one byte over the limit to detect too large avatars
+	data, err := io.ReadAll(io.LimitReader(r, ap.cfg.MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(data)) > ap.cfg.MaxSize {
+		return nil, ErrAvatarTooLarge
+	}
+
+	img, err := imaging.Decode(data, ap.cfg.MaxPixels)
+	if err != nil {
+		return nil, err
+	}
+
+	img = imaging.SquareCrop(img)
+
+	prefix := make([]byte, 16)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, err
+	}
+
+	variants := make([]model.ImageVariant, 0, len(ap.cfg.Sizes))
+
+	for _, size := range ap.cfg.Sizes {
+		var buf bytes.Buffer
+
+		resized := imaging.Resize(img, size, size)
+		if err := imaging.EncodeJPEG(&buf, resized, ap.cfg.Quality); err != nil {
+			return nil, err
+		}
+
+		key := fmt.Sprintf("avatars/%s/%s/%d.jpg", userID, hex.EncodeToString(prefix), size)
+
+		err := ap.storage.Put(key, &buf, int64(buf.Len()), "image/jpeg")
+		if err != nil {
+			// Don't leave already uploaded variants behind
+			ap.Delete(variants)
+			return nil, err
+		}
+
+		variants = append(variants, model.ImageVariant{
+			Size: size,
+			URL:  ap.storage.URL(key),
+		})
+	}
+
+	return variants, nil
+}
+
+// Deletes all the avatar variants stored in the storage, others are ignored
+func (ap *AvatarProcessor) Delete(variants []model.ImageVariant) error {
+	var errs []error
+
+	for _, v := range variants {
+		key, ok := ap.storage.Key(v.URL)
+		if !ok {
+			continue
+		}
+
+		if err := ap.storage.Delete(key); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Returns URLs of the generated avatar of every configured size for users
+// who never uploaded one
+func (ap *AvatarProcessor) DefaultVariants(userID string) []model.ImageVariant {
+	base := strings.ReplaceAll(ap.cfg.DefaultURL, avatarURLUserID, url.PathEscape(userID))
+
+	variants := make([]model.ImageVariant, 0, len(ap.cfg.Sizes))
+	for _, size := range ap.cfg.Sizes {
+		variants = append(variants, model.ImageVariant{
+			Size: size,
+			URL:  fmt.Sprintf("%s?size=%d", base, size),
+		})
+	}
+
+	return variants
+}
+
+// Tells if the generated avatar shows user's initials. They reveal that
+// the account exists and part of its name, so they are opt-in
+func (ap *AvatarProcessor) RendersInitials() bool {
+	return ap.cfg.DefaultInitials
+}
+
+// Deterministically renders initials avatar if initials are enabled and
+// name is known, and identicon of the user id otherwise. Size is limited
+// to the configured ones, so the rendered avatars can be cached.
+//
+// Returns encoded image and its content type
+func (ap *AvatarProcessor) RenderDefault(
+	userID, name string, size int, format string,
+) (
+	[]byte, string, error,
+) {
+	if !slices.Contains(ap.cfg.Sizes, size) {
+		return nil, "", ErrAvatarUnsupportedSize
+	}
+
+	initials := ""
+	if ap.cfg.DefaultInitials {
+		initials = imaging.Initials(name)
+	}
+
+	switch format {
+	case AvatarFormatSVG:
+		if initials != "" {
+			return imaging.InitialsSVG(userID, initials, size), "image/svg+xml", nil
+		}
+
+		return imaging.IdenticonSVG(userID, size), "image/svg+xml", nil
+	case AvatarFormatPNG:
+		img := imaging.Identicon(userID, size)
+
+		if initials != "" {
+			var err error
+			if img, err = imaging.InitialsImage(userID, initials, size); err != nil {
+				return nil, "", err
+			}
+		}
+
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, "", err
+		}
+
+		return buf.Bytes(), "image/png", nil
+	}
+
+	return nil, "", ErrAvatarUnsupportedFormat
+}
@@ -0,0 +1,72 @@
+package manager
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	model "wildproject/internal/app/domain/models"
+)
+
+const testUserID = "6f1c2a9e-0c4b-4a51-9a7e-2f0f3c1d5b8a"
+
+func TestAvatarRenderDefault(t *testing.T) {
+	tests := []struct {
+		name     string
+		initials bool
+		userName string
+		contains string
+	}{
+		{"identicon", false, "", "<svg"},
+		{"name is ignored", false, "John Smith", "<svg"},
+		{"initials", true, "John Smith", ">JS</text>"},
+		{"no name", true, "", "<svg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ap := NewAvatarProcessor(&model.AvatarConfig{Sizes: []int{64}, DefaultInitials: tt.initials}, nil)
+
+			img, contentType, err := ap.RenderDefault(testUserID, tt.userName, 64, AvatarFormatSVG)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if contentType != "image/svg+xml" {
+				t.Fatalf("unexpected content type %s", contentType)
+			}
+
+			if !bytes.Contains(img, []byte(tt.contains)) {
+				t.Fatalf("expected %q in %s", tt.contains, img)
+			}
+
+			// Without initials nothing but the id is rendered
+			if !tt.initials || tt.userName == "" {
+				identicon, _, _ := ap.RenderDefault(testUserID, "", 64, AvatarFormatSVG)
+				if !bytes.Equal(img, identicon) {
+					t.Fatal("expected identicon of the user id")
+				}
+			}
+		})
+	}
+}
+
+func TestAvatarRenderDefaultPNG(t *testing.T) {
+	ap := NewAvatarProcessor(&model.AvatarConfig{Sizes: []int{64}, DefaultInitials: true}, nil)
+
+	img, contentType, err := ap.RenderDefault(testUserID, "John Smith", 64, AvatarFormatPNG)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if contentType != "image/png" || !bytes.HasPrefix(img, []byte("\x89PNG")) {
+		t.Fatalf("expected png image, got %s", contentType)
+	}
+
+	if _, _, err := ap.RenderDefault(testUserID, "", 32, AvatarFormatPNG); !errors.Is(err, ErrAvatarUnsupportedSize) {
+		t.Fatalf("expected ErrAvatarUnsupportedSize, got %v", err)
+	}
+
+	if _, _, err := ap.RenderDefault(testUserID, "", 64, "gif"); !errors.Is(err, ErrAvatarUnsupportedFormat) {
+		t.Fatalf("expected ErrAvatarUnsupportedFormat, got %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package manager
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+// Longest expected corpus line: 40 hex symbols, ":", count and "\r\n"
+const maxCorpusLineLength = 64
+
+var (
+	ErrCorpusLineTooLong = errors.New("breached passwords corpus line is too long")
+)
+
+// Looks passwords up in a local copy of the HIBP "Pwned Passwords" corpus
+// (SHA-1 ordered by hash), so no network access is needed. The file is
+// never loaded in memory, each lookup binary searches it with a few reads
+type PwnedCorpus struct {
+	file *os.File
+	size int64
+}
+
+func OpenPwnedCorpus(path string) (*PwnedCorpus, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &PwnedCorpus{file, info.Size()}, nil
+}
+
+func (pc *PwnedCorpus) Close() error {
+	return pc.file.Close()
+}
+
+func (pc *PwnedCorpus) IsBreached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	target := []byte(strings.ToUpper(hex.EncodeToString(sum[:])))
+
+	// Invariant: the line we are looking for starts in [lo, hi)
+	lo, hi := int64(0), pc.size
+
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+
+		start, end, hash, err := pc.lineAt(mid)
+		if err != nil {
+			return false, err
+		}
+
+		if start >= hi {
+			hi = mid
+			continue
+		}
+
+		switch bytes.Compare(hash, target) {
+		case 0:
+			return true, nil
+		case -1:
+			lo = end
+		default:
+			hi = mid
+		}
+	}
+
+	return false, nil
+}
+
+// Reads the first line starting at or after the offset.
+//
+// Returns line's start and end offsets and uppercased hash
+func (pc *PwnedCorpus) lineAt(offset int64) (int64, int64, []byte, error) {
+	start := offset
+
+	// Unless offset is a line start, skip the rest of the current line
+	if offset > 0 {
+		start = offset - 1
+	}
+
+	buf := make([]byte, 2*maxCorpusLineLength)
+
+	n, err := pc.file.ReadAt(buf, start)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, 0, nil, err
+	}
+	buf = buf[:n]
+	eof := start+int64(n) >= pc.size
+
+	if offset > 0 {
+		i := bytes.IndexByte(buf, '\n')
+		if i < 0 {
+			// Offset is within the last line
+			if eof {
+				return pc.size, pc.size, nil, nil
+			}
+
+			return 0, 0, nil, ErrCorpusLineTooLong
+		}
+
+		buf = buf[i+1:]
+		start += int64(i) + 1
+	}
+
+	line := buf
+	end := start + int64(len(buf))
+
+	if i := bytes.IndexByte(buf, '\n'); i >= 0 {
+		line = buf[:i]
+		end = start + int64(i) + 1
+	} else if start+int64(len(buf)) < pc.size {
+		return 0, 0, nil, ErrCorpusLineTooLong
+	}
+
+	if start >= pc.size {
+		return pc.size, pc.size, nil, nil
+	}
+
+	hash, _, _ := bytes.Cut(bytes.TrimRight(line, "\r"), []byte(":"))
+
+	return start, end, bytes.ToUpper(hash), nil
+}
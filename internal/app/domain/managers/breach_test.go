@@ -0,0 +1,179 @@
+package manager
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+type corpusEntry struct {
+	password string
+	hash     string
+}
+
+// Passwords of the fixture ordered by their hashes, as the corpus is
+func corpusEntries(n int) []corpusEntry {
+	entries := make([]corpusEntry, n)
+
+	for i := range entries {
+		password := fmt.Sprintf("password-%d", i)
+		sum := sha1.Sum([]byte(password))
+		entries[i] = corpusEntry{password, strings.ToUpper(hex.EncodeToString(sum[:]))}
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].hash < entries[j].hash })
+
+	return entries
+}
+
+func writeCorpus(t *testing.T, entries []corpusEntry, lower bool, eol string) string {
+	t.Helper()
+
+	var b strings.Builder
+
+	for i, e := range entries {
+		hash := e.hash
+		if lower {
+			hash = strings.ToLower(hash)
+		}
+
+		fmt.Fprintf(&b, "%s:%d%s", hash, i+1, eol)
+	}
+
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+
+	if err := os.WriteFile(path, []byte(b.String()), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestPwnedCorpusIsBreached(t *testing.T) {
+	entries := corpusEntries(16)
+
+	// Every other entry is left out of the corpus, so misses fall between
+	// the lines, before the first and after the last one
+	var corpus []corpusEntry
+	for i := 1; i < len(entries)-1; i += 2 {
+		corpus = append(corpus, entries[i])
+	}
+
+	first, last := corpus[0], corpus[len(corpus)-1]
+	before, between, after := entries[0], entries[4], entries[len(entries)-1]
+
+	files := []struct {
+		name  string
+		lower bool
+		eol   string
+	}{
+		{"uppercase", false, "\n"},
+		{"lowercase", true, "\n"},
+		{"crlf", false, "\r\n"},
+		{"lowercase crlf", true, "\r\n"},
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		want     bool
+	}{
+		{"first line", first.password, true},
+		{"last line", last.password, true},
+		{"middle line", corpus[len(corpus)/2].password, true},
+		{"miss before first", before.password, false},
+		{"miss between entries", between.password, false},
+		{"miss after last", after.password, false},
+	}
+
+	for _, f := range files {
+		pc, err := OpenPwnedCorpus(writeCorpus(t, corpus, f.lower, f.eol))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { pc.Close() })
+
+		for _, tt := range tests {
+			t.Run(f.name+"/"+tt.name, func(t *testing.T) {
+				got, err := pc.IsBreached(tt.password)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if got != tt.want {
+					t.Fatalf("expected %t for %q, got %t", tt.want, tt.password, got)
+				}
+			})
+		}
+
+		// Each line of the corpus is found, whatever its position
+		t.Run(f.name+"/every line", func(t *testing.T) {
+			for _, e := range corpus {
+				if got, err := pc.IsBreached(e.password); err != nil || !got {
+					t.Fatalf("expected %q to be found, got %t, %v", e.password, got, err)
+				}
+			}
+		})
+	}
+}
+
+func TestPwnedCorpusSingleLine(t *testing.T) {
+	entries := corpusEntries(2)
+
+	for _, eol := range []string{"", "\n", "\r\n"} {
+		pc, err := OpenPwnedCorpus(writeCorpus(t, entries[:1], false, eol))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer pc.Close()
+
+		if got, err := pc.IsBreached(entries[0].password); err != nil || !got {
+			t.Fatalf("expected %q to be found with eol %q, got %t, %v", entries[0].password, eol, got, err)
+		}
+
+		if got, err := pc.IsBreached(entries[1].password); err != nil || got {
+			t.Fatalf("expected %q to be missed with eol %q, got %t, %v", entries[1].password, eol, got, err)
+		}
+	}
+}
+
+func TestPwnedCorpusEmptyFile(t *testing.T) {
+	pc, err := OpenPwnedCorpus(writeCorpus(t, nil, false, "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	got, err := pc.IsBreached("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got {
+		t.Fatal("expected empty corpus to contain nothing")
+	}
+}
+
+func TestPwnedCorpusLineTooLong(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+	line := strings.Repeat("F", 3*maxCorpusLineLength) + ":1\n"
+
+	if err := os.WriteFile(path, []byte(line+line), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	pc, err := OpenPwnedCorpus(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	if _, err := pc.IsBreached("password"); err != ErrCorpusLineTooLong {
+		t.Fatalf("expected ErrCorpusLineTooLong, got %v", err)
+	}
+}
@@ -0,0 +1,294 @@
+package manager
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	model "wildproject/internal/app/domain/models"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	HashAlgorithmArgon2id = "argon2id"
+	HashAlgorithmBcrypt   = "bcrypt"
+)
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+	// Argon2 requires at least 8 KiB of memory per thread
+	argon2MinMemoryPerThread = 8
+	// Bcrypt rejects longer passwords
+	bcryptMaxPasswordBytes = 72
+)
+
+var (
+	ErrUnknownHashAlgorithm = errors.New("unknown password hash algorithm")
+	ErrInvalidHash          = errors.New("invalid password hash format")
+	ErrInvalidHashParams    = errors.New("invalid password hash parameters")
+)
+
+// Hashes passwords with configured algorithm and verifies hashes made
+// with any of the supported ones, so the algorithm can be switched
+// without invalidating existing passwords
+type PasswordHasherManager struct {
+	primary string
+	argon2  *Argon2idHasher
+	bcrypt  *BcryptHasher
+}
+
+func NewPasswordHasher(cfg *model.PasswordConfig) (*PasswordHasherManager, error) {
+	if cfg.HashAlgorithm != HashAlgorithmArgon2id && cfg.HashAlgorithm != HashAlgorithmBcrypt {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownHashAlgorithm, cfg.HashAlgorithm)
+	}
+
+	// Checked here too, not only by the config validation, as argon2
+	// panics on invalid parameters instead of returning an error
+	if cfg.HashAlgorithm == HashAlgorithmArgon2id {
+		if err := checkArgon2idParams(cfg.Argon2Memory, cfg.Argon2Time, cfg.Argon2Threads); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.HashAlgorithm == HashAlgorithmBcrypt {
+		if cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf(
+				"%w: bcrypt cost must be in %d..%d", ErrInvalidHashParams, bcrypt.MinCost, bcrypt.MaxCost,
+			)
+		}
+	}
+
+	hm := PasswordHasherManager{
+		primary: cfg.HashAlgorithm,
+		argon2:  NewArgon2idHasher(cfg.Argon2Memory, cfg.Argon2Time, cfg.Argon2Threads),
+		bcrypt:  NewBcryptHasher(cfg.BcryptCost),
+	}
+
+	return &hm, nil
+}
+
+func (hm *PasswordHasherManager) Algorithm() string {
+	return hm.primary
+}
+
+func (hm *PasswordHasherManager) Hash(password string) (string, error) {
+	if hm.primary == HashAlgorithmBcrypt {
+		return hm.bcrypt.Hash(password)
+	}
+
+	return hm.argon2.Hash(password)
+}
+
+// Hash of unknown or malformed format is a mismatch, not an error, so
+// such accounts fail to log in instead of failing the request. They
+// can regain access by resetting the password
+func (hm *PasswordHasherManager) Verify(password, encoded string) (bool, error) {
+	hasher, err := hm.hasherOf(encoded)
+	if err != nil {
+		return false, nil
+	}
+
+	ok, err := hasher.Verify(password, encoded)
+	if errors.Is(err, ErrInvalidHash) {
+		return false, nil
+	}
+
+	return ok, err
+}
+
+// Reports whether hash is made with not the primary algorithm or with
+// outdated parameters
+func (hm *PasswordHasherManager) NeedsRehash(encoded string) bool {
+	hasher, err := hm.hasherOf(encoded)
+	if err != nil {
+		return true
+	}
+
+	if hasher.Algorithm() != hm.primary {
+		return true
+	}
+
+	return hasher.NeedsRehash(encoded)
+}
+
+func (hm *PasswordHasherManager) hasherOf(encoded string) (PasswordHasher, error) {
+	switch {
+	case strings.HasPrefix(encoded, "$"+HashAlgorithmArgon2id+"$"):
+		return hm.argon2, nil
+	case isBcryptHash(encoded):
+		return hm.bcrypt, nil
+	}
+
+	return nil, ErrUnknownHashAlgorithm
+}
+
+// Produces PHC string format hashes:
+// $argon2id$v=19$m=<memory>,t=<time>,p=<threads>$<salt>$<hash>
+type Argon2idHasher struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+}
+
+// "memory" is set in KiB
+func NewArgon2idHasher(memory, time uint32, threads uint8) *Argon2idHasher {
+	return &Argon2idHasher{memory, time, threads}
+}
+
+func (h *Argon2idHasher) Algorithm() string {
+	return HashAlgorithmArgon2id
+}
+
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.time, h.memory, h.threads, argon2KeyLength)
+
+	encoded := fmt.Sprintf(
+		"$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		HashAlgorithmArgon2id, argon2.Version, h.memory, h.time, h.threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+
+	return encoded, nil
+}
+
+func (h *Argon2idHasher) Verify(password, encoded string) (bool, error) {
+	params, salt, key, err := parseArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	actual := argon2.IDKey(
+		[]byte(password), salt, params.time, params.memory, params.threads, uint32(len(key)),
+	)
+
+	return subtle.ConstantTimeCompare(actual, key) == 1, nil
+}
+
+func (h *Argon2idHasher) NeedsRehash(encoded string) bool {
+	params, _, key, err := parseArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+
+	return *params != *h || len(key) != argon2KeyLength
+}
+
+func checkArgon2idParams(memory, time uint32, threads uint8) error {
+	if threads == 0 || time == 0 {
+		return fmt.Errorf("%w: argon2 time and threads must be positive", ErrInvalidHashParams)
+	}
+
+	if memory < argon2MinMemoryPerThread*uint32(threads) {
+		return fmt.Errorf(
+			"%w: argon2 memory must be at least %d KiB per thread", ErrInvalidHashParams, argon2MinMemoryPerThread,
+		)
+	}
+
+	return nil
+}
+
+func parseArgon2id(encoded string) (*Argon2idHasher, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, hash
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != HashAlgorithmArgon2id {
+		return nil, nil, nil, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return nil, nil, nil, ErrInvalidHash
+	}
+
+	if version != argon2.Version {
+		return nil, nil, nil, ErrInvalidHash
+	}
+
+	var params Argon2idHasher
+	_, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads)
+	if err != nil {
+		return nil, nil, nil, ErrInvalidHash
+	}
+
+	// Stored hash must not be able to panic the verification
+	if checkArgon2idParams(params.memory, params.time, params.threads) != nil {
+		return nil, nil, nil, ErrInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, ErrInvalidHash
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return nil, nil, nil, ErrInvalidHash
+	}
+
+	return &params, salt, key, nil
+}
+
+// Produces modular crypt format hashes ($2a$<cost>$...), which already
+// record both algorithm and cost. Passwords are limited to 72 bytes
+type BcryptHasher struct {
+	cost int
+}
+
+func NewBcryptHasher(cost int) *BcryptHasher {
+	return &BcryptHasher{cost}
+}
+
+func (h *BcryptHasher) Algorithm() string {
+	return HashAlgorithmBcrypt
+}
+
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+func (h *BcryptHasher) Verify(password, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("%w: %s", ErrInvalidHash, err)
+	}
+
+	return true, nil
+}
+
+func (h *BcryptHasher) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	if err != nil {
+		return true
+	}
+
+	return cost != h.cost
+}
+
+func isBcryptHash(encoded string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(encoded, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
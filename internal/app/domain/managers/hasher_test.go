@@ -0,0 +1,98 @@
+package manager
+
+import (
+	"errors"
+	"testing"
+	model "wildproject/internal/app/domain/models"
+)
+
+func testHasherConfig(algorithm string) *model.PasswordConfig {
+	return &model.PasswordConfig{
+		HashAlgorithm: algorithm,
+		Argon2Memory:  64,
+		Argon2Time:    1,
+		Argon2Threads: 1,
+		BcryptCost:    4,
+	}
+}
+
+func TestNewPasswordHasherRejectsInvalidParams(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(cfg *model.PasswordConfig)
+	}{
+		{"argon2 zero threads", func(cfg *model.PasswordConfig) { cfg.Argon2Threads = 0 }},
+		{"argon2 zero time", func(cfg *model.PasswordConfig) { cfg.Argon2Time = 0 }},
+		{"argon2 low memory", func(cfg *model.PasswordConfig) { cfg.Argon2Threads = 4; cfg.Argon2Memory = 16 }},
+		{"bcrypt low cost", func(cfg *model.PasswordConfig) { cfg.HashAlgorithm = HashAlgorithmBcrypt; cfg.BcryptCost = 3 }},
+		{"bcrypt high cost", func(cfg *model.PasswordConfig) { cfg.HashAlgorithm = HashAlgorithmBcrypt; cfg.BcryptCost = 32 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testHasherConfig(HashAlgorithmArgon2id)
+			tt.edit(cfg)
+
+			if _, err := NewPasswordHasher(cfg); !errors.Is(err, ErrInvalidHashParams) {
+				t.Fatalf("expected ErrInvalidHashParams, got %v", err)
+			}
+		})
+	}
+}
+
+func TestPasswordHasherVerify(t *testing.T) {
+	for _, algorithm := range []string{HashAlgorithmArgon2id, HashAlgorithmBcrypt} {
+		t.Run(algorithm, func(t *testing.T) {
+			hm, err := NewPasswordHasher(testHasherConfig(algorithm))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			hash, err := hm.Hash("correct horse")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if ok, err := hm.Verify("correct horse", hash); !ok || err != nil {
+				t.Fatalf("expected match, got %v, %v", ok, err)
+			}
+
+			if ok, err := hm.Verify("wrong horse", hash); ok || err != nil {
+				t.Fatalf("expected mismatch, got %v, %v", ok, err)
+			}
+
+			if hm.NeedsRehash(hash) {
+				t.Fatal("fresh hash needs rehash")
+			}
+		})
+	}
+}
+
+func TestPasswordHasherVerifyMalformed(t *testing.T) {
+	hm, err := NewPasswordHasher(testHasherConfig(HashAlgorithmArgon2id))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hashes := map[string]string{
+		"empty":          "",
+		"unknown":        "$md5$abc",
+		"argon2 garbage": "$argon2id$garbage",
+		"argon2 p=0":     "$argon2id$v=19$m=64,t=1,p=0$c2FsdHNhbHQ$a2V5a2V5",
+		"argon2 no key":  "$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHQ$",
+		"bcrypt short":   "$2a$04$short",
+	}
+
+	for name, hash := range hashes {
+		t.Run(name, func(t *testing.T) {
+			ok, err := hm.Verify("password", hash)
+			if ok || err != nil {
+				t.Fatalf("expected mismatch, got %v, %v", ok, err)
+			}
+
+			if !hm.NeedsRehash(hash) {
+				t.Fatal("malformed hash doesn't need rehash")
+			}
+		})
+	}
+}
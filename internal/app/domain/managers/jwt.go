@@ -3,7 +3,9 @@ package manager
 import (
 	"errors"
 	"fmt"
+	"slices"
 	"strconv"
+	"sync"
 	"time"
 	model "wildproject/internal/app/domain/models"
 
@@ -12,22 +14,44 @@ import (
 
 var (
 	ErrInvalidSigningMethod   = errors.New("invalid token's signing method")
+	ErrUnknownSigningKey      = errors.New("unknown token's signing key")
+	ErrRetiredSigningKey      = errors.New("token's signing key is retired")
 	ErrInvalidClaims          = errors.New("invalid claims")
 	ErrClaimsEmptySessionID   = errors.New("claims' session_id is empty")
 	ErrClaimsInvalidSessionID = errors.New("claims' session_id is invalid")
 	ErrClaimsEmptyUserID      = errors.New("claims' user_id is empty")
 )
 
+// Signs access tokens with the latest of the rotated keys, it's named by
+// the "kid" header. Replaced key still verifies the tokens for the token
+// TTL, so the tokens issued before the rotation live their time out.
+//
+// Secret from the config is the key without id, which is used until the
+// first rotation and is retired like any other key after it
 type JwtAccessManager struct {
-	secret []byte
-	ttl    time.Duration
+	ttl time.Duration
+	mu  sync.RWMutex
+	// Newest first, the config secret is always the last one
+	keys []model.SigningKey
 }
 
 func NewJwtManager(
 	secret []byte,
 	ttl time.Duration,
 ) *JwtAccessManager {
-	return &JwtAccessManager{secret, ttl}
+	return &JwtAccessManager{
+		ttl:  ttl,
+		keys: []model.SigningKey{{Secret: secret}},
+	}
+}
+
+// Replaces the rotated keys, "keys" must be ordered newest first
+func (tm *JwtAccessManager) SetKeys(keys []model.SigningKey) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	fallback := tm.keys[len(tm.keys)-1]
+	tm.keys = append(slices.Clone(keys), fallback)
 }
 
 func (tm *JwtAccessManager) Generate(sessionID int, userID string) (string, error) {
@@ -41,7 +65,15 @@ func (tm *JwtAccessManager) Generate(sessionID int, userID string) (string, erro
 
 	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
 
-	return jwtToken.SignedString(tm.secret)
+	tm.mu.RLock()
+	key := tm.keys[0]
+	tm.mu.RUnlock()
+
+	if key.ID != "" {
+		jwtToken.Header["kid"] = key.ID
+	}
+
+	return jwtToken.SignedString(key.Secret)
 }
 
 func (tm *JwtAccessManager) getValidateFn() func(t *jwt.Token) (interface{}, error) {
@@ -51,10 +83,33 @@ func (tm *JwtAccessManager) getValidateFn() func(t *jwt.Token) (interface{}, err
 			return nil, ErrInvalidSigningMethod
 		}
 
-		return tm.secret, nil
+		// Tokens issued before the first rotation have no key id
+		keyID, _ := t.Header["kid"].(string)
+
+		return tm.findKey(keyID)
 	}
 }
 
+// Returns secret of the key, unless it was replaced more than token TTL ago
+func (tm *JwtAccessManager) findKey(keyID string) ([]byte, error) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	for i, key := range tm.keys {
+		if key.ID != keyID {
+			continue
+		}
+
+		if i > 0 && time.Since(tm.keys[i-1].CreatedAt) > tm.ttl {
+			return nil, ErrRetiredSigningKey
+		}
+
+		return key.Secret, nil
+	}
+
+	return nil, ErrUnknownSigningKey
+}
+
 func (tm *JwtAccessManager) ParseAndValidate(accessToken string) (model.TokenPayload, error) {
 	var claims jwt.RegisteredClaims
 
@@ -99,5 +154,9 @@ func (tm *JwtAccessManager) parseClaims(claims jwt.RegisteredClaims) (model.Toke
 		UserID:    userID,
 	}
 
+	if claims.ExpiresAt != nil {
+		data.ExpiresAt = claims.ExpiresAt.Time
+	}
+
 	return data, nil
 }
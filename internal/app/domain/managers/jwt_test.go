@@ -0,0 +1,63 @@
+package manager
+
+import (
+	"errors"
+	"testing"
+	"time"
+	model "wildproject/internal/app/domain/models"
+)
+
+func TestJwtManagerRotation(t *testing.T) {
+	tm := NewJwtManager([]byte("config secret"), time.Hour)
+
+	legacy, err := tm.Generate(1, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tm.ParseAndValidate(legacy); err != nil {
+		t.Fatalf("expected token of the config secret to be valid, got %v", err)
+	}
+
+	// Key rotated just now, the tokens issued before live their time out
+	tm.SetKeys([]model.SigningKey{{ID: "first", Secret: []byte("first secret"), CreatedAt: time.Now()}})
+
+	rotated, err := tm.Generate(2, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, token := range []string{legacy, rotated} {
+		if _, err := tm.ParseAndValidate(token); err != nil {
+			t.Fatalf("expected token to be valid after rotation, got %v", err)
+		}
+	}
+
+	// Next rotation happened longer than token TTL ago, so only the key
+	// it replaced is retired
+	tm.SetKeys([]model.SigningKey{
+		{ID: "second", Secret: []byte("second secret"), CreatedAt: time.Now().Add(-time.Minute)},
+		{ID: "first", Secret: []byte("first secret"), CreatedAt: time.Now().Add(-2 * time.Hour)},
+	})
+
+	if _, err := tm.ParseAndValidate(legacy); !errors.Is(err, ErrRetiredSigningKey) {
+		t.Fatalf("expected ErrRetiredSigningKey for the config secret, got %v", err)
+	}
+
+	if _, err := tm.ParseAndValidate(rotated); err != nil {
+		t.Fatalf("expected token of the replaced key to be valid, got %v", err)
+	}
+
+	// Keys unknown to the instance are never trusted
+	other := NewJwtManager([]byte("config secret"), time.Hour)
+	other.SetKeys([]model.SigningKey{{ID: "other", Secret: []byte("other secret"), CreatedAt: time.Now()}})
+
+	foreign, err := other.Generate(3, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tm.ParseAndValidate(foreign); !errors.Is(err, ErrUnknownSigningKey) {
+		t.Fatalf("expected ErrUnknownSigningKey, got %v", err)
+	}
+}
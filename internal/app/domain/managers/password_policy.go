@@ -0,0 +1,191 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+	model "wildproject/internal/app/domain/models"
+)
+
+// Machine-readable codes of password policy violations
+const (
+	ViolationTooShort      = "too_short"
+	ViolationTooLong       = "too_long"
+	ViolationMissingLower  = "missing_lower"
+	ViolationMissingUpper  = "missing_upper"
+	ViolationMissingDigit  = "missing_digit"
+	ViolationMissingSymbol = "missing_symbol"
+	ViolationTooRepetitive = "too_repetitive"
+	ViolationContainsEmail = "contains_email"
+	ViolationContainsName  = "contains_name"
+	ViolationTooWeak       = "too_weak"
+	ViolationBreached      = "breached"
+	ViolationReused        = "reused"
+)
+
+// Personal data parts shorter than this are not matched against password
+const minPersonalPartLength = 3
+
+var (
+	ErrPasswordPolicy = errors.New("password does not satisfy policy")
+)
+
+// Describes all the rules the checked password violates
+type PasswordPolicyError struct {
+	Violations []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrPasswordPolicy, strings.Join(e.Violations, ", "))
+}
+
+func (e *PasswordPolicyError) Unwrap() error {
+	return ErrPasswordPolicy
+}
+
+type PasswordPolicyManager struct {
+	cfg      *model.PasswordConfig
+	breaches BreachChecker
+}
+
+// "bc" can be nil, then passwords are not checked against known breaches
+func NewPasswordPolicy(cfg *model.PasswordConfig, bc BreachChecker) *PasswordPolicyManager {
+	return &PasswordPolicyManager{cfg, bc}
+}
+
+// Checks password against all configured rules at once.
+// "email" and "name" are the user's personal data and can be omitted.
+//
+// Returns *PasswordPolicyError listing every violated rule or nil
+func (pm *PasswordPolicyManager) Check(password, email, name string) error {
+	violations := make([]string, 0)
+
+	length := utf8.RuneCountInString(password)
+	if pm.cfg.MinLength > 0 && length < pm.cfg.MinLength {
+		violations = append(violations, ViolationTooShort)
+	}
+
+	// Max length is in runes, while bcrypt limits passwords in bytes, so
+	// non-ASCII passwords of the max length may not fit it
+	tooLong := pm.cfg.MaxLength > 0 && length > pm.cfg.MaxLength
+	if pm.cfg.HashAlgorithm == HashAlgorithmBcrypt && len(password) > bcryptMaxPasswordBytes {
+		tooLong = true
+	}
+
+	if tooLong {
+		violations = append(violations, ViolationTooLong)
+	}
+
+	var hasLower, hasUpper, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case !unicode.IsLetter(r):
+			hasSymbol = true
+		}
+	}
+
+	if pm.cfg.RequireLower && !hasLower {
+		violations = append(violations, ViolationMissingLower)
+	}
+
+	if pm.cfg.RequireUpper && !hasUpper {
+		violations = append(violations, ViolationMissingUpper)
+	}
+
+	if pm.cfg.RequireDigit && !hasDigit {
+		violations = append(violations, ViolationMissingDigit)
+	}
+
+	if pm.cfg.RequireSymbol && !hasSymbol {
+		violations = append(violations, ViolationMissingSymbol)
+	}
+
+	if pm.cfg.MaxRepeated > 0 && longestRun(password) > pm.cfg.MaxRepeated {
+		violations = append(violations, ViolationTooRepetitive)
+	}
+
+	if pm.cfg.DenyPersonal {
+		lowered := strings.ToLower(password)
+
+		if containsEmail(lowered, email) {
+			violations = append(violations, ViolationContainsEmail)
+		}
+
+		if containsName(lowered, name) {
+			violations = append(violations, ViolationContainsName)
+		}
+	}
+
+	if pm.cfg.MinStrength > 0 && EstimateStrength(password) < pm.cfg.MinStrength {
+		violations = append(violations, ViolationTooWeak)
+	}
+
+	if pm.breaches != nil {
+		breached, err := pm.breaches.IsBreached(password)
+		if err != nil {
+			return err
+		}
+
+		if breached {
+			violations = append(violations, ViolationBreached)
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{violations}
+	}
+
+	return nil
+}
+
+// Returns length of the longest sequence of the same repeated symbol
+func longestRun(s string) int {
+	longest, current := 0, 0
+	var prev rune = -1
+
+	for _, r := range s {
+		if r == prev {
+			current++
+		} else {
+			current = 1
+			prev = r
+		}
+
+		if current > longest {
+			longest = current
+		}
+	}
+
+	return longest
+}
+
+func containsEmail(lowered, email string) bool {
+	local, _, _ := strings.Cut(strings.ToLower(email), "@")
+	if utf8.RuneCountInString(local) < minPersonalPartLength {
+		return false
+	}
+
+	return strings.Contains(lowered, local)
+}
+
+func containsName(lowered, name string) bool {
+	for _, part := range strings.Fields(strings.ToLower(name)) {
+		if utf8.RuneCountInString(part) < minPersonalPartLength {
+			continue
+		}
+
+		if strings.Contains(lowered, part) {
+			return true
+		}
+	}
+
+	return false
+}
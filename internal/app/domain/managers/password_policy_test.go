@@ -0,0 +1,169 @@
+package manager
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	model "wildproject/internal/app/domain/models"
+)
+
+type stubBreaches map[string]bool
+
+func (s stubBreaches) IsBreached(password string) (bool, error) {
+	return s[password], nil
+}
+
+type failingBreaches struct{}
+
+func (failingBreaches) IsBreached(string) (bool, error) {
+	return false, errors.New("corpus is unreadable")
+}
+
+func TestPasswordPolicyCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      model.PasswordConfig
+		password string
+		email    string
+		userName string
+		want     []string
+	}{
+		{"no rules", model.PasswordConfig{}, "a", "", "", nil},
+		{"too short", model.PasswordConfig{MinLength: 8}, "short", "", "", []string{ViolationTooShort}},
+		{"min length", model.PasswordConfig{MinLength: 8}, "12345678", "", "", nil},
+		{"length in runes", model.PasswordConfig{MinLength: 4, MaxLength: 4}, "пары", "", "", nil},
+		{"too long", model.PasswordConfig{MaxLength: 8}, "123456789", "", "", []string{ViolationTooLong}},
+		{
+			"too long for bcrypt",
+			model.PasswordConfig{MaxLength: 72, HashAlgorithm: HashAlgorithmBcrypt},
+			strings.Repeat("п", 40), "", "",
+			[]string{ViolationTooLong},
+		},
+		{
+			"fits bcrypt",
+			model.PasswordConfig{MaxLength: 72, HashAlgorithm: HashAlgorithmBcrypt},
+			strings.Repeat("п", 36), "", "",
+			nil,
+		},
+		{
+			"bytes are not limited by argon2id",
+			model.PasswordConfig{MaxLength: 72, HashAlgorithm: HashAlgorithmArgon2id},
+			strings.Repeat("п", 40), "", "",
+			nil,
+		},
+		{"missing lower", model.PasswordConfig{RequireLower: true}, "ABC123", "", "", []string{ViolationMissingLower}},
+		{"missing upper", model.PasswordConfig{RequireUpper: true}, "abc123", "", "", []string{ViolationMissingUpper}},
+		{"missing digit", model.PasswordConfig{RequireDigit: true}, "abcABC", "", "", []string{ViolationMissingDigit}},
+		{"missing symbol", model.PasswordConfig{RequireSymbol: true}, "abcABC123", "", "", []string{ViolationMissingSymbol}},
+		{"space is symbol", model.PasswordConfig{RequireSymbol: true}, "abc ABC", "", "", nil},
+		{"non latin letters", model.PasswordConfig{RequireLower: true, RequireUpper: true}, "Пароль", "", "", nil},
+		{
+			"all classes",
+			model.PasswordConfig{RequireLower: true, RequireUpper: true, RequireDigit: true, RequireSymbol: true},
+			"aB3$", "", "",
+			nil,
+		},
+		{
+			"all classes missing",
+			model.PasswordConfig{RequireLower: true, RequireUpper: true, RequireDigit: true, RequireSymbol: true},
+			"", "", "",
+			[]string{ViolationMissingLower, ViolationMissingUpper, ViolationMissingDigit, ViolationMissingSymbol},
+		},
+		{"max repeated", model.PasswordConfig{MaxRepeated: 2}, "abbc", "", "", nil},
+		{"too repetitive", model.PasswordConfig{MaxRepeated: 2}, "abbbc", "", "", []string{ViolationTooRepetitive}},
+		{
+			"contains email",
+			model.PasswordConfig{DenyPersonal: true},
+			"my-JohnDoe-pass", "johndoe@example.com", "",
+			[]string{ViolationContainsEmail},
+		},
+		{
+			"email domain is allowed",
+			model.PasswordConfig{DenyPersonal: true},
+			"example-pass", "johndoe@example.com", "",
+			nil,
+		},
+		{
+			"short email is ignored",
+			model.PasswordConfig{DenyPersonal: true},
+			"jo-pass", "jo@example.com", "",
+			nil,
+		},
+		{
+			"contains name part",
+			model.PasswordConfig{DenyPersonal: true},
+			"SMITH-forever", "", "John Smith",
+			[]string{ViolationContainsName},
+		},
+		{
+			"short name part is ignored",
+			model.PasswordConfig{DenyPersonal: true},
+			"al-forever", "", "Al Smith",
+			nil,
+		},
+		{
+			"personal data allowed",
+			model.PasswordConfig{},
+			"johndoe-smith", "johndoe@example.com", "John Smith",
+			nil,
+		},
+		{"too weak", model.PasswordConfig{MinStrength: 3}, "password", "", "", []string{ViolationTooWeak}},
+		{"strong enough", model.PasswordConfig{MinStrength: 3}, "xK9#mQ2$vL7!", "", "", nil},
+		{
+			"every violation is reported",
+			model.PasswordConfig{MinLength: 10, RequireDigit: true, MaxRepeated: 2, MinStrength: 2},
+			"aaab", "", "",
+			[]string{ViolationTooShort, ViolationMissingDigit, ViolationTooRepetitive, ViolationTooWeak},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewPasswordPolicy(&tt.cfg, nil).Check(tt.password, tt.email, tt.userName)
+			expectViolations(t, err, tt.want)
+		})
+	}
+}
+
+func TestPasswordPolicyCheckBreaches(t *testing.T) {
+	cfg := &model.PasswordConfig{}
+	breaches := stubBreaches{"hunter2": true}
+
+	err := NewPasswordPolicy(cfg, breaches).Check("hunter2", "", "")
+	expectViolations(t, err, []string{ViolationBreached})
+
+	err = NewPasswordPolicy(cfg, breaches).Check("xK9#mQ2$vL7!", "", "")
+	expectViolations(t, err, nil)
+
+	// Password is never accepted unchecked
+	err = NewPasswordPolicy(cfg, failingBreaches{}).Check("xK9#mQ2$vL7!", "", "")
+	if err == nil || errors.Is(err, ErrPasswordPolicy) {
+		t.Fatalf("expected checker error, got %v", err)
+	}
+}
+
+func expectViolations(t *testing.T, err error, want []string) {
+	t.Helper()
+
+	if want == nil {
+		if err != nil {
+			t.Fatalf("expected no violations, got %v", err)
+		}
+
+		return
+	}
+
+	var policyErr *PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("expected *PasswordPolicyError, got %v", err)
+	}
+
+	if !errors.Is(err, ErrPasswordPolicy) {
+		t.Fatal("expected error to wrap ErrPasswordPolicy")
+	}
+
+	if !slices.Equal(policyErr.Violations, want) {
+		t.Fatalf("expected violations %v, got %v", want, policyErr.Violations)
+	}
+}
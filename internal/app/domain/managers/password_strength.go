@@ -0,0 +1,255 @@
+package manager
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Upper bounds (log10 of guesses) of the strength scores 0..3, anything
+// above the last one is scored as 4. Mirrors zxcvbn thresholds
+var strengthThresholds = []float64{3, 6, 8, 10}
+
+// Minimal length of a sequence, repeat or keyboard run to be treated as a pattern
+const minPatternLength = 3
+
+var commonWords = []string{
+	"password", "qwerty", "letmein", "welcome", "admin", "administrator",
+	"login", "master", "dragon", "monkey", "football", "baseball", "soccer",
+	"hockey", "iloveyou", "princess", "sunshine", "shadow", "superman",
+	"batman", "starwars", "freedom", "whatever", "secret", "summer", "winter",
+	"spring", "autumn", "hello", "love", "money", "test", "user", "root",
+	"pass", "qwertz", "azerty", "michael", "jordan", "charlie", "computer",
+	"internet", "trustno", "flower", "cookie", "cheese", "killer", "pepper",
+	"ginger", "hunter", "ranger", "buster", "tigger", "robert", "thomas",
+	"daniel", "jessica", "ashley", "matrix", "access", "mustang", "service",
+	"default", "changeme", "wildproject",
+}
+
+var keyboardRows = []string{
+	"`1234567890-=", "qwertyuiop[]\\", "asdfghjkl;'", "zxcvbnm,./",
+}
+
+var leetReplacer = strings.NewReplacer(
+	"4", "a", "@", "a", "3", "e", "1", "i", "!", "i", "0", "o",
+	"$", "s", "5", "s", "7", "t", "+", "t",
+)
+
+// Roughly estimates password strength in zxcvbn manner: password is split
+// into the cheapest known patterns (dictionary words, repeats, sequences,
+// keyboard runs, years) and the rest is brute forced symbol by symbol.
+//
+// Returns score from 0 (too guessable) to 4 (very unguessable)
+func EstimateStrength(password string) int {
+	guesses := estimateGuesses(password)
+
+	for score, threshold := range strengthThresholds {
+		if guesses < threshold {
+			return score
+		}
+	}
+
+	return len(strengthThresholds)
+}
+
+// Returns log10 of guesses needed to crack the password
+func estimateGuesses(password string) float64 {
+	runes := []rune(password)
+	lowered := []rune(strings.ToLower(password))
+	normalized := []rune(leetReplacer.Replace(string(lowered)))
+
+	// Leet replacements preserve length, but lowercasing may not for some
+	// scripts, so fallback to plain brute force estimate in this rare case
+	if len(lowered) != len(runes) || len(normalized) != len(runes) {
+		return bruteForceGuesses(runes)
+	}
+
+	var total float64
+
+	for i := 0; i < len(runes); {
+		length, guesses := matchPattern(runes, lowered, normalized, i)
+		if length == 0 {
+			length, guesses = 1, math.Log10(float64(charsetSize(runes[i])))
+		}
+
+		total += guesses
+		i += length
+	}
+
+	return math.Min(total, bruteForceGuesses(runes))
+}
+
+// Finds the longest known pattern starting at "i".
+//
+// Returns the pattern length and log10 of its guesses or zeros if not found
+func matchPattern(runes, lowered, normalized []rune, i int) (int, float64) {
+	bestLength, bestGuesses := 0, 0.0
+
+	consider := func(length int, guesses float64) {
+		if length > bestLength {
+			bestLength, bestGuesses = length, guesses
+		}
+	}
+
+	rest := string(normalized[i:])
+	for rank, word := range commonWords {
+		if strings.HasPrefix(rest, word) {
+			length := len([]rune(word))
+			// Capitalization and leet substitutions double the guesses
+			variations := 1.0
+			if string(runes[i:i+length]) != word {
+				variations = 2
+			}
+
+			consider(length, math.Log10(float64(rank+1)*variations))
+		}
+	}
+
+	if length := repeatLength(runes, i); length >= minPatternLength {
+		consider(length, math.Log10(float64(charsetSize(runes[i])*length)))
+	}
+
+	if length := sequenceLength(lowered, i); length >= minPatternLength {
+		consider(length, math.Log10(float64(26*length)))
+	}
+
+	if length := keyboardLength(lowered, i); length >= minPatternLength+1 {
+		consider(length, math.Log10(float64(len(keyboardRows)*length*2)))
+	}
+
+	if length := yearLength(runes, i); length > 0 {
+		consider(length, math.Log10(200))
+	}
+
+	return bestLength, bestGuesses
+}
+
+func repeatLength(runes []rune, i int) int {
+	j := i + 1
+	for j < len(runes) && runes[j] == runes[i] {
+		j++
+	}
+
+	return j - i
+}
+
+// Counts ascending or descending sequence like "abc" or "987"
+func sequenceLength(runes []rune, i int) int {
+	if i+1 >= len(runes) {
+		return 1
+	}
+
+	delta := runes[i+1] - runes[i]
+	if delta != 1 && delta != -1 {
+		return 1
+	}
+
+	j := i + 1
+	for j < len(runes) && runes[j]-runes[j-1] == delta {
+		j++
+	}
+
+	return j - i
+}
+
+// Counts sequence of adjacent keys of a single keyboard row in any direction
+func keyboardLength(runes []rune, i int) int {
+	best := 1
+
+	for _, row := range keyboardRows {
+		for _, r := range []string{row, reverse(row)} {
+			j := i
+			start := strings.IndexRune(r, runes[i])
+			if start < 0 {
+				continue
+			}
+
+			for j < len(runes) && start+j-i < len(r) && rune(r[start+j-i]) == runes[j] {
+				j++
+			}
+
+			if j-i > best {
+				best = j - i
+			}
+		}
+	}
+
+	return best
+}
+
+// Matches years from 1900 to 2099
+func yearLength(runes []rune, i int) int {
+	if i+4 > len(runes) {
+		return 0
+	}
+
+	year := string(runes[i : i+4])
+	if !strings.HasPrefix(year, "19") && !strings.HasPrefix(year, "20") {
+		return 0
+	}
+
+	for _, r := range year {
+		if !unicode.IsDigit(r) {
+			return 0
+		}
+	}
+
+	return 4
+}
+
+func bruteForceGuesses(runes []rune) float64 {
+	var lower, upper, digit, symbol, other bool
+
+	for _, r := range runes {
+		switch {
+		case r > unicode.MaxASCII:
+			other = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	size := 0
+	for _, class := range []struct {
+		present bool
+		size    int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if class.present {
+			size += class.size
+		}
+	}
+
+	if size == 0 {
+		return 0
+	}
+
+	return float64(len(runes)) * math.Log10(float64(size))
+}
+
+func charsetSize(r rune) int {
+	switch {
+	case r > unicode.MaxASCII:
+		return 100
+	case unicode.IsLower(r), unicode.IsUpper(r):
+		return 26
+	case unicode.IsDigit(r):
+		return 10
+	}
+
+	return 33
+}
+
+func reverse(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+
+	return string(runes)
+}
@@ -0,0 +1,52 @@
+package manager
+
+import "testing"
+
+func TestEstimateStrength(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		want     int
+	}{
+		{"empty", "", 0},
+		{"common word", "password", 0},
+		{"capitalized leet word", "P@ssw0rd", 0},
+		{"repeat", "aaaaaaaaaaaa", 0},
+		{"sequence", "abcdefgh", 0},
+		{"keyboard run", "qwertyuiop", 0},
+		{"repeated year", "19841984", 1},
+		{"word and year", "hello2023", 1},
+		{"word, year and symbol", "Summer2024!", 1},
+		{"random 6 symbols", "xk9mq2", 2},
+		{"random 7 symbols", "xk9mq2v", 3},
+		{"random 8 symbols", "xk9mq2vb", 4},
+		{"mixed classes", "xK9#mQ2$vL7!", 4},
+		{"passphrase", "correct horse battery staple", 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EstimateStrength(tt.password); got != tt.want {
+				t.Fatalf("expected score %d for %q, got %d", tt.want, tt.password, got)
+			}
+		})
+	}
+}
+
+// Known patterns are never scored higher than brute force of the same symbols
+func TestEstimateStrengthPatternsAreCheaper(t *testing.T) {
+	pairs := []struct {
+		pattern, random string
+	}{
+		{"password", "xkwmqtvb"},
+		{"abcdefgh", "hfbdgcae"},
+		{"qwertyui", "qtiewyru"},
+		{"20242024", "83917265"},
+	}
+
+	for _, p := range pairs {
+		if estimateGuesses(p.pattern) >= estimateGuesses(p.random) {
+			t.Fatalf("expected %q to be cheaper than %q", p.pattern, p.random)
+		}
+	}
+}
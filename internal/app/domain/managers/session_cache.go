@@ -0,0 +1,97 @@
+package manager
+
+import (
+	"strconv"
+	"sync"
+	event "wildproject/internal/app/domain/events"
+	model "wildproject/internal/app/domain/models"
+	"wildproject/internal/cache"
+)
+
+// Caches sessions validated on every protected request. Entries are
+// invalidated by the session events, which are shared between the app
+// instances by the events bus, and otherwise live no longer than TTL
+type SessionCacheManager struct {
+	lru *cache.LRU[int, model.RefreshSession]
+	// Guards the generation, so session read before an invalidation
+	// is never cached after it
+	mu  sync.Mutex
+	gen uint64
+}
+
+// Cache of zero size is disabled
+func NewSessionCache(cfg *model.AuthConfig, es event.Subscriber) *SessionCacheManager {
+	c := &SessionCacheManager{
+		lru: cache.NewLRU[int, model.RefreshSession](cfg.SessionCacheSize, cfg.SessionCacheTTL),
+	}
+
+	es.Subscribe(c.handle)
+
+	return c
+}
+
+func (c *SessionCacheManager) Get(sessionID int) (model.RefreshSession, bool) {
+	return c.lru.Get(sessionID)
+}
+
+func (c *SessionCacheManager) Generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.gen
+}
+
+// Session is not cached if any invalidation happened while it was read,
+// as the invalidation might be about it. That's rare, so the cost is
+// only an extra read
+func (c *SessionCacheManager) Set(session model.RefreshSession, gen uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if gen == c.gen {
+		c.lru.Set(session.SessionID, session)
+	}
+}
+
+func (c *SessionCacheManager) Stats() cache.Stats {
+	return c.lru.Stats()
+}
+
+func (c *SessionCacheManager) handle(e event.Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch e.Name {
+	case event.SessionRevoked, event.SessionInvalidated:
+		rawSessionID, ok := e.Data["session_id"]
+		if !ok {
+			c.invalidateUser(e.UserID)
+			return
+		}
+
+		sessionID, err := strconv.Atoi(rawSessionID)
+		if err != nil {
+			c.invalidateUser(e.UserID)
+			return
+		}
+
+		c.gen++
+		c.lru.Delete(sessionID)
+	case event.PasswordChanged, event.UserDeleted:
+		c.invalidateUser(e.UserID)
+	case event.Resync:
+		// Invalidations might have been lost
+		c.gen++
+		c.lru.DeleteFunc(func(_ int, _ model.RefreshSession) bool {
+			return true
+		})
+	}
+}
+
+// Must be called with the lock held
+func (c *SessionCacheManager) invalidateUser(userID string) {
+	c.gen++
+	c.lru.DeleteFunc(func(_ int, s model.RefreshSession) bool {
+		return s.UserID == userID
+	})
+}
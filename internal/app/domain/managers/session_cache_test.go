@@ -0,0 +1,60 @@
+package manager
+
+import (
+	"testing"
+	"time"
+	event "wildproject/internal/app/domain/events"
+	model "wildproject/internal/app/domain/models"
+)
+
+func newTestSessionCache() (*SessionCacheManager, *event.Bus) {
+	bus := event.NewBus()
+	cfg := &model.AuthConfig{SessionCacheSize: 10, SessionCacheTTL: time.Minute}
+
+	return NewSessionCache(cfg, bus), bus
+}
+
+func TestSessionCacheSkipsSessionInvalidatedDuringRead(t *testing.T) {
+	c, bus := newTestSessionCache()
+
+	gen := c.Generation()
+	bus.Publish(event.New(event.SessionRevoked, "user", map[string]string{"session_id": "1"}))
+	c.Set(model.RefreshSession{SessionID: 1, UserID: "user"}, gen)
+
+	if _, ok := c.Get(1); ok {
+		t.Fatal("session invalidated during the read is cached")
+	}
+
+	c.Set(model.RefreshSession{SessionID: 1, UserID: "user"}, c.Generation())
+
+	if _, ok := c.Get(1); !ok {
+		t.Fatal("session is not cached")
+	}
+}
+
+func TestSessionCacheInvalidation(t *testing.T) {
+	tests := []struct {
+		name string
+		ev   event.Event
+	}{
+		{"session revoked", event.New(event.SessionRevoked, "user", map[string]string{"session_id": "1"})},
+		{"session invalidated", event.New(event.SessionInvalidated, "user", map[string]string{"session_id": "1"})},
+		{"all sessions revoked", event.New(event.SessionRevoked, "user", nil)},
+		{"password changed", event.New(event.PasswordChanged, "user", nil)},
+		{"user deleted", event.New(event.UserDeleted, "user", nil)},
+		{"resync", event.New(event.Resync, "", nil)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, bus := newTestSessionCache()
+			c.Set(model.RefreshSession{SessionID: 1, UserID: "user"}, c.Generation())
+
+			bus.Publish(tt.ev)
+
+			if _, ok := c.Get(1); ok {
+				t.Fatal("session is still cached")
+			}
+		})
+	}
+}
@@ -1,9 +1,50 @@
 package manager
 
-import model "wildproject/internal/app/domain/models"
+import (
+	"io"
+	model "wildproject/internal/app/domain/models"
+	"wildproject/internal/cache"
+)
 
 type TokenManager interface {
 	Parse(accessToken string) (model.TokenPayload, error)
 	Generate(sessionID int, userID string) (string, error)
 	ParseAndValidate(accessToken string) (model.TokenPayload, error)
 }
+
+// Keys the token manager signs and verifies the tokens with
+type SigningKeySet interface {
+	SetKeys(keys []model.SigningKey)
+}
+
+type PasswordPolicy interface {
+	Check(password, email, name string) error
+}
+
+type BreachChecker interface {
+	IsBreached(password string) (bool, error)
+}
+
+type PasswordHasher interface {
+	Algorithm() string
+	Hash(password string) (string, error)
+	Verify(password, encoded string) (bool, error)
+	NeedsRehash(encoded string) bool
+}
+
+type AvatarManager interface {
+	Upload(userID string, r io.Reader) ([]model.ImageVariant, error)
+	Delete(variants []model.ImageVariant) error
+	DefaultVariants(userID string) []model.ImageVariant
+	RendersInitials() bool
+	RenderDefault(userID, name string, size int, format string) ([]byte, string, error)
+}
+
+type SessionCache interface {
+	Get(sessionID int) (model.RefreshSession, bool)
+	// Returns the current generation, which is changed by every invalidation
+	Generation() uint64
+	// Caches session read after "gen" was taken, unless it was invalidated since
+	Set(session model.RefreshSession, gen uint64)
+	Stats() cache.Stats
+}
@@ -2,35 +2,192 @@ package model
 
 import "time"
 
+// Config value redacted when the config is printed or formatted
+type Secret string
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+
+	return "[redacted]"
+}
+
+func (s Secret) MarshalYAML() (any, error) {
+	return s.String(), nil
+}
+
+// Resolved app config. File keys match the env variables, e.g.
+// "auth.jwt_secret" is overridden by AUTH_JWT_SECRET. Durations in env
+// are given in their historical units, see the "unit" tags, e.g.
+// AUTH_ACCESS_TOKEN_TTL=15 is 15 minutes, though "15m" is accepted too
 type Config struct {
-	Name     string
-	Env      string
-	Database DatabaseConfig
-	Server   ServerConfig
-	Auth     AuthConfig
-	Sentry   SentryConfig
+	Name     string         `yaml:"name" toml:"name" env:"NAME"`
+	Env      string         `yaml:"env" toml:"env" env:"ENV"`
+	Database DatabaseConfig `yaml:"database" toml:"database" envPrefix:"DATABASE_"`
+	Server   ServerConfig   `yaml:"server" toml:"server" envPrefix:"SERVER_"`
+	Auth     AuthConfig     `yaml:"auth" toml:"auth" envPrefix:"AUTH_"`
+	Sessions SessionsConfig `yaml:"sessions" toml:"sessions" envPrefix:"SESSIONS_"`
+	Password PasswordConfig `yaml:"password" toml:"password" envPrefix:"PASSWORD_"`
+	Users    UsersConfig    `yaml:"users" toml:"users" envPrefix:"USERS_"`
+	Storage  StorageConfig  `yaml:"storage" toml:"storage" envPrefix:"STORAGE_"`
+	Avatar   AvatarConfig   `yaml:"avatar" toml:"avatar" envPrefix:"AVATAR_"`
+	Export   ExportConfig   `yaml:"export" toml:"export" envPrefix:"EXPORT_"`
+	Mail     MailConfig     `yaml:"mail" toml:"mail" envPrefix:"MAIL_"`
+	Events   EventsConfig   `yaml:"events" toml:"events" envPrefix:"EVENTS_"`
+	Sentry   SentryConfig   `yaml:"sentry" toml:"sentry" envPrefix:"SENTRY_"`
 }
 
 type DatabaseConfig struct {
-	Conn string
+	// Either "postgres" or "memory"
+	Driver string `yaml:"driver" toml:"driver" env:"DRIVER"`
+	Conn   Secret `yaml:"conn_string" toml:"conn_string" env:"CONN_STRING"`
+	// Max duration of a single query, 0 means no limit. Env gives it
+	// in milliseconds, as queries are expected to be fast
+	QueryTimeout time.Duration `yaml:"query_timeout" toml:"query_timeout" env:"QUERY_TIMEOUT" unit:"1ms"`
+	// Apply pending schema migrations before serving
+	MigrateOnStart bool `yaml:"migrate_on_start" toml:"migrate_on_start" env:"MIGRATE_ON_START"`
 }
 
 type ServerConfig struct {
-	Addr         string
-	ReadTimeout  time.Duration
-	WriteTimeout time.Duration
-	IdleTimeout  time.Duration
+	Addr         string        `yaml:"addr" toml:"addr" env:"ADDR"`
+	ReadTimeout  time.Duration `yaml:"read_timeout" toml:"read_timeout" env:"READ_TIMEOUT" unit:"1s"`
+	WriteTimeout time.Duration `yaml:"write_timeout" toml:"write_timeout" env:"WRITE_TIMEOUT" unit:"1s"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout" toml:"idle_timeout" env:"IDLE_TIMEOUT" unit:"1s"`
+	// Time between readiness failing and closing the listener, so load
+	// balancer stops routing new requests to the instance
+	ShutdownDelay time.Duration `yaml:"shutdown_delay" toml:"shutdown_delay" env:"SHUTDOWN_DELAY" unit:"1s"`
+	// Time given to in-flight requests to finish on shutdown
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" unit:"1s"`
 }
 
 type AuthConfig struct {
-	AuthJwtSecret   []byte
-	AccessTokenTTL  time.Duration
-	RefreshTokenTTL time.Duration
+	AuthJwtSecret   Secret        `yaml:"jwt_secret" toml:"jwt_secret" env:"JWT_SECRET"`
+	AccessTokenTTL  time.Duration `yaml:"access_token_ttl" toml:"access_token_ttl" env:"ACCESS_TOKEN_TTL" unit:"1m"`
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl" toml:"refresh_token_ttl" env:"REFRESH_TOKEN_TTL" unit:"1m"`
+	// Max number of cached sessions, 0 disables the cache
+	SessionCacheSize int           `yaml:"session_cache_size" toml:"session_cache_size" env:"SESSION_CACHE_SIZE"`
+	SessionCacheTTL  time.Duration `yaml:"session_cache_ttl" toml:"session_cache_ttl" env:"SESSION_CACHE_TTL" unit:"1s"`
+}
+
+type SessionsConfig struct {
+	// Either "database" or "redis"
+	Driver        string `yaml:"driver" toml:"driver" env:"DRIVER"`
+	RedisAddr     string `yaml:"redis_addr" toml:"redis_addr" env:"REDIS_ADDR"`
+	RedisPassword Secret `yaml:"redis_password" toml:"redis_password" env:"REDIS_PASSWORD"`
+	RedisDB       int    `yaml:"redis_db" toml:"redis_db" env:"REDIS_DB"`
+	// Prefix of all the keys, so the server can be shared
+	RedisPrefix string `yaml:"redis_prefix" toml:"redis_prefix" env:"REDIS_PREFIX"`
+	// How long the server keeps expired sessions, so they are still
+	// listed and refreshing them is told apart from unknown tokens
+	RedisExpiredRetention time.Duration `yaml:"redis_expired_retention" toml:"redis_expired_retention" env:"REDIS_EXPIRED_RETENTION" unit:"1h"`
+}
+
+type PasswordConfig struct {
+	MinLength int `yaml:"min_length" toml:"min_length" env:"MIN_LENGTH"`
+	// In runes. Bcrypt also limits passwords to 72 bytes
+	MaxLength     int  `yaml:"max_length" toml:"max_length" env:"MAX_LENGTH"`
+	RequireLower  bool `yaml:"require_lower" toml:"require_lower" env:"REQUIRE_LOWER"`
+	RequireUpper  bool `yaml:"require_upper" toml:"require_upper" env:"REQUIRE_UPPER"`
+	RequireDigit  bool `yaml:"require_digit" toml:"require_digit" env:"REQUIRE_DIGIT"`
+	RequireSymbol bool `yaml:"require_symbol" toml:"require_symbol" env:"REQUIRE_SYMBOL"`
+	MaxRepeated   int  `yaml:"max_repeated" toml:"max_repeated" env:"MAX_REPEATED"`
+	DenyPersonal  bool `yaml:"deny_personal" toml:"deny_personal" env:"DENY_PERSONAL"`
+	MinStrength   int  `yaml:"min_strength" toml:"min_strength" env:"MIN_STRENGTH"`
+	// Number of the last passwords (including current) that can't be reused
+	HistorySize   int    `yaml:"history_size" toml:"history_size" env:"HISTORY_SIZE"`
+	HashAlgorithm string `yaml:"hash_algorithm" toml:"hash_algorithm" env:"HASH_ALGORITHM"`
+	// Argon2id memory in KiB
+	Argon2Memory  uint32 `yaml:"argon2_memory" toml:"argon2_memory" env:"ARGON2_MEMORY"`
+	Argon2Time    uint32 `yaml:"argon2_time" toml:"argon2_time" env:"ARGON2_TIME"`
+	Argon2Threads uint8  `yaml:"argon2_threads" toml:"argon2_threads" env:"ARGON2_THREADS"`
+	BcryptCost    int    `yaml:"bcrypt_cost" toml:"bcrypt_cost" env:"BCRYPT_COST"`
+	// Path to HIBP "Pwned Passwords" SHA-1 file ordered by hash, optional
+	BreachCorpusPath string `yaml:"breach_corpus_path" toml:"breach_corpus_path" env:"BREACH_CORPUS_PATH"`
+}
+
+type UsersConfig struct {
+	DeletionGracePeriod time.Duration `yaml:"deletion_grace_period" toml:"deletion_grace_period" env:"DELETION_GRACE_PERIOD" unit:"1h"`
+	// Either "anonymize" or "delete"
+	DeletionMode     string        `yaml:"deletion_mode" toml:"deletion_mode" env:"DELETION_MODE"`
+	DeletionInterval time.Duration `yaml:"deletion_interval" toml:"deletion_interval" env:"DELETION_INTERVAL" unit:"1m"`
+	// How long the new email can be confirmed
+	EmailChangeTTL time.Duration `yaml:"email_change_ttl" toml:"email_change_ttl" env:"EMAIL_CHANGE_TTL" unit:"1h"`
+	// Public URLs of the email change confirmation and cancelation,
+	// "{token}" is replaced with the corresponding token
+	EmailConfirmURL string `yaml:"email_confirm_url" toml:"email_confirm_url" env:"EMAIL_CONFIRM_URL"`
+	EmailCancelURL  string `yaml:"email_cancel_url" toml:"email_cancel_url" env:"EMAIL_CANCEL_URL"`
+	// Roles the operators can grant
+	Roles []string `yaml:"roles" toml:"roles" env:"ROLES"`
+}
+
+type StorageConfig struct {
+	// Either "local" or "s3"
+	Driver string `yaml:"driver" toml:"driver" env:"DRIVER"`
+	// Base URL stored objects are publicly available by
+	PublicURL string `yaml:"public_url" toml:"public_url" env:"PUBLIC_URL"`
+	LocalDir  string `yaml:"local_dir" toml:"local_dir" env:"LOCAL_DIR"`
+	// Directory of the private objects, it must not be served publicly,
+	// so it can't be inside the "LocalDir"
+	PrivateDir string `yaml:"private_dir" toml:"private_dir" env:"PRIVATE_DIR"`
+	S3Endpoint string `yaml:"s3_endpoint" toml:"s3_endpoint" env:"S3_ENDPOINT"`
+	S3Region   string `yaml:"s3_region" toml:"s3_region" env:"S3_REGION"`
+	S3Bucket   string `yaml:"s3_bucket" toml:"s3_bucket" env:"S3_BUCKET"`
+	// Bucket of the private objects, it must not allow public reads
+	S3PrivateBucket string `yaml:"s3_private_bucket" toml:"s3_private_bucket" env:"S3_PRIVATE_BUCKET"`
+	S3AccessKey     string `yaml:"s3_access_key" toml:"s3_access_key" env:"S3_ACCESS_KEY"`
+	S3SecretKey     Secret `yaml:"s3_secret_key" toml:"s3_secret_key" env:"S3_SECRET_KEY"`
+	S3PathStyle     bool   `yaml:"s3_path_style" toml:"s3_path_style" env:"S3_PATH_STYLE"`
+}
+
+type AvatarConfig struct {
+	// Max upload size in bytes, env gives it in KiB
+	MaxSize      int64    `yaml:"max_size" toml:"max_size" env:"MAX_SIZE" unit:"1024"`
+	AllowedTypes []string `yaml:"allowed_types" toml:"allowed_types" env:"ALLOWED_TYPES"`
+	// Max width * height of the uploaded image
+	MaxPixels int64 `yaml:"max_pixels" toml:"max_pixels" env:"MAX_PIXELS"`
+	// Sides of the square variants avatar is resized into
+	Sizes []int `yaml:"sizes" toml:"sizes" env:"SIZES"`
+	// JPEG quality of the variants, 1..100
+	Quality int `yaml:"quality" toml:"quality" env:"QUALITY"`
+	// Public URL of the generated avatar, "{user_id}" is replaced with
+	// user's id
+	DefaultURL string `yaml:"default_url" toml:"default_url" env:"DEFAULT_URL"`
+	// Show initials of the user's name on the generated avatar instead of
+	// identicon. The avatar is public, so it reveals the name and whether
+	// the account exists
+	DefaultInitials bool `yaml:"default_initials" toml:"default_initials" env:"DEFAULT_INITIALS"`
+}
+
+type ExportConfig struct {
+	// How long the ready export can be downloaded
+	LinkTTL      time.Duration `yaml:"link_ttl" toml:"link_ttl" env:"LINK_TTL" unit:"1h"`
+	PollInterval time.Duration `yaml:"poll_interval" toml:"poll_interval" env:"POLL_INTERVAL" unit:"1s"`
+	// Public URL of the export download, "{token}" is replaced with
+	// the export's download token
+	DownloadURL string `yaml:"download_url" toml:"download_url" env:"DOWNLOAD_URL"`
+}
+
+type MailConfig struct {
+	// Either "log" or "smtp"
+	Driver       string `yaml:"driver" toml:"driver" env:"DRIVER"`
+	From         string `yaml:"from" toml:"from" env:"FROM"`
+	SMTPHost     string `yaml:"smtp_host" toml:"smtp_host" env:"SMTP_HOST"`
+	SMTPPort     int    `yaml:"smtp_port" toml:"smtp_port" env:"SMTP_PORT"`
+	SMTPUsername string `yaml:"smtp_username" toml:"smtp_username" env:"SMTP_USERNAME"`
+	SMTPPassword Secret `yaml:"smtp_password" toml:"smtp_password" env:"SMTP_PASSWORD"`
+}
+
+type EventsConfig struct {
+	// Either "local" for a single instance or "postgres" to share
+	// events between instances through LISTEN/NOTIFY
+	Driver  string `yaml:"driver" toml:"driver" env:"DRIVER"`
+	Channel string `yaml:"channel" toml:"channel" env:"CHANNEL"`
 }
 
 type SentryConfig struct {
-	Dsn              string
-	TracesSampleRate float64
-	AttachStackTrace bool
-	Debug            bool
+	Dsn              Secret  `yaml:"dsn" toml:"dsn" env:"DSN"`
+	TracesSampleRate float64 `yaml:"traces_sample_rate" toml:"traces_sample_rate" env:"TRACES_SAMPLE_RATE"`
+	AttachStackTrace bool    `yaml:"attach_stacktrace" toml:"attach_stacktrace" env:"ATTACH_STACKTRACE"`
+	Debug            bool    `yaml:"debug" toml:"debug" env:"DEBUG"`
 }
@@ -0,0 +1,44 @@
+package model
+
+import "wildproject/internal/stamp"
+
+// Types of the security audit events
+const (
+	AuditSignup                = "user.signup"
+	AuditUserCreated           = "user.created"
+	AuditLoginSucceeded        = "user.login_succeeded"
+	AuditLoginFailed           = "user.login_failed"
+	AuditPasswordChanged       = "user.password_changed"
+	AuditPasswordRehashed      = "user.password_rehashed"
+	AuditPasswordReset         = "user.password_reset"
+	AuditEmailChangeRequested  = "user.email_change_requested"
+	AuditEmailChangeCanceled   = "user.email_change_canceled"
+	AuditEmailChanged          = "user.email_changed"
+	AuditDeletionRequested     = "user.deletion_requested"
+	AuditDeletionCanceled      = "user.deletion_canceled"
+	AuditRolesGranted          = "user.roles_granted"
+	AuditSessionCreated        = "session.created"
+	AuditSessionRefreshed      = "session.refreshed"
+	AuditRefreshTokenRejected  = "session.refresh_token_rejected"
+	AuditAccessTokenReused     = "session.access_token_reused"
+	AuditDeviceMismatch        = "session.device_mismatch"
+	AuditSessionDropped        = "session.dropped"
+	AuditSessionsDropped       = "session.dropped_all"
+	AuditExpiredSessionsPurged = "session.expired_purged"
+	AuditMigrationsApplied     = "schema.migrations_applied"
+	AuditMigrationsReverted    = "schema.migrations_reverted"
+	AuditMigrationsBaselined   = "schema.migrations_baselined"
+	AuditInvalidAccessToken    = "auth.invalid_access_token"
+	AuditSigningKeysRotated    = "auth.signing_keys_rotated"
+)
+
+type AuditEvent struct {
+	ID        int64             `json:"event_id"`
+	Type      string            `json:"type"`
+	ActorID   string            `json:"actor_id,omitempty"`
+	TargetID  string            `json:"target_id,omitempty"`
+	IP        string            `json:"ip,omitempty"`
+	Uagent    string            `json:"user_agent,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	CreatedAt stamp.Stamp       `json:"created_at"`
+}
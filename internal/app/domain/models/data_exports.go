@@ -0,0 +1,43 @@
+package model
+
+import "wildproject/internal/stamp"
+
+const (
+	DataExportPending    = "pending"
+	DataExportProcessing = "processing"
+	DataExportReady      = "ready"
+	DataExportFailed     = "failed"
+	DataExportExpired    = "expired"
+)
+
+const (
+	DataExportFormatJSON = "json"
+	DataExportFormatZIP  = "zip"
+)
+
+type DataExport struct {
+	ID          string       `json:"export_id"`
+	Status      string       `json:"status"`
+	Format      string       `json:"format"`
+	DownloadURL string       `json:"download_url,omitempty"`
+	ExpiresAt   *stamp.Stamp `json:"expires_at,omitempty"`
+	CreatedAt   stamp.Stamp  `json:"created_at"`
+}
+
+// Everything stored about the user, answers subject access requests
+type PersonalData struct {
+	GeneratedAt     stamp.Stamp           `json:"generated_at"`
+	User            UserDetailed          `json:"user"`
+	Sessions        []PersonalDataSession `json:"sessions"`
+	PasswordChanges []stamp.Stamp         `json:"password_changes"`
+	SecurityEvents  []AuditEvent          `json:"security_events"`
+}
+
+// Refresh session without tokens, which are credentials, not personal data
+type PersonalDataSession struct {
+	SessionID int         `json:"session_id"`
+	Uagent    string      `json:"user_agent"`
+	Fprint    string      `json:"fingerprint"`
+	ExpiresAt stamp.Stamp `json:"expires_at"`
+	CreatedAt stamp.Stamp `json:"created_at"`
+}
@@ -1,6 +1,19 @@
 package model
 
-import "wildproject/internal/stamp"
+import (
+	"time"
+	"wildproject/internal/stamp"
+)
+
+const (
+	SessionStatusActive  = "active"
+	SessionStatusExpired = "expired"
+)
+
+const (
+	SortAsc  = "asc"
+	SortDesc = "desc"
+)
 
 type RefreshSession struct {
 	SessionID    int         `json:"session_id"`
@@ -13,10 +26,48 @@ type RefreshSession struct {
 }
 
 type ClientRefreshSession struct {
-	SessionID int         `json:"session_id"`
-	Uagent    string      `json:"user_agent"`
-	ExpiresAt stamp.Stamp `json:"expires_at,omitempty"`
-	CreatedAt stamp.Stamp `json:"created_at,omitempty"`
+	SessionID int           `json:"session_id"`
+	Uagent    string        `json:"user_agent"`
+	Device    SessionDevice `json:"device"`
+	IsCurrent bool          `json:"is_current"`
+	ExpiresAt stamp.Stamp   `json:"expires_at,omitempty"`
+	CreatedAt stamp.Stamp   `json:"created_at,omitempty"`
+}
+
+// Client info parsed from the user agent, so clients can show
+// e.g. "Chrome on macOS". Fields are empty if unknown
+type SessionDevice struct {
+	Browser        string `json:"browser,omitempty"`
+	BrowserVersion string `json:"browser_version,omitempty"`
+	OS             string `json:"os,omitempty"`
+	OSVersion      string `json:"os_version,omitempty"`
+	// Either "desktop", "mobile", "tablet" or "bot"
+	Type       string `json:"type,omitempty"`
+	Model      string `json:"model,omitempty"`
+	AppVersion string `json:"app_version,omitempty"`
+}
+
+type SessionsFilter struct {
+	UserID string
+	// Session the request is made with
+	CurrentSessionID int
+	// Either SessionStatusActive, SessionStatusExpired or empty for both
+	Status string
+	// Browser, os or device type, ignoring case
+	Device       string
+	CreatedAfter *time.Time
+	// Id of the last session of the previous page, 0 for the first page
+	Cursor int
+	// Either SortAsc or SortDesc by creation
+	Order string
+	// 0 means no limit
+	Limit int
+}
+
+type SessionsPage struct {
+	Sessions []ClientRefreshSession `json:"sessions"`
+	// Cursor of the next page, 0 if there are no more sessions
+	NextCursor int `json:"next_cursor"`
 }
 
 type TokenPair struct {
@@ -27,14 +78,24 @@ type TokenPair struct {
 type TokenPayload struct {
 	SessionID int    `json:"session_id"`
 	UserID    string `json:"usuer_id"`
+	// Zero if the token has no expiration
+	ExpiresAt time.Time `json:"-"`
 }
 
 type DeviceInfo struct {
 	Uagent string `json:"user_agent"`
 	Fprint string `json:"fingerprint"`
+	IP     string `json:"ip"`
 }
 
 type CommonRequestPayload struct {
 	TokenPayload
 	DeviceInfo
 }
+
+// Key the access tokens are signed with, identified in the tokens by "kid"
+type SigningKey struct {
+	ID        string
+	Secret    []byte
+	CreatedAt time.Time
+}
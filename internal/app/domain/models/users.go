@@ -8,17 +8,31 @@ type (
 		Email     string      `json:"email"`
 		CreatedAt stamp.Stamp `json:"created_at,omitempty"`
 		UpdatedAt stamp.Stamp `json:"updated_at,omitempty"`
+		// Set if account is pending deletion
+		DeleteAfter *stamp.Stamp `json:"delete_after,omitempty"`
 	}
 
 	UserDetailed struct {
 		User
-		Name     string `json:"name,omitempty"`
-		Sex      int    `json:"sex,omitempty"`
-		ImageURL string `json:"img_url,omitempty"`
+		Name   string         `json:"name,omitempty"`
+		Sex    int            `json:"sex,omitempty"`
+		Avatar []ImageVariant `json:"avatar,omitempty"`
+	}
+
+	// Square image of the "size" side
+	ImageVariant struct {
+		Size int    `json:"size"`
+		URL  string `json:"url"`
 	}
 
 	UserWithCredentials struct {
 		User
 		PasswordHash string `json:"-"`
 	}
+
+	// Email change waiting for confirmation from the new address
+	PendingEmailChange struct {
+		Email     string      `json:"email"`
+		ExpiresAt stamp.Stamp `json:"expires_at"`
+	}
 )
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	entity "wildproject/internal/app/data/entities"
+	repo "wildproject/internal/app/data/repositories"
+	model "wildproject/internal/app/domain/models"
+	"wildproject/internal/stamp"
+
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// Pseudonyms are truncated, as they only need to link attempts together
+const pseudonymLength = 16
+
+type Audit struct {
+	repo repo.AuditEventsRepo
+	key  []byte
+}
+
+// Pseudonym key is derived from "secret", so it differs from the key
+// the secret is used as elsewhere
+func NewAudit(r repo.AuditEventsRepo, secret []byte) *Audit {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("audit pseudonym"))
+
+	return &Audit{r, mac.Sum(nil)}
+}
+
+// Persists security event. Audit must never break the audited operation,
+// so errors are only logged. Events are recorded even if the request is
+// canceled meanwhile, as failed and aborted attempts matter the most
+func (a *Audit) Record(ctx context.Context, e model.AuditEvent) {
+	err := a.repo.Create(context.WithoutCancel(ctx), entity.AuditEvent{
+		EventType: e.Type,
+		ActorID:   e.ActorID,
+		TargetID:  e.TargetID,
+		IP:        e.IP,
+		Uagent:    e.Uagent,
+		Metadata:  e.Metadata,
+	})
+
+	if err != nil {
+		log.Errorf("cannot record audit event %s: %s", e.Type, err)
+	}
+}
+
+// Returns keyed hash of personal data that must not be stored as is,
+// e.g. email of unknown account, so repeated values are still linkable
+func (a *Audit) Pseudonymize(value string) string {
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(value))
+
+	return hex.EncodeToString(mac.Sum(nil))[:pseudonymLength]
+}
+
+// Returns up to "limit" events made by or to the user, newest first.
+// Pass "before" of 0 to get the first page, then id of the last event
+func (a *Audit) FindAll(ctx context.Context, userID string, before int64, limit int) ([]model.AuditEvent, error) {
+	if before <= 0 {
+		before = math.MaxInt64
+	}
+
+	ents, err := a.repo.FindAllByUserID(ctx, userID, before, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]model.AuditEvent, 0, len(ents))
+	for _, e := range ents {
+		events = append(events, model.AuditEvent{
+			ID:        e.EventID,
+			Type:      e.EventType,
+			ActorID:   e.ActorID,
+			TargetID:  e.TargetID,
+			IP:        e.IP,
+			Uagent:    e.Uagent,
+			Metadata:  e.Metadata,
+			CreatedAt: stamp.Parse(e.CreatedAt),
+		})
+	}
+
+	return events, nil
+}
+
+// Builds event made by the user from the device, "meta" is optional
+func userEvent(
+	eventType, userID string, device model.DeviceInfo, meta map[string]string,
+) model.AuditEvent {
+	return model.AuditEvent{
+		Type:     eventType,
+		ActorID:  userID,
+		TargetID: userID,
+		IP:       device.IP,
+		Uagent:   device.Uagent,
+		Metadata: meta,
+	}
+}
+
+// Builds event made by an operator, e.g. with wildctl, to the user or to
+// no one in particular if "userID" is empty. Operators have no account,
+// so the actor is left empty
+func OperatorEvent(
+	eventType, userID string, device model.DeviceInfo, meta map[string]string,
+) model.AuditEvent {
+	return model.AuditEvent{
+		Type:     eventType,
+		TargetID: userID,
+		IP:       device.IP,
+		Uagent:   device.Uagent,
+		Metadata: meta,
+	}
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+	entity "wildproject/internal/app/data/entities"
+	repo "wildproject/internal/app/data/repositories"
+	"wildproject/internal/app/data/storage"
+	event "wildproject/internal/app/domain/events"
+	model "wildproject/internal/app/domain/models"
+	"wildproject/internal/stamp"
+)
+
+// Placeholder of the public URLs replaced with the token
+const urlToken = "{token}"
+
+var (
+	ErrUnknownExportFormat = errors.New("unknown export format, expected json or zip")
+	ErrExportNotReady      = errors.New("export is not ready")
+	ErrExportExpired       = errors.New("export is expired")
+)
+
+// Personal data exports are requested here and assembled asynchronously
+// by job.DataExports
+type DataExports struct {
+	cfg      *model.ExportConfig
+	repo     repo.DataExportsRepo
+	archives storage.Objects
+	events   event.Publisher
+}
+
+func NewDataExports(
+	cfg *model.ExportConfig,
+	r repo.DataExportsRepo,
+	as storage.Objects,
+	ep event.Publisher,
+) *DataExports {
+	return &DataExports{cfg, r, as, ep}
+}
+
+// Queues new export or returns the one already queued for the user
+func (d *DataExports) Request(ctx context.Context, userID, format string) (model.DataExport, error) {
+	if format != model.DataExportFormatJSON && format != model.DataExportFormatZIP {
+		return model.DataExport{}, ErrUnknownExportFormat
+	}
+
+	active, err := d.findActive(ctx, userID)
+	if !errors.Is(err, ErrNotFound) {
+		return active, err
+	}
+
+	exportID, err := d.repo.Create(ctx, userID, format)
+	if err != nil {
+		// Concurrent request has just queued one
+		if errors.Is(err, repo.ErrActiveExportExists) {
+			return d.findActive(ctx, userID)
+		}
+
+		return model.DataExport{}, err
+	}
+
+	d.events.Publish(event.New(event.DataExportRequested, userID, map[string]string{
+		"export_id": exportID,
+	}))
+
+	return d.Find(ctx, userID, exportID)
+}
+
+func (d *DataExports) findActive(ctx context.Context, userID string) (model.DataExport, error) {
+	ent, err := d.repo.FindActive(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrNotFound
+		}
+
+		return model.DataExport{}, err
+	}
+
+	return d.toModel(ent), nil
+}
+
+// Finds user's export, exports of other users are never found
+func (d *DataExports) Find(ctx context.Context, userID, exportID string) (model.DataExport, error) {
+	ent, err := d.repo.FindByID(ctx, exportID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.DataExport{}, ErrNotFound
+		}
+
+		return model.DataExport{}, err
+	}
+
+	if ent.UserID != userID {
+		return model.DataExport{}, ErrNotFound
+	}
+
+	return d.toModel(ent), nil
+}
+
+// Opens ready export's archive by its download token
+func (d *DataExports) Open(ctx context.Context, token string) (io.ReadCloser, model.DataExport, error) {
+	ent, err := d.repo.FindByToken(ctx, token)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.DataExport{}, ErrNotFound
+		}
+
+		return nil, model.DataExport{}, err
+	}
+
+	if ent.Status != model.DataExportReady {
+		return nil, model.DataExport{}, ErrExportNotReady
+	}
+
+	if time.Now().After(stamp.Parse(ent.ExpiresAt.String).Time) {
+		return nil, model.DataExport{}, ErrExportExpired
+	}
+
+	archive, err := d.archives.Get(ent.ObjectKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, model.DataExport{}, ErrExportExpired
+		}
+
+		return nil, model.DataExport{}, err
+	}
+
+	return archive, d.toModel(ent), nil
+}
+
+func (d *DataExports) toModel(ent entity.DataExport) model.DataExport {
+	export := model.DataExport{
+		ID:        ent.ExportID,
+		Status:    ent.Status,
+		Format:    ent.Format,
+		CreatedAt: stamp.Parse(ent.CreatedAt),
+	}
+
+	if ent.ExpiresAt.Valid {
+		expiresAt := stamp.Parse(ent.ExpiresAt.String)
+		export.ExpiresAt = &expiresAt
+	}
+
+	if ent.Status == model.DataExportReady && ent.DownloadToken != "" {
+		export.DownloadURL = tokenURL(d.cfg.DownloadURL, ent.DownloadToken)
+	}
+
+	return export
+}
+
+// Builds public URL by the template containing token placeholder
+func tokenURL(template, token string) string {
+	return strings.ReplaceAll(template, urlToken, url.PathEscape(token))
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	repo "wildproject/internal/app/data/repositories"
+	model "wildproject/internal/app/domain/models"
+)
+
+// Role allowed to see the service internals, e.g. the metrics
+const RoleAdmin = "admin"
+
+var (
+	ErrUnknownRole = errors.New("unknown role")
+)
+
+// Roles the operators grant to the users. Only the roles listed in the
+// config can be granted, so a typo never becomes a role
+type Roles struct {
+	cfg   *model.UsersConfig
+	repo  repo.UserRolesRepo
+	users repo.UsersRepo
+	audit AuditRecorder
+}
+
+func NewRoles(
+	cfg *model.UsersConfig,
+	r repo.UserRolesRepo,
+	ur repo.UsersRepo,
+	ar AuditRecorder,
+) *Roles {
+	return &Roles{cfg, r, ur, ar}
+}
+
+// Grants the roles on behalf of an operator, e.g. with wildctl. Returns
+// all the user's roles, including the ones granted before
+func (r *Roles) Grant(ctx context.Context, userID string, roles []string, device model.DeviceInfo) ([]string, error) {
+	for _, role := range roles {
+		if !slices.Contains(r.cfg.Roles, role) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownRole, role)
+		}
+	}
+
+	if err := r.findUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	if err := r.repo.Grant(ctx, userID, roles); err != nil {
+		return nil, err
+	}
+
+	r.audit.Record(ctx, OperatorEvent(model.AuditRolesGranted, userID, device, map[string]string{
+		"roles": strings.Join(roles, ","),
+	}))
+
+	return r.repo.FindAll(ctx, userID)
+}
+
+func (r *Roles) FindAll(ctx context.Context, userID string) ([]string, error) {
+	return r.repo.FindAll(ctx, userID)
+}
+
+func (r *Roles) findUser(ctx context.Context, userID string) error {
+	_, err := r.users.FindByID(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+
+	return err
+}
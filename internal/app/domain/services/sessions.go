@@ -1,14 +1,18 @@
 package service
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 	entity "wildproject/internal/app/data/entities"
 	repo "wildproject/internal/app/data/repositories"
+	event "wildproject/internal/app/domain/events"
 	manager "wildproject/internal/app/domain/managers"
 	model "wildproject/internal/app/domain/models"
 	"wildproject/internal/stamp"
+	"wildproject/internal/useragent"
 
 	"github.com/gofiber/fiber/v2/log"
 )
@@ -20,50 +24,67 @@ var (
 )
 
 type Sessions struct {
-	cfg  *model.AuthConfig
-	repo repo.SessionsRepo
-	tm   manager.TokenManager
+	cfg    *model.AuthConfig
+	repo   repo.SessionsRepo
+	tm     manager.TokenManager
+	audit  AuditRecorder
+	events event.Publisher
+	cache  manager.SessionCache
+	uow    repo.UnitOfWork
 }
 
 func NewSessions(
 	cfg *model.AuthConfig,
 	sr repo.SessionsRepo,
 	tm manager.TokenManager,
+	ar AuditRecorder,
+	ep event.Publisher,
+	sc manager.SessionCache,
+	uow repo.UnitOfWork,
 ) *Sessions {
-	return &Sessions{cfg, sr, tm}
+	return &Sessions{cfg, sr, tm, ar, ep, sc, uow}
 }
 
-func (s *Sessions) Find(sessionID int) (model.ClientRefreshSession, error) {
-	ent, err := s.repo.FindBySessionID(sessionID)
+// Returns the user's session. Sessions of other users are not found
+func (s *Sessions) Find(ctx context.Context, userID string, sessionID int) (model.ClientRefreshSession, error) {
+	ent, err := s.findOwned(ctx, userID, sessionID)
+	if err != nil {
+		return model.ClientRefreshSession{}, err
+	}
+
+	return toClientSession(ent), nil
+}
+
+// Session ids are sequential, so the owner is checked to not let users
+// read or drop sessions of each other
+func (s *Sessions) findOwned(ctx context.Context, userID string, sessionID int) (entity.RefreshSession, error) {
+	ent, err := s.repo.FindBySessionID(ctx, sessionID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return model.ClientRefreshSession{}, ErrNotFound
+			return entity.RefreshSession{}, ErrNotFound
 		}
 
-		return model.ClientRefreshSession{}, err
+		return entity.RefreshSession{}, err
 	}
 
-	session := model.ClientRefreshSession{
-		SessionID: ent.SessionID,
-		Uagent:    ent.Uagent,
-		ExpiresAt: stamp.Parse(ent.ExpiresAt),
-		CreatedAt: stamp.Parse(ent.CreatedAt),
+	if ent.UserID != userID {
+		return entity.RefreshSession{}, ErrNotFound
 	}
 
-	return session, nil
+	return ent, nil
 }
 
 // Find all sessions by "user_id" or find all by "user_id" and "device" if passed
 //
 // TODO: upgrade to pass { userID: required, uagent, fprint: optional }
-func (s *Sessions) FindAll(userID, uagent, fprint string) ([]model.ClientRefreshSession, error) {
+func (s *Sessions) FindAll(ctx context.Context, userID, uagent, fprint string) ([]model.ClientRefreshSession, error) {
 	var ents []entity.RefreshSession
 	var err error
 
 	if uagent != "" && fprint != "" {
-		ents, err = s.repo.FindAllByDevice(userID, uagent, fprint)
+		ents, err = s.repo.FindAllByDevice(ctx, userID, uagent, fprint)
 	} else {
-		ents, err = s.repo.FindAllByUserID(userID)
+		ents, err = s.repo.FindAllByUserID(ctx, userID)
 	}
 
 	if err != nil {
@@ -76,36 +97,85 @@ func (s *Sessions) FindAll(userID, uagent, fprint string) ([]model.ClientRefresh
 
 	sessions := make([]model.ClientRefreshSession, 0)
 	for _, e := range ents {
-		sessions = append(sessions, model.ClientRefreshSession{
-			SessionID: e.SessionID,
-			Uagent:    e.Uagent,
-			ExpiresAt: stamp.Parse(e.ExpiresAt),
-			CreatedAt: stamp.Parse(e.CreatedAt),
-		})
+		sessions = append(sessions, toClientSession(e))
 	}
 
 	return sessions, nil
 }
 
+// Finds page of user's sessions matching the filter
+func (s *Sessions) FindPage(ctx context.Context, f model.SessionsFilter) (model.SessionsPage, error) {
+	if f.Device != "" {
+		if err := s.storeLegacyDevices(ctx, f.UserID); err != nil {
+			return model.SessionsPage{}, err
+		}
+	}
+
+	ents, err := s.repo.FindPage(ctx, entity.SessionsFilter{
+		UserID:           f.UserID,
+		CurrentSessionID: f.CurrentSessionID,
+		Status:           f.Status,
+		Device:           f.Device,
+		CreatedAfter:     f.CreatedAfter,
+		Cursor:           f.Cursor,
+		Desc:             f.Order != model.SortAsc,
+		Limit:            f.Limit,
+	})
+	if err != nil {
+		return model.SessionsPage{}, err
+	}
+
+	page := model.SessionsPage{
+		Sessions: make([]model.ClientRefreshSession, 0, len(ents)),
+	}
+
+	for _, e := range ents {
+		page.Sessions = append(page.Sessions, toClientSession(e))
+	}
+
+	if len(ents) == f.Limit && f.Limit > 0 {
+		page.NextCursor = ents[len(ents)-1].SessionID
+	}
+
+	return page, nil
+}
+
 // Drops all old user sessions associated with the device and creates new one
-func (s *Sessions) Create(userID, uagent, fprint string) (model.TokenPair, error) {
+func (s *Sessions) Create(ctx context.Context, userID string, device model.DeviceInfo) (model.TokenPair, error) {
 	// TODO: figure out how to handle error
-	s.DropAll(userID, uagent, fprint)
+	s.dropDevice(ctx, userID, device.Uagent, device.Fprint, "replaced")
+
+	pair, err := s.generateTokens(ctx, userID, device.Uagent, device.Fprint)
+	if err != nil {
+		return model.TokenPair{}, err
+	}
+
+	s.audit.Record(ctx, userEvent(model.AuditSessionCreated, userID, device, nil))
 
-	return s.generateTokens(userID, uagent, fprint)
+	return pair, nil
 }
 
-func (s *Sessions) Refresh(token, userID, uagent, fprint string) (model.TokenPair, error) {
-	session, err := s.repo.FindByRefreshToken(token)
+func (s *Sessions) Refresh(ctx context.Context, token, userID string, device model.DeviceInfo) (model.TokenPair, error) {
+	session, err := s.repo.FindByRefreshToken(ctx, token)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			s.audit.Record(ctx, userEvent(model.AuditRefreshTokenRejected, userID, device, map[string]string{
+				"reason": "unknown",
+			}))
+		}
+
 		return model.TokenPair{}, err
 	}
 
-	if err = s.repo.Drop(session.SessionID); err != nil {
+	if err = s.repo.Drop(ctx, session.SessionID); err != nil {
 		// TODO: add sentry logs
 		log.Errorf("cannot drop session while refreshing token: %s", err)
 	}
 
+	s.events.Publish(event.New(event.SessionInvalidated, session.UserID, map[string]string{
+		"session_id": fmt.Sprint(session.SessionID),
+	}))
+
 	if token != session.RefreshToken {
 		return model.TokenPair{}, ErrUnknownToken
 	}
@@ -114,70 +184,159 @@ func (s *Sessions) Refresh(token, userID, uagent, fprint string) (model.TokenPai
 	now := time.Now().UTC()
 
 	if now.After(expiresAt) {
+		s.audit.Record(ctx, userEvent(model.AuditRefreshTokenRejected, userID, device, map[string]string{
+			"reason":     "expired",
+			"session_id": fmt.Sprint(session.SessionID),
+		}))
+
 		return model.TokenPair{}, ErrExpiredToken
 	}
 
-	return s.generateTokens(userID, uagent, fprint)
+	pair, err := s.generateTokens(ctx, userID, device.Uagent, device.Fprint)
+	if err != nil {
+		return model.TokenPair{}, err
+	}
+
+	s.audit.Record(ctx, userEvent(model.AuditSessionRefreshed, userID, device, map[string]string{
+		"previous_session_id": fmt.Sprint(session.SessionID),
+	}))
+
+	return pair, nil
 }
 
-func (s *Sessions) generateTokens(userID, uagent, fprint string) (model.TokenPair, error) {
-	rTokenExriresAt := time.Now().Add(s.cfg.RefreshTokenTTL).UTC()
+// Creates session and sets its access token atomically, so there are
+// no sessions without access token
+func (s *Sessions) generateTokens(ctx context.Context, userID, uagent, fprint string) (model.TokenPair, error) {
+	rTokenExpiresAt := time.Now().Add(s.cfg.RefreshTokenTTL).UTC()
+
+	var pair model.TokenPair
+
+	err := s.uow.Do(ctx, func(r repo.Repositories) error {
+		sessionID, refreshToken, err := r.Sessions.Create(
+			ctx, userID, uagent, fprint, parseDevice(uagent), rTokenExpiresAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		accessToken, err := s.tm.Generate(sessionID, userID)
+		if err != nil {
+			return err
+		}
+
+		if err := r.Sessions.SetAccessToken(ctx, sessionID, accessToken); err != nil {
+			return err
+		}
+
+		pair = model.TokenPair{
+			AccessToken:  accessToken,
+			RefreshToken: refreshToken,
+		}
+
+		return nil
+	})
 
-	sessionID, refreshToken, err := s.repo.Create(userID, uagent, fprint, rTokenExriresAt)
 	if err != nil {
 		return model.TokenPair{}, err
 	}
 
-	accessToken, err := s.tm.Generate(sessionID, userID)
+	return pair, nil
+}
+
+func (s *Sessions) Validate(ctx context.Context, sessionID int, accessToken string, device model.DeviceInfo) error {
+	old, err := s.findForValidation(ctx, sessionID)
 	if err != nil {
-		return model.TokenPair{}, err
+		return err
 	}
 
-	err = s.repo.SetAccessToken(sessionID, accessToken)
-	if err != nil {
-		return model.TokenPair{}, err
+	meta := map[string]string{
+		"session_id": fmt.Sprint(old.SessionID),
 	}
 
-	pair := model.TokenPair{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
+	if accessToken != old.AccessToken {
+		s.repo.Drop(ctx, old.SessionID)
+		s.audit.Record(ctx, userEvent(model.AuditAccessTokenReused, old.UserID, device, meta))
+		s.revoked(old.UserID, old.SessionID, "access_token_reused")
+		return ErrUnknownToken
 	}
 
-	return pair, nil
+	if device.Uagent != old.Uagent || device.Fprint != old.Fprint {
+		s.repo.Drop(ctx, old.SessionID)
+		s.audit.Record(ctx, userEvent(model.AuditDeviceMismatch, old.UserID, device, meta))
+		s.revoked(old.UserID, old.SessionID, "device_mismatch")
+		return ErrUnknownDevice
+	}
+
+	return nil
 }
 
-func (s *Sessions) Validate(sessionID int, accessToken, uagent, fprint string) error {
-	old, err := s.repo.FindBySessionID(sessionID)
+// Finds session in the cache, as validation runs on every protected request,
+// and falls back to the repo. Cache is invalidated by the session events
+func (s *Sessions) findForValidation(ctx context.Context, sessionID int) (model.RefreshSession, error) {
+	if session, ok := s.cache.Get(sessionID); ok {
+		return session, nil
+	}
+
+	// Taken before the read, so the session dropped meanwhile is not cached
+	gen := s.cache.Generation()
+
+	ent, err := s.repo.FindBySessionID(ctx, sessionID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return ErrNotFound
+			return model.RefreshSession{}, ErrNotFound
 		}
 
-		return err
+		return model.RefreshSession{}, err
 	}
 
-	if accessToken != old.AccessToken {
-		s.repo.Drop(old.SessionID)
-		return ErrUnknownToken
+	session := model.RefreshSession{
+		SessionID:    ent.SessionID,
+		UserID:       ent.UserID,
+		Uagent:       ent.Uagent,
+		Fprint:       ent.Fprint,
+		RefreshToken: ent.RefreshToken,
+		AccessToken:  ent.AccessToken,
+		ExpiresAt:    stamp.Parse(ent.ExpiresAt),
 	}
 
-	if uagent != old.Uagent || fprint != old.Fprint {
-		s.repo.Drop(old.SessionID)
-		return ErrUnknownDevice
+	s.cache.Set(session, gen)
+
+	return session, nil
+}
+
+// Drops the user's session. Sessions of other users are not found
+func (s *Sessions) Drop(ctx context.Context, userID string, sessionID int, device model.DeviceInfo) error {
+	if _, err := s.findOwned(ctx, userID, sessionID); err != nil {
+		return err
+	}
+
+	if err := s.repo.Drop(ctx, sessionID); err != nil {
+		return err
 	}
 
+	s.audit.Record(ctx, userEvent(model.AuditSessionDropped, userID, device, map[string]string{
+		"session_id": fmt.Sprint(sessionID),
+	}))
+	s.revoked(userID, sessionID, "dropped")
+
 	return nil
 }
 
-// Drops all user sessions associated with the device
-func (s *Sessions) Drop(sessionID int) error {
-	return s.repo.Drop(sessionID)
+// Drops all user sessions on every device (equivalent to logout everywhere)
+func (s *Sessions) DropAll(ctx context.Context, userID string, device model.DeviceInfo) error {
+	if err := s.dropDevice(ctx, userID, "", "", "dropped_all"); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, userEvent(model.AuditSessionsDropped, userID, device, nil))
+
+	return nil
 }
 
-// Drops all user sessions associated with the device.
-// Both "uagent" and "fprint" can be ommited
-func (s *Sessions) DropAll(userID, uagent, fprint string) error {
-	deviceSessions, err := s.FindAll(userID, uagent, fprint)
+// Drops all user sessions associated with the device and notifies about
+// each of them with the "reason". Both "uagent" and "fprint" can be ommited
+func (s *Sessions) dropDevice(ctx context.Context, userID, uagent, fprint, reason string) error {
+	deviceSessions, err := s.FindAll(ctx, userID, uagent, fprint)
 	if err != nil {
 		return err
 	}
@@ -185,10 +344,12 @@ func (s *Sessions) DropAll(userID, uagent, fprint string) error {
 	if deviceSessions != nil && len(deviceSessions) > 0 {
 		// Try to delete sessions with this device
 		for _, session := range deviceSessions {
-			if err := s.repo.Drop(session.SessionID); err != nil {
+			if err := s.repo.Drop(ctx, session.SessionID); err != nil {
 				// TODO: Figure out what to do
 				continue
 			}
+
+			s.revoked(userID, session.SessionID, reason)
 		}
 	} else {
 		// TODO: push notification: New device login detected
@@ -196,3 +357,73 @@ func (s *Sessions) DropAll(userID, uagent, fprint string) error {
 
 	return nil
 }
+
+// Stores parsed devices of the user's sessions created before parsing
+// was introduced, so the device filter matches them too
+func (s *Sessions) storeLegacyDevices(ctx context.Context, userID string) error {
+	ents, err := s.repo.FindAllByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range ents {
+		if e.Device != (entity.SessionDevice{}) {
+			continue
+		}
+
+		if err := s.repo.SetDevice(ctx, e.SessionID, parseDevice(e.Uagent)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func toClientSession(e entity.RefreshSession) model.ClientRefreshSession {
+	// Sessions created before parsing was introduced have no parsed fields
+	if e.Device == (entity.SessionDevice{}) {
+		e.Device = parseDevice(e.Uagent)
+	}
+
+	return model.ClientRefreshSession{
+		SessionID: e.SessionID,
+		Uagent:    e.Uagent,
+		Device: model.SessionDevice{
+			Browser:        e.Device.Browser,
+			BrowserVersion: e.Device.BrowserVersion,
+			OS:             e.Device.OS,
+			OSVersion:      e.Device.OSVersion,
+			Type:           e.Device.Type,
+			Model:          e.Device.Model,
+			AppVersion:     e.Device.AppVersion,
+		},
+		IsCurrent: e.IsCurrent,
+		ExpiresAt: stamp.Parse(e.ExpiresAt),
+		CreatedAt: stamp.Parse(e.CreatedAt),
+	}
+}
+
+func parseDevice(uagent string) entity.SessionDevice {
+	ua := useragent.Parse(uagent)
+
+	return entity.SessionDevice{
+		Browser:        ua.Browser,
+		BrowserVersion: ua.BrowserVersion,
+		OS:             ua.OS,
+		OSVersion:      ua.OSVersion,
+		Type:           ua.DeviceType,
+		Model:          ua.DeviceModel,
+		AppVersion:     ua.AppVersion,
+	}
+}
+
+// Notifies user's clients about revoked session, "sessionID" of 0 means
+// all the sessions are revoked
+func (s *Sessions) revoked(userID string, sessionID int, reason string) {
+	data := map[string]string{"reason": reason}
+	if sessionID != 0 {
+		data["session_id"] = fmt.Sprint(sessionID)
+	}
+
+	s.events.Publish(event.New(event.SessionRevoked, userID, data))
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+	entity "wildproject/internal/app/data/entities"
+	repo "wildproject/internal/app/data/repositories"
+	event "wildproject/internal/app/domain/events"
+	manager "wildproject/internal/app/domain/managers"
+	model "wildproject/internal/app/domain/models"
+)
+
+const firefoxUagent = "Mozilla/5.0 (X11; Linux x86_64; rv:120.0) Gecko/20100101 Firefox/120.0"
+
+// Sessions created before devices were parsed have no device stored,
+// filtering by device stores it, so they are matched like the others
+func TestSessionsFindPageLegacyDevice(t *testing.T) {
+	ctx := context.Background()
+	cfg := &model.AuthConfig{AccessTokenTTL: time.Minute, RefreshTokenTTL: time.Hour}
+	repos := repo.NewMemoryRepositories()
+	bus := event.NewBus()
+
+	userID, err := repos.Users.Create(ctx, "user@example.com", "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sessionID, _, err := repos.Sessions.Create(
+		ctx, userID, firefoxUagent, "fprint", entity.SessionDevice{}, time.Now().Add(time.Hour),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewSessions(
+		cfg, repos.Sessions, manager.NewJwtManager([]byte("secret"), cfg.AccessTokenTTL),
+		NewAudit(repos.AuditEvents, []byte("secret")), bus, manager.NewSessionCache(cfg, bus),
+		repo.NewMemoryUnitOfWork(repos),
+	)
+
+	page, err := s.FindPage(ctx, model.SessionsFilter{UserID: userID, Device: "firefox"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(page.Sessions) != 1 || page.Sessions[0].SessionID != sessionID {
+		t.Fatalf("expected legacy session to match, got %+v", page.Sessions)
+	}
+
+	session, err := repos.Sessions.FindBySessionID(ctx, sessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if session.Device.Browser != "Firefox" || session.Device.OS != "Linux" {
+		t.Fatalf("expected parsed device to be stored, got %+v", session.Device)
+	}
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+	repo "wildproject/internal/app/data/repositories"
+	event "wildproject/internal/app/domain/events"
+	model "wildproject/internal/app/domain/models"
+
+	"github.com/gofiber/fiber/v2/log"
+)
+
+const (
+	signingKeyIDLength = 8
+	// HS256 key as long as the hash output
+	signingKeyLength = 32
+)
+
+// Rotates the keys the access tokens are signed with. Running instances
+// pick the new key up by the event
+type SigningKeys struct {
+	cfg    *model.AuthConfig
+	repo   repo.SigningKeysRepo
+	audit  AuditRecorder
+	events event.Publisher
+}
+
+func NewSigningKeys(
+	cfg *model.AuthConfig,
+	r repo.SigningKeysRepo,
+	ar AuditRecorder,
+	ep event.Publisher,
+) *SigningKeys {
+	return &SigningKeys{cfg, r, ar, ep}
+}
+
+// Creates the key the new tokens are signed with, on behalf of an operator.
+// The keys retired long enough for their tokens to expire are deleted.
+// Returns id of the new key
+func (k *SigningKeys) Rotate(ctx context.Context, device model.DeviceInfo) (string, error) {
+	id := make([]byte, signingKeyIDLength)
+	secret := make([]byte, signingKeyLength)
+
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	keyID := hex.EncodeToString(id)
+
+	if err := k.repo.Create(ctx, keyID, secret); err != nil {
+		return "", err
+	}
+
+	// Key is rotated anyway, retired keys are dropped by the next rotation
+	dropped, err := k.repo.DropSuperseded(ctx, time.Now().Add(-k.cfg.AccessTokenTTL))
+	if err != nil {
+		log.Errorf("cannot drop retired signing keys: %s", err)
+	}
+
+	k.events.Publish(event.New(event.SigningKeysRotated, "", map[string]string{
+		"key_id": keyID,
+	}))
+
+	k.audit.Record(ctx, OperatorEvent(model.AuditSigningKeysRotated, "", device, map[string]string{
+		"key_id":  keyID,
+		"dropped": fmt.Sprint(dropped),
+	}))
+
+	return keyID, nil
+}
@@ -1,26 +1,63 @@
 package service
 
-import model "wildproject/internal/app/domain/models"
+import (
+	"context"
+	"io"
+	"time"
+	model "wildproject/internal/app/domain/models"
+)
 
 type UsersService interface {
-	Find(userID, email string) (model.User, error)
-	FindDetailedByID(userID string) (model.UserDetailed, error)
-	IsRegistered(email string) (bool, error)
-	Create(email, passwordHash string) (string, error)
-	Authenticate(email, password string) (string, error)
-	ChangeName(userID, name string) (string, error)
-	ChangeSex(userID string, sexID int) (int, error)
-	ChangeEmail(userID, email string) (string, error)
-	ChangePassword(userID, password string) error
-	ChangeImageURL(userID, url string) error
+	Find(ctx context.Context, userID, email string) (model.User, error)
+	FindDetailedByID(ctx context.Context, userID string) (model.UserDetailed, error)
+	IsRegistered(ctx context.Context, email string) (bool, error)
+	Create(ctx context.Context, email, password string, device model.DeviceInfo) (string, error)
+	Provision(ctx context.Context, email, password string, device model.DeviceInfo) (string, error)
+	Authenticate(ctx context.Context, email, password string, device model.DeviceInfo) (string, error)
+	ChangeName(ctx context.Context, userID, name string) (string, error)
+	ChangeSex(ctx context.Context, userID string, sexID int) (int, error)
+	ChangeEmail(ctx context.Context, userID, email string, device model.DeviceInfo) (model.PendingEmailChange, error)
+	ConfirmEmail(ctx context.Context, token string, device model.DeviceInfo) (string, error)
+	CancelEmailChange(ctx context.Context, token string, device model.DeviceInfo) error
+	ChangePassword(ctx context.Context, userID string, sessionID int, password string, device model.DeviceInfo) error
+	ResetPassword(ctx context.Context, userID, password string, device model.DeviceInfo) error
+	ChangeImage(ctx context.Context, userID string, img io.Reader) ([]model.ImageVariant, error)
+	RenderDefaultImage(ctx context.Context, userID string, size int, format string) ([]byte, string, error)
+	Delete(ctx context.Context, userID, password string, device model.DeviceInfo) (time.Time, error)
 }
 
 type SessionsService interface {
-	Find(sessionID int) (model.ClientRefreshSession, error)
-	FindAll(userID, uagent, fprint string) ([]model.ClientRefreshSession, error)
-	Create(userID, uagent, fprint string) (model.TokenPair, error)
-	Refresh(token, userID, uagent, fprint string) (model.TokenPair, error)
-	Validate(sessionID int, accessToken, uagent, fprint string) error
-	DropAll(userID, uagent, fprint string) error
-	Drop(sessionID int) error
+	Find(ctx context.Context, userID string, sessionID int) (model.ClientRefreshSession, error)
+	FindAll(ctx context.Context, userID, uagent, fprint string) ([]model.ClientRefreshSession, error)
+	FindPage(ctx context.Context, f model.SessionsFilter) (model.SessionsPage, error)
+	Create(ctx context.Context, userID string, device model.DeviceInfo) (model.TokenPair, error)
+	Refresh(ctx context.Context, token, userID string, device model.DeviceInfo) (model.TokenPair, error)
+	Validate(ctx context.Context, sessionID int, accessToken string, device model.DeviceInfo) error
+	DropAll(ctx context.Context, userID string, device model.DeviceInfo) error
+	Drop(ctx context.Context, userID string, sessionID int, device model.DeviceInfo) error
+}
+
+type RolesService interface {
+	Grant(ctx context.Context, userID string, roles []string, device model.DeviceInfo) ([]string, error)
+	FindAll(ctx context.Context, userID string) ([]string, error)
+}
+
+type SigningKeysService interface {
+	Rotate(ctx context.Context, device model.DeviceInfo) (string, error)
+}
+
+type AuditRecorder interface {
+	Record(ctx context.Context, e model.AuditEvent)
+	Pseudonymize(value string) string
+}
+
+type AuditService interface {
+	AuditRecorder
+	FindAll(ctx context.Context, userID string, before int64, limit int) ([]model.AuditEvent, error)
+}
+
+type DataExportsService interface {
+	Request(ctx context.Context, userID, format string) (model.DataExport, error)
+	Find(ctx context.Context, userID, exportID string) (model.DataExport, error)
+	Open(ctx context.Context, token string) (io.ReadCloser, model.DataExport, error)
 }
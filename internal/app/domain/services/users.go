@@ -1,42 +1,72 @@
 package service
 
 import (
+	"context"
+	"crypto/rand"
 	"database/sql"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"io"
+	"time"
 	entity "wildproject/internal/app/data/entities"
 	repo "wildproject/internal/app/data/repositories"
+	event "wildproject/internal/app/domain/events"
+	manager "wildproject/internal/app/domain/managers"
 	model "wildproject/internal/app/domain/models"
+	"wildproject/internal/app/mail"
 	"wildproject/internal/stamp"
 
-	"golang.org/x/crypto/bcrypt"
-)
-
-const (
-	passwordCost = 12
+	"github.com/gofiber/fiber/v2/log"
 )
 
 var (
-	ErrIDAndEmailEmpty   = errors.New("expected user_id or email, but both are empty")
-	ErrPasswordsMismatch = errors.New("passwords mismatches")
+	ErrIDAndEmailEmpty    = errors.New("expected user_id or email, but both are empty")
+	ErrPasswordsMismatch  = errors.New("passwords mismatches")
+	ErrEmailChangeExpired = errors.New("email change is expired")
 )
 
 type Users struct {
-	repo repo.UsersRepo
+	cfg     *model.UsersConfig
+	pcfg    *model.PasswordConfig
+	repo    repo.UsersRepo
+	history repo.PasswordHistoryRepo
+	policy  manager.PasswordPolicy
+	hasher  manager.PasswordHasher
+	avatars manager.AvatarManager
+	changes repo.EmailChangesRepo
+	mailer  mail.Mailer
+	audit   AuditRecorder
+	events  event.Publisher
+	uow     repo.UnitOfWork
 }
 
-func NewUsers(r repo.UsersRepo) *Users {
-	return &Users{r}
+func NewUsers(
+	cfg *model.UsersConfig,
+	pcfg *model.PasswordConfig,
+	r repo.UsersRepo,
+	hr repo.PasswordHistoryRepo,
+	pp manager.PasswordPolicy,
+	ph manager.PasswordHasher,
+	am manager.AvatarManager,
+	cr repo.EmailChangesRepo,
+	m mail.Mailer,
+	ar AuditRecorder,
+	ep event.Publisher,
+	uow repo.UnitOfWork,
+) *Users {
+	return &Users{cfg, pcfg, r, hr, pp, ph, am, cr, m, ar, ep, uow}
 }
 
 // Find user either by passed id or by passed email
-func (u *Users) Find(userID, email string) (model.User, error) {
+func (u *Users) Find(ctx context.Context, userID, email string) (model.User, error) {
 	var ent entity.User
 	var err error
 
 	if userID != "" {
-		ent, err = u.repo.FindByID(userID)
+		ent, err = u.repo.FindByID(ctx, userID)
 	} else if email != "" {
-		ent, err = u.repo.FindByEmail(email)
+		ent, err = u.repo.FindByEmail(ctx, email)
 	} else {
 		return model.User{}, ErrIDAndEmailEmpty
 	}
@@ -56,11 +86,16 @@ func (u *Users) Find(userID, email string) (model.User, error) {
 		UpdatedAt: stamp.Parse(ent.UpdatetdAt),
 	}
 
+	if ent.DeleteAfter.Valid {
+		deleteAfter := stamp.Parse(ent.DeleteAfter.String)
+		user.DeleteAfter = &deleteAfter
+	}
+
 	return user, nil
 }
 
-func (u *Users) FindDetailedByID(userID string) (model.UserDetailed, error) {
-	ent, err := u.repo.FindDetailedByID(userID)
+func (u *Users) FindDetailedByID(ctx context.Context, userID string) (model.UserDetailed, error) {
+	ent, err := u.repo.FindDetailedByID(ctx, userID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			err = ErrNotFound
@@ -76,16 +111,25 @@ func (u *Users) FindDetailedByID(userID string) (model.UserDetailed, error) {
 			CreatedAt: stamp.Parse(ent.CreatedAt),
 			UpdatedAt: stamp.Parse(ent.UpdatetdAt),
 		},
-		Name:     ent.Name,
-		Sex:      ent.SexID,
-		ImageURL: ent.ImageURL,
+		Name:   ent.Name,
+		Sex:    ent.SexID,
+		Avatar: toImageVariants(ent.ImageVariants),
+	}
+
+	if len(user.Avatar) == 0 {
+		user.Avatar = u.avatars.DefaultVariants(user.ID)
+	}
+
+	if ent.DeleteAfter.Valid {
+		deleteAfter := stamp.Parse(ent.DeleteAfter.String)
+		user.DeleteAfter = &deleteAfter
 	}
 
 	return user, nil
 }
 
-func (u *Users) IsRegistered(email string) (bool, error) {
-	count, err := u.repo.CountByEmail(email)
+func (u *Users) IsRegistered(ctx context.Context, email string) (bool, error) {
+	count, err := u.repo.CountByEmail(ctx, email)
 	if err != nil {
 		return true, err
 	}
@@ -93,8 +137,32 @@ func (u *Users) IsRegistered(email string) (bool, error) {
 	return count > 0, nil
 }
 
-func (u *Users) Create(email, password string) (string, error) {
-	registered, err := u.IsRegistered(email)
+func (u *Users) Create(ctx context.Context, email, password string, device model.DeviceInfo) (string, error) {
+	userID, err := u.create(ctx, email, password)
+	if err != nil {
+		return "", err
+	}
+
+	u.audit.Record(ctx, userEvent(model.AuditSignup, userID, device, nil))
+
+	return userID, nil
+}
+
+// Creates user on behalf of an operator, e.g. with wildctl. Password
+// follows the same rules as on signup
+func (u *Users) Provision(ctx context.Context, email, password string, device model.DeviceInfo) (string, error) {
+	userID, err := u.create(ctx, email, password)
+	if err != nil {
+		return "", err
+	}
+
+	u.audit.Record(ctx, OperatorEvent(model.AuditUserCreated, userID, device, nil))
+
+	return userID, nil
+}
+
+func (u *Users) create(ctx context.Context, email, password string) (string, error) {
+	registered, err := u.IsRegistered(ctx, email)
 	if err != nil {
 		return "", err
 	}
@@ -103,36 +171,97 @@ func (u *Users) Create(email, password string) (string, error) {
 		return "", ErrAlreadyExists
 	}
 
-	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), passwordCost)
+	// Name is not asked at signup, it's set later by ChangeName, so only
+	// the email is denied here. Password isn't rechecked on name change,
+	// as only its hash is known then
+	if err := u.policy.Check(password, email, ""); err != nil {
+		return "", err
+	}
+
+	passwordHash, err := u.hasher.Hash(password)
 	if err != nil {
 		return "", err
 	}
 
-	return u.repo.Create(email, string(passwordHash))
+	return u.repo.Create(ctx, email, passwordHash)
 }
 
-func (u *Users) Authenticate(email, password string) (string, error) {
-	user, err := u.repo.FindByEmail(email)
+func (u *Users) Authenticate(ctx context.Context, email, password string, device model.DeviceInfo) (string, error) {
+	user, err := u.repo.FindByEmail(ctx, email)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
+			// Unknown account has no id, so the email is the only trace.
+			// It's typed by anyone and kept forever, so only its hash is
+			u.audit.Record(ctx, userEvent(model.AuditLoginFailed, "", device, map[string]string{
+				"email_hash": u.audit.Pseudonymize(email),
+				"reason":     "unknown_email",
+			}))
+
 			err = ErrNotFound
 		}
 
 		return "", err
 	}
 
-	bHash := []byte(user.PasswordHash)
-	bPass := []byte(password)
+	// Account past the grace period only waits for the deletion job
+	if user.DeleteAfter.Valid && !time.Now().Before(stamp.Parse(user.DeleteAfter.String).Time) {
+		u.audit.Record(ctx, userEvent(model.AuditLoginFailed, user.ID, device, map[string]string{
+			"reason": "deletion_due",
+		}))
+
+		return "", ErrNotFound
+	}
+
+	ok, err := u.hasher.Verify(password, user.PasswordHash)
+	if err != nil {
+		return "", err
+	}
+
+	if !ok {
+		u.audit.Record(ctx, userEvent(model.AuditLoginFailed, user.ID, device, map[string]string{
+			"reason": "wrong_password",
+		}))
 
-	if err := bcrypt.CompareHashAndPassword(bHash, bPass); err != nil {
 		return "", ErrPasswordsMismatch
 	}
 
+	// Login during the grace period means user changed their mind. The
+	// period may end meanwhile, then the account is already being deleted
+	if user.DeleteAfter.Valid {
+		if err := u.repo.CancelDeletion(ctx, user.ID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				u.audit.Record(ctx, userEvent(model.AuditLoginFailed, user.ID, device, map[string]string{
+					"reason": "deletion_due",
+				}))
+
+				err = ErrNotFound
+			}
+
+			return "", err
+		}
+
+		u.audit.Record(ctx, userEvent(model.AuditDeletionCanceled, user.ID, device, nil))
+	}
+
+	u.audit.Record(ctx, userEvent(model.AuditLoginSucceeded, user.ID, device, nil))
+
+	// Password is known only at this point, so upgrade outdated hash now.
+	// Login is not failed if upgrade fails, it will be retried next time
+	if u.hasher.NeedsRehash(user.PasswordHash) {
+		if err := u.rehash(ctx, user.ID, password); err != nil {
+			log.Errorf("cannot upgrade password hash: %s", err)
+		} else {
+			u.audit.Record(ctx, userEvent(model.AuditPasswordRehashed, user.ID, device, map[string]string{
+				"algorithm": u.hasher.Algorithm(),
+			}))
+		}
+	}
+
 	return user.ID, nil
 }
 
-func (u *Users) ChangeName(userID, name string) (string, error) {
-	err := u.repo.ChangeName(userID, name)
+func (u *Users) ChangeName(ctx context.Context, userID, name string) (string, error) {
+	err := u.repo.ChangeName(ctx, userID, name)
 	if err != nil {
 		return "", err
 	}
@@ -140,8 +269,8 @@ func (u *Users) ChangeName(userID, name string) (string, error) {
 	return name, err
 }
 
-func (u *Users) ChangeSex(userID string, sexID int) (int, error) {
-	err := u.repo.ChangeSex(userID, sexID)
+func (u *Users) ChangeSex(ctx context.Context, userID string, sexID int) (int, error) {
+	err := u.repo.ChangeSex(ctx, userID, sexID)
 	if err != nil {
 		return -1, err
 	}
@@ -149,8 +278,115 @@ func (u *Users) ChangeSex(userID string, sexID int) (int, error) {
 	return sexID, err
 }
 
-func (u *Users) ChangeEmail(userID, email string) (string, error) {
-	registered, err := u.IsRegistered(email)
+// Requests email change, which is applied only after confirmation by the link
+// sent to the new address. The old address is notified and can cancel it, so
+// a hijacked session can't silently move the account to another mailbox.
+//
+// Previous pending changes are discarded
+func (u *Users) ChangeEmail(
+	ctx context.Context,
+	userID, email string,
+	device model.DeviceInfo,
+) (model.PendingEmailChange, error) {
+	user, err := u.repo.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrNotFound
+		}
+
+		return model.PendingEmailChange{}, err
+	}
+
+	registered, err := u.IsRegistered(ctx, email)
+	if err != nil {
+		return model.PendingEmailChange{}, err
+	}
+
+	if registered {
+		return model.PendingEmailChange{}, ErrAlreadyExists
+	}
+
+	confirmToken, err := randomToken()
+	if err != nil {
+		return model.PendingEmailChange{}, err
+	}
+
+	cancelToken, err := randomToken()
+	if err != nil {
+		return model.PendingEmailChange{}, err
+	}
+
+	expiresAt := time.Now().Add(u.cfg.EmailChangeTTL).UTC()
+
+	// Links are mailed only once the change replacing the previous ones
+	// is committed
+	err = u.uow.Do(ctx, func(r repo.Repositories) error {
+		if err := r.EmailChanges.DeleteAllByUserID(ctx, userID); err != nil {
+			return err
+		}
+
+		_, err := r.EmailChanges.Create(ctx, userID, email, confirmToken, cancelToken, expiresAt)
+		return err
+	})
+	if err != nil {
+		return model.PendingEmailChange{}, err
+	}
+
+	err = u.mailer.Send(mail.Message{
+		To:      email,
+		Subject: "Confirm your new email",
+		Body: fmt.Sprintf(
+			"Follow the link to make this address your account email:\n\n%s\n\n"+
+				"The link expires at %s. If you didn't request it, ignore this email.",
+			tokenURL(u.cfg.EmailConfirmURL, confirmToken),
+			expiresAt.Format(time.RFC1123),
+		),
+	})
+	if err != nil {
+		return model.PendingEmailChange{}, err
+	}
+
+	err = u.mailer.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Your account email is being changed",
+		Body: fmt.Sprintf(
+			"Change of your account email to %s was requested.\n\n"+
+				"If it wasn't you, cancel it by the link and change your password:\n\n%s",
+			email,
+			tokenURL(u.cfg.EmailCancelURL, cancelToken),
+		),
+	})
+	if err != nil {
+		return model.PendingEmailChange{}, err
+	}
+
+	u.audit.Record(ctx, userEvent(model.AuditEmailChangeRequested, userID, device, nil))
+
+	return model.PendingEmailChange{
+		Email:     email,
+		ExpiresAt: stamp.Stamp{Time: expiresAt},
+	}, nil
+}
+
+// Applies email change by the token sent to the new address.
+//
+// Returns the new email
+func (u *Users) ConfirmEmail(ctx context.Context, token string, device model.DeviceInfo) (string, error) {
+	change, err := u.changes.FindByConfirmToken(ctx, token)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrNotFound
+		}
+
+		return "", err
+	}
+
+	if time.Now().After(stamp.Parse(change.ExpiresAt).Time) {
+		return "", ErrEmailChangeExpired
+	}
+
+	// The address could be taken since the change was requested
+	registered, err := u.IsRegistered(ctx, change.Email)
 	if err != nil {
 		return "", err
 	}
@@ -159,23 +395,358 @@ func (u *Users) ChangeEmail(userID, email string) (string, error) {
 		return "", ErrAlreadyExists
 	}
 
-	err = u.repo.ChangeEmail(userID, email)
+	// Applied change is deleted with the email, so it can't be confirmed
+	// again
+	err = u.uow.Do(ctx, func(r repo.Repositories) error {
+		if err := r.Users.ChangeEmail(ctx, change.UserID, change.Email); err != nil {
+			return err
+		}
+
+		return r.EmailChanges.DeleteAllByUserID(ctx, change.UserID)
+	})
 	if err != nil {
 		return "", err
 	}
 
-	return email, err
+	u.audit.Record(ctx, userEvent(model.AuditEmailChanged, change.UserID, device, nil))
+	u.events.Publish(event.New(event.EmailChanged, change.UserID, nil))
+
+	return change.Email, nil
 }
 
-func (u *Users) ChangePassword(userID, password string) error {
-	phash, err := bcrypt.GenerateFromPassword([]byte(password), passwordCost)
+// Discards pending email change by the token sent to the old address
+func (u *Users) CancelEmailChange(ctx context.Context, token string, device model.DeviceInfo) error {
+	change, err := u.changes.FindByCancelToken(ctx, token)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrNotFound
+		}
+
+		return err
+	}
+
+	if err := u.changes.Delete(ctx, change.ChangeID); err != nil {
+		return err
+	}
+
+	u.audit.Record(ctx, userEvent(model.AuditEmailChangeCanceled, change.UserID, device, nil))
+
+	return nil
+}
+
+func (u *Users) ChangePassword(
+	ctx context.Context,
+	userID string,
+	sessionID int,
+	password string,
+	device model.DeviceInfo,
+) error {
+	// Only the session the password is changed with is kept, the others
+	// (even of the same device) may be the reason of the password change
+	keep := func(s entity.RefreshSession) bool {
+		return s.SessionID == sessionID
+	}
+
+	if err := u.setPassword(ctx, userID, password, keep, "password_changed"); err != nil {
 		return err
 	}
 
-	return u.repo.ChangePasswordHash(userID, string(phash))
+	u.audit.Record(ctx, userEvent(model.AuditPasswordChanged, userID, device, nil))
+
+	return nil
 }
 
-func (u *Users) ChangeImageURL(userID, url string) error {
-	return u.repo.ChangeImageURL(userID, url)
+// Sets password on behalf of the user, e.g. by an operator. It's checked
+// the same way as the one changed by the user, and all the sessions are
+// dropped, so the user has to log in again everywhere
+func (u *Users) ResetPassword(ctx context.Context, userID, password string, device model.DeviceInfo) error {
+	if err := u.setPassword(ctx, userID, password, nil, "password_reset"); err != nil {
+		return err
+	}
+
+	u.audit.Record(ctx, OperatorEvent(model.AuditPasswordReset, userID, device, nil))
+
+	return nil
+}
+
+// Checks and saves new password, remembering the replaced one, and drops
+// the user's sessions except the kept ones, all of them if "keep" is nil.
+// "reason" is sent along with the revoked sessions
+func (u *Users) setPassword(
+	ctx context.Context,
+	userID, password string,
+	keep func(entity.RefreshSession) bool,
+	reason string,
+) error {
+	user, err := u.repo.FindDetailedByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrNotFound
+		}
+
+		return err
+	}
+
+	if err := u.policy.Check(password, user.Email, user.Name); err != nil {
+		return err
+	}
+
+	if err := u.checkReuse(ctx, userID, user.PasswordHash, password); err != nil {
+		return err
+	}
+
+	phash, err := u.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	var dropped []int
+
+	err = u.uow.Do(ctx, func(r repo.Repositories) error {
+		if err := r.Users.ChangePasswordHash(ctx, userID, phash); err != nil {
+			return err
+		}
+
+		if err := u.remember(ctx, r.PasswordHistory, userID, user.PasswordHash); err != nil {
+			return err
+		}
+
+		dropped, err = dropSessions(ctx, r.Sessions, userID, keep)
+		return err
+	})
+
+	if err != nil {
+		return err
+	}
+
+	u.events.Publish(event.New(event.PasswordChanged, userID, nil))
+	u.revoked(userID, dropped, reason)
+
+	return nil
+}
+
+// Drops the user's sessions except the kept ones.
+//
+// Returns ids of the dropped sessions
+func dropSessions(
+	ctx context.Context,
+	sr repo.SessionsRepo,
+	userID string,
+	keep func(entity.RefreshSession) bool,
+) ([]int, error) {
+	sessions, err := sr.FindAllByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var dropped []int
+
+	for _, s := range sessions {
+		if keep != nil && keep(s) {
+			continue
+		}
+
+		if err := sr.Drop(ctx, s.SessionID); err != nil {
+			return nil, err
+		}
+
+		dropped = append(dropped, s.SessionID)
+	}
+
+	return dropped, nil
+}
+
+// Notifies about each of the dropped sessions with the "reason"
+func (u *Users) revoked(userID string, sessionIDs []int, reason string) {
+	for _, sessionID := range sessionIDs {
+		u.events.Publish(event.New(event.SessionRevoked, userID, map[string]string{
+			"session_id": fmt.Sprint(sessionID),
+			"reason":     reason,
+		}))
+	}
+}
+
+// Checks that password is not one of the user's last N passwords including
+// the current one, where N is configured history size
+func (u *Users) checkReuse(ctx context.Context, userID, currentHash, password string) error {
+	if u.pcfg.HistorySize <= 0 {
+		return nil
+	}
+
+	hashes := []string{currentHash}
+
+	if u.pcfg.HistorySize > 1 {
+		history, err := u.history.FindLatest(ctx, userID, u.pcfg.HistorySize-1)
+		if err != nil {
+			return err
+		}
+
+		for _, h := range history {
+			hashes = append(hashes, h.PasswordHash)
+		}
+	}
+
+	for _, hash := range hashes {
+		// Hashes of unknown or broken format can't match, so skip them
+		reused, err := u.hasher.Verify(password, hash)
+		if err != nil {
+			continue
+		}
+
+		if reused {
+			return &manager.PasswordPolicyError{
+				Violations: []string{manager.ViolationReused},
+			}
+		}
+	}
+
+	return nil
+}
+
+// Saves replaced password hash to the history and prunes outdated entries
+func (u *Users) remember(ctx context.Context, hr repo.PasswordHistoryRepo, userID, oldHash string) error {
+	if u.pcfg.HistorySize <= 1 {
+		return nil
+	}
+
+	if err := hr.Create(ctx, userID, oldHash); err != nil {
+		return err
+	}
+
+	return hr.Prune(ctx, userID, u.pcfg.HistorySize-1)
+}
+
+func (u *Users) rehash(ctx context.Context, userID, password string) error {
+	phash, err := u.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	return u.repo.ChangePasswordHash(ctx, userID, phash)
+}
+
+// Schedules account deletion after the grace period, during which
+// the deletion is canceled by login, and drops all the sessions.
+//
+// Returns the time the account will be deleted at
+func (u *Users) Delete(ctx context.Context, userID, password string, device model.DeviceInfo) (time.Time, error) {
+	user, err := u.repo.FindByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrNotFound
+		}
+
+		return time.Time{}, err
+	}
+
+	ok, err := u.hasher.Verify(password, user.PasswordHash)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if !ok {
+		return time.Time{}, ErrPasswordsMismatch
+	}
+
+	deleteAfter := time.Now().Add(u.cfg.DeletionGracePeriod).UTC()
+
+	var dropped []int
+
+	// All the sessions are dropped, so the account is left only by login,
+	// which cancels the deletion
+	err = u.uow.Do(ctx, func(r repo.Repositories) error {
+		if err := r.Users.ScheduleDeletion(ctx, userID, deleteAfter); err != nil {
+			return err
+		}
+
+		dropped, err = dropSessions(ctx, r.Sessions, userID, nil)
+		return err
+	})
+
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	u.audit.Record(ctx, userEvent(model.AuditDeletionRequested, userID, device, map[string]string{
+		"delete_after": deleteAfter.Format(time.RFC3339),
+	}))
+	u.revoked(userID, dropped, "deletion_requested")
+
+	return deleteAfter, nil
+}
+
+// Processes and uploads new user's avatar and deletes the previous one.
+//
+// Returns all the uploaded avatar variants
+func (u *Users) ChangeImage(ctx context.Context, userID string, img io.Reader) ([]model.ImageVariant, error) {
+	user, err := u.repo.FindDetailedByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrNotFound
+		}
+
+		return nil, err
+	}
+
+	variants, err := u.avatars.Upload(userID, img)
+	if err != nil {
+		return nil, err
+	}
+
+	ents := make([]entity.ImageVariant, 0, len(variants))
+	for _, v := range variants {
+		ents = append(ents, entity.ImageVariant{Size: v.Size, URL: v.URL})
+	}
+
+	if err := u.repo.ChangeImageVariants(ctx, userID, ents); err != nil {
+		u.avatars.Delete(variants)
+		return nil, err
+	}
+
+	// Avatar is already changed, so leftovers are only logged
+	if err := u.avatars.Delete(toImageVariants(user.ImageVariants)); err != nil {
+		log.Errorf("cannot delete previous avatar: %s", err)
+	}
+
+	return variants, nil
+}
+
+// Renders generated avatar for the user, see manager.AvatarManager.
+// User is looked up only if the avatar shows initials, otherwise the
+// endpoint doesn't reveal accounts.
+//
+// Returns encoded image and its content type
+func (u *Users) RenderDefaultImage(ctx context.Context, userID string, size int, format string) ([]byte, string, error) {
+	if !u.avatars.RendersInitials() {
+		return u.avatars.RenderDefault(userID, "", size, format)
+	}
+
+	user, err := u.repo.FindDetailedByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			err = ErrNotFound
+		}
+
+		return nil, "", err
+	}
+
+	return u.avatars.RenderDefault(user.ID, user.Name, size, format)
+}
+
+func toImageVariants(ents []entity.ImageVariant) []model.ImageVariant {
+	variants := make([]model.ImageVariant, 0, len(ents))
+	for _, e := range ents {
+		variants = append(variants, model.ImageVariant{Size: e.Size, URL: e.URL})
+	}
+
+	return variants
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
 }
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	repo "wildproject/internal/app/data/repositories"
+	event "wildproject/internal/app/domain/events"
+	model "wildproject/internal/app/domain/models"
+	"wildproject/internal/app/mail"
+)
+
+// Keeps the sent messages, links are their bodies
+type recordedMailer struct {
+	messages []mail.Message
+}
+
+func (m *recordedMailer) Send(msg mail.Message) error {
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+func (m *recordedMailer) lastTo(to string) string {
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].To == to {
+			return m.messages[i].Body
+		}
+	}
+
+	return ""
+}
+
+func TestUsersChangeEmail(t *testing.T) {
+	ctx := context.Background()
+	repos := repo.NewMemoryRepositories()
+	mailer := &recordedMailer{}
+	device := model.DeviceInfo{Uagent: "uagent", Fprint: "fprint"}
+
+	cfg := &model.UsersConfig{
+		EmailChangeTTL:  time.Hour,
+		EmailConfirmURL: confirmURL + urlToken,
+		EmailCancelURL:  "https://example.com/cancel/" + urlToken,
+	}
+
+	// Password and avatar managers are not used by the email change
+	u := NewUsers(
+		cfg, &model.PasswordConfig{}, repos.Users, repos.PasswordHistory, nil, nil, nil,
+		repos.EmailChanges, mailer, NewAudit(repos.AuditEvents, []byte("secret")), event.NewBus(),
+		repo.NewMemoryUnitOfWork(repos),
+	)
+
+	userID, err := repos.Users.Create(ctx, "old@example.com", "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := u.ChangeEmail(ctx, userID, "first@example.com", device); err != nil {
+		t.Fatal(err)
+	}
+
+	replaced := mailer.lastTo("first@example.com")
+
+	if _, err := u.ChangeEmail(ctx, userID, "second@example.com", device); err != nil {
+		t.Fatal(err)
+	}
+
+	// Pending change is replaced by the next one
+	if _, err := u.ConfirmEmail(ctx, confirmToken(t, replaced), device); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for replaced change, got %v", err)
+	}
+
+	token := confirmToken(t, mailer.lastTo("second@example.com"))
+
+	email, err := u.ConfirmEmail(ctx, token, device)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if email != "second@example.com" {
+		t.Fatalf("expected second@example.com, got %s", email)
+	}
+
+	// Applied change is deleted with the email change
+	if _, err := u.ConfirmEmail(ctx, token, device); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for applied change, got %v", err)
+	}
+}
+
+const confirmURL = "https://example.com/confirm/"
+
+func confirmToken(t *testing.T, body string) string {
+	t.Helper()
+
+	for _, field := range strings.Fields(body) {
+		if token, ok := strings.CutPrefix(field, confirmURL); ok {
+			return token
+		}
+	}
+
+	t.Fatalf("expected confirmation link in %q", body)
+
+	return ""
+}
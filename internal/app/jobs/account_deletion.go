@@ -0,0 +1,191 @@
+package job
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	repo "wildproject/internal/app/data/repositories"
+	"wildproject/internal/app/data/storage"
+	event "wildproject/internal/app/domain/events"
+	manager "wildproject/internal/app/domain/managers"
+	model "wildproject/internal/app/domain/models"
+
+	"github.com/gofiber/fiber/v2/log"
+)
+
+const (
+	DeletionModeAnonymize = "anonymize"
+	DeletionModeDelete    = "delete"
+)
+
+// Max number of accounts finalized per run
+const deletionBatchSize = 100
+
+// Finalizes deletion of accounts whose grace period is over: either erases
+// personal data keeping the user row or deletes it with all related data
+type AccountDeletion struct {
+	cfg      *model.UsersConfig
+	users    repo.UsersRepo
+	uow      repo.UnitOfWork
+	avatars  manager.AvatarManager
+	archives storage.Objects
+	events   event.Publisher
+	// Objects of the finalized accounts, which failed to be deleted.
+	// They are retried every run, until the process exits
+	leftovers []accountObjects
+}
+
+// Stored objects of the account, deleted once the account is finalized
+type accountObjects struct {
+	userID   string
+	avatars  []model.ImageVariant
+	archives []string
+}
+
+func NewAccountDeletion(
+	cfg *model.UsersConfig,
+	ur repo.UsersRepo,
+	uow repo.UnitOfWork,
+	am manager.AvatarManager,
+	as storage.Objects,
+	ep event.Publisher,
+) *AccountDeletion {
+	return &AccountDeletion{cfg: cfg, users: ur, uow: uow, avatars: am, archives: as, events: ep}
+}
+
+// Blocks processing due deletions periodically until context is canceled
+func (j *AccountDeletion) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.cfg.DeletionInterval)
+	defer ticker.Stop()
+
+	for {
+		j.process(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (j *AccountDeletion) process(ctx context.Context) {
+	j.retryLeftovers()
+
+	ids, err := j.users.FindDueDeletion(ctx, deletionBatchSize)
+	if err != nil {
+		log.Errorf("cannot find accounts due deletion: %s", err)
+		return
+	}
+
+	for _, userID := range ids {
+		objects, err := j.finalize(ctx, userID)
+
+		// Deletion was canceled since the account was found
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+
+		if err != nil {
+			// Account stays due deletion, so it will be retried next run
+			log.Errorf("cannot delete account %s: %s", userID, err)
+			continue
+		}
+
+		j.events.Publish(event.New(event.UserDeleted, userID, map[string]string{
+			"mode": j.cfg.DeletionMode,
+		}))
+
+		if err := j.deleteObjects(objects); err != nil {
+			log.Errorf("cannot delete objects of account %s, retrying next run: %s", userID, err)
+			j.leftovers = append(j.leftovers, objects)
+		}
+	}
+}
+
+func (j *AccountDeletion) retryLeftovers() {
+	leftovers := j.leftovers
+	j.leftovers = nil
+
+	for _, objects := range leftovers {
+		if err := j.deleteObjects(objects); err != nil {
+			log.Errorf("cannot delete objects of account %s: %s", objects.userID, err)
+			j.leftovers = append(j.leftovers, objects)
+		}
+	}
+}
+
+// Deleting objects is idempotent, so all of them are retried on failure
+func (j *AccountDeletion) deleteObjects(objects accountObjects) error {
+	errs := []error{j.avatars.Delete(objects.avatars)}
+
+	for _, key := range objects.archives {
+		errs = append(errs, j.archives.Delete(key))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Erases the account in a single unit of work. It's read again there and
+// stays locked, so a login can't cancel the deletion halfway. Stored
+// objects are left to the caller to delete after the commit, so they
+// survive if the unit of work is rolled back.
+//
+// Returns the account's objects or sql.ErrNoRows if the account is no
+// longer due deletion
+func (j *AccountDeletion) finalize(ctx context.Context, userID string) (accountObjects, error) {
+	objects := accountObjects{userID: userID}
+
+	err := j.uow.Do(ctx, func(r repo.Repositories) error {
+		user, err := r.Users.FindDetailedDueDeletion(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		for _, v := range user.ImageVariants {
+			objects.avatars = append(objects.avatars, model.ImageVariant{Size: v.Size, URL: v.URL})
+		}
+
+		// Export archives are full copies of personal data
+		exports, err := r.DataExports.FindReadyByUserID(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		for _, export := range exports {
+			objects.archives = append(objects.archives, export.ObjectKey)
+
+			if err := r.DataExports.Expire(ctx, export.ExportID); err != nil {
+				return err
+			}
+		}
+
+		// Audit events outlive the account, but not its ip and user agent
+		if err := r.AuditEvents.AnonymizeByUserID(ctx, userID); err != nil {
+			return err
+		}
+
+		// Sessions are not cascaded by the user deletion, when they are
+		// stored outside of the database
+		if err := r.Sessions.DropAll(ctx, userID); err != nil {
+			return err
+		}
+
+		switch j.cfg.DeletionMode {
+		case DeletionModeDelete:
+			return r.Users.Delete(ctx, userID)
+		case DeletionModeAnonymize:
+			if err := r.PasswordHistory.Prune(ctx, userID, 0); err != nil {
+				return err
+			}
+
+			return r.Users.Anonymize(ctx, userID)
+		}
+
+		return fmt.Errorf("unknown deletion mode: %s", j.cfg.DeletionMode)
+	})
+
+	return objects, err
+}
@@ -0,0 +1,130 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	entity "wildproject/internal/app/data/entities"
+	repo "wildproject/internal/app/data/repositories"
+	"wildproject/internal/app/data/storage"
+	event "wildproject/internal/app/domain/events"
+	manager "wildproject/internal/app/domain/managers"
+	model "wildproject/internal/app/domain/models"
+)
+
+// Only deletions are used by the job
+type recordedAvatars struct {
+	manager.AvatarManager
+	deleted []model.ImageVariant
+}
+
+func (a *recordedAvatars) Delete(variants []model.ImageVariant) error {
+	a.deleted = append(a.deleted, variants...)
+	return nil
+}
+
+// Fails the first "failures" deletions
+type recordedArchives struct {
+	storage.Objects
+	failures int
+	deleted  []string
+}
+
+func (a *recordedArchives) Delete(key string) error {
+	if a.failures > 0 {
+		a.failures--
+		return errors.New("storage is unavailable")
+	}
+
+	a.deleted = append(a.deleted, key)
+	return nil
+}
+
+// Creates account due deletion with an avatar and a ready export
+func createDueAccount(t *testing.T, repos repo.Repositories) string {
+	ctx := context.Background()
+
+	userID, err := repos.Users.Create(ctx, "user@example.com", "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	variants := []entity.ImageVariant{{Size: 64, URL: "https://cdn.example.com/avatar.jpg"}}
+	if err := repos.Users.ChangeImageVariants(ctx, userID, variants); err != nil {
+		t.Fatal(err)
+	}
+
+	exportID, err := repos.DataExports.Create(ctx, userID, "zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repos.DataExports.Claim(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repos.DataExports.Complete(ctx, exportID, "exports/archive.zip", "token", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repos.Users.ScheduleDeletion(ctx, userID, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	return userID
+}
+
+func newTestAccountDeletion(mode string, repos repo.Repositories, avatars *recordedAvatars, archives *recordedArchives) *AccountDeletion {
+	cfg := &model.UsersConfig{DeletionMode: mode, DeletionInterval: time.Minute}
+
+	return NewAccountDeletion(cfg, repos.Users, repo.NewMemoryUnitOfWork(repos), avatars, archives, event.NewBus())
+}
+
+// Objects of the account survive a rolled back deletion, the account
+// still refers to them
+func TestAccountDeletionKeepsObjectsOnRollback(t *testing.T) {
+	repos := repo.NewMemoryRepositories()
+	userID := createDueAccount(t, repos)
+	avatars, archives := &recordedAvatars{}, &recordedArchives{}
+
+	// Unknown mode fails the unit of work after everything else is done
+	newTestAccountDeletion("unknown", repos, avatars, archives).process(context.Background())
+
+	if len(avatars.deleted) != 0 || len(archives.deleted) != 0 {
+		t.Fatalf("expected no objects deleted, got %v and %v", avatars.deleted, archives.deleted)
+	}
+
+	exports, err := repos.DataExports.FindReadyByUserID(context.Background(), userID)
+	if err != nil || len(exports) != 1 {
+		t.Fatalf("expected export to stay ready, got %v, %v", exports, err)
+	}
+}
+
+func TestAccountDeletionRetriesObjects(t *testing.T) {
+	repos := repo.NewMemoryRepositories()
+	userID := createDueAccount(t, repos)
+	avatars, archives := &recordedAvatars{}, &recordedArchives{failures: 1}
+
+	j := newTestAccountDeletion(DeletionModeDelete, repos, avatars, archives)
+	j.process(context.Background())
+
+	if _, err := repos.Users.FindByID(context.Background(), userID); err == nil {
+		t.Fatal("expected account to be deleted")
+	}
+
+	if len(avatars.deleted) != 1 || len(archives.deleted) != 0 {
+		t.Fatalf("expected only avatar deleted, got %v and %v", avatars.deleted, archives.deleted)
+	}
+
+	// Account is gone, only the leftovers are retried
+	j.process(context.Background())
+
+	if len(archives.deleted) != 1 || archives.deleted[0] != "exports/archive.zip" {
+		t.Fatalf("expected archive deleted on the next run, got %v", archives.deleted)
+	}
+
+	if len(j.leftovers) != 0 {
+		t.Fatalf("expected no leftovers, got %+v", j.leftovers)
+	}
+}
@@ -0,0 +1,328 @@
+package job
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+	entity "wildproject/internal/app/data/entities"
+	repo "wildproject/internal/app/data/repositories"
+	"wildproject/internal/app/data/storage"
+	event "wildproject/internal/app/domain/events"
+	model "wildproject/internal/app/domain/models"
+	"wildproject/internal/stamp"
+
+	"github.com/gofiber/fiber/v2/log"
+)
+
+const (
+	// Processing exports older than this are considered abandoned by
+	// a crashed worker and are queued again
+	exportStaleAfter = time.Hour
+	// Max number of expired exports cleaned up per run
+	exportExpireBatchSize = 100
+	// Password changes are kept in the history, which is pruned anyway
+	exportMaxPasswordChanges = 1000
+	// Security events are append-only, so the export is capped
+	exportMaxSecurityEvents = 10000
+)
+
+var exportContentTypes = map[string]string{
+	model.DataExportFormatJSON: "application/json",
+	model.DataExportFormatZIP:  "application/zip",
+}
+
+// Assembles requested personal data exports into JSON or ZIP archives,
+// uploads them to the private storage and cleans them up once expired.
+// Archives are downloaded only through the app, which checks the token
+// and expiration
+type DataExports struct {
+	cfg      *model.ExportConfig
+	exports  repo.DataExportsRepo
+	users    repo.UsersRepo
+	sessions repo.SessionsRepo
+	history  repo.PasswordHistoryRepo
+	audit    repo.AuditEventsRepo
+	avatars  storage.Storage
+	archives storage.Objects
+	events   event.Subscriber
+}
+
+func NewDataExports(
+	cfg *model.ExportConfig,
+	er repo.DataExportsRepo,
+	ur repo.UsersRepo,
+	sr repo.SessionsRepo,
+	hr repo.PasswordHistoryRepo,
+	ar repo.AuditEventsRepo,
+	st storage.Storage,
+	as storage.Objects,
+	es event.Subscriber,
+) *DataExports {
+	return &DataExports{cfg, er, ur, sr, hr, ar, st, as, es}
+}
+
+// Blocks processing exports until context is canceled. Exports are picked
+// up right after they are requested or by polling otherwise
+func (j *DataExports) Run(ctx context.Context) {
+	wake := make(chan struct{}, 1)
+
+	unsubscribe := j.events.Subscribe(func(e event.Event) {
+		// Requests might have been missed on resync
+		if e.Name != event.DataExportRequested && e.Name != event.Resync {
+			return
+		}
+
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	})
+	defer unsubscribe()
+
+	ticker := time.NewTicker(j.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		j.requeueStale(ctx)
+		j.processPending(ctx)
+		j.cleanupExpired(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-wake:
+		}
+	}
+}
+
+func (j *DataExports) requeueStale(ctx context.Context) {
+	if err := j.exports.RequeueStale(ctx, time.Now().Add(-exportStaleAfter)); err != nil {
+		log.Errorf("cannot requeue stale data exports: %s", err)
+	}
+}
+
+func (j *DataExports) processPending(ctx context.Context) {
+	for {
+		export, err := j.exports.Claim(ctx)
+		if err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				log.Errorf("cannot claim data export: %s", err)
+			}
+
+			return
+		}
+
+		if err := j.process(ctx, export); err != nil {
+			log.Errorf("cannot assemble data export %s: %s", export.ExportID, err)
+
+			if err := j.exports.Fail(ctx, export.ExportID, err.Error()); err != nil {
+				log.Errorf("cannot fail data export %s: %s", export.ExportID, err)
+			}
+		}
+	}
+}
+
+func (j *DataExports) process(ctx context.Context, export entity.DataExport) error {
+	data, err := j.collect(ctx, export.UserID)
+	if err != nil {
+		return err
+	}
+
+	var archive []byte
+
+	switch export.Format {
+	case model.DataExportFormatJSON:
+		archive, err = json.MarshalIndent(data, "", "  ")
+	case model.DataExportFormatZIP:
+		archive, err = j.zip(data)
+	default:
+		err = fmt.Errorf("unknown export format: %s", export.Format)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	token, err := randomHex(32)
+	if err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("exports/%s/%s/%s.%s", export.UserID, export.ExportID, token, export.Format)
+
+	err = j.archives.Put(key, bytes.NewReader(archive), int64(len(archive)), exportContentTypes[export.Format])
+	if err != nil {
+		return err
+	}
+
+	expiresAt := time.Now().Add(j.cfg.LinkTTL).UTC()
+
+	return j.exports.Complete(ctx, export.ExportID, key, token, expiresAt)
+}
+
+func (j *DataExports) collect(ctx context.Context, userID string) (model.PersonalData, error) {
+	user, err := j.users.FindDetailedByID(ctx, userID)
+	if err != nil {
+		return model.PersonalData{}, err
+	}
+
+	sessions, err := j.sessions.FindAllByUserID(ctx, userID)
+	if err != nil {
+		return model.PersonalData{}, err
+	}
+
+	history, err := j.history.FindLatest(ctx, userID, exportMaxPasswordChanges)
+	if err != nil {
+		return model.PersonalData{}, err
+	}
+
+	events, err := j.audit.FindAllByUserID(ctx, userID, math.MaxInt64, exportMaxSecurityEvents)
+	if err != nil {
+		return model.PersonalData{}, err
+	}
+
+	data := model.PersonalData{
+		GeneratedAt: stamp.Stamp{Time: time.Now().UTC()},
+		User: model.UserDetailed{
+			User: model.User{
+				ID:        user.ID,
+				Email:     user.Email,
+				CreatedAt: stamp.Parse(user.CreatedAt),
+				UpdatedAt: stamp.Parse(user.UpdatetdAt),
+			},
+			Name:   user.Name,
+			Sex:    user.SexID,
+			Avatar: make([]model.ImageVariant, 0, len(user.ImageVariants)),
+		},
+		Sessions:        make([]model.PersonalDataSession, 0, len(sessions)),
+		PasswordChanges: make([]stamp.Stamp, 0, len(history)),
+		SecurityEvents:  make([]model.AuditEvent, 0, len(events)),
+	}
+
+	for _, v := range user.ImageVariants {
+		data.User.Avatar = append(data.User.Avatar, model.ImageVariant{Size: v.Size, URL: v.URL})
+	}
+
+	for _, s := range sessions {
+		data.Sessions = append(data.Sessions, model.PersonalDataSession{
+			SessionID: s.SessionID,
+			Uagent:    s.Uagent,
+			Fprint:    s.Fprint,
+			ExpiresAt: stamp.Parse(s.ExpiresAt),
+			CreatedAt: stamp.Parse(s.CreatedAt),
+		})
+	}
+
+	for _, h := range history {
+		data.PasswordChanges = append(data.PasswordChanges, stamp.Parse(h.CreatedAt))
+	}
+
+	for _, e := range events {
+		data.SecurityEvents = append(data.SecurityEvents, model.AuditEvent{
+			ID:        e.EventID,
+			Type:      e.EventType,
+			ActorID:   e.ActorID,
+			TargetID:  e.TargetID,
+			IP:        e.IP,
+			Uagent:    e.Uagent,
+			Metadata:  e.Metadata,
+			CreatedAt: stamp.Parse(e.CreatedAt),
+		})
+	}
+
+	return data, nil
+}
+
+// Packs personal data as "data.json" together with the uploaded avatar files
+func (j *DataExports) zip(data model.PersonalData) ([]byte, error) {
+	var buf bytes.Buffer
+
+	w := zip.NewWriter(&buf)
+
+	f, err := w.Create("data.json")
+	if err != nil {
+		return nil, err
+	}
+
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(data); err != nil {
+		return nil, err
+	}
+
+	for _, v := range data.User.Avatar {
+		key, ok := j.avatars.Key(v.URL)
+		if !ok {
+			continue
+		}
+
+		if err := j.zipObject(w, key, fmt.Sprintf("avatar/%d.jpg", v.Size)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (j *DataExports) zipObject(w *zip.Writer, key, name string) error {
+	object, err := j.avatars.Get(key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil
+		}
+
+		return err
+	}
+	defer object.Close()
+
+	f, err := w.Create(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(f, object)
+	return err
+}
+
+func (j *DataExports) cleanupExpired(ctx context.Context) {
+	exports, err := j.exports.FindExpired(ctx, exportExpireBatchSize)
+	if err != nil {
+		log.Errorf("cannot find expired data exports: %s", err)
+		return
+	}
+
+	for _, export := range exports {
+		if err := j.archives.Delete(export.ObjectKey); err != nil {
+			log.Errorf("cannot delete expired data export %s: %s", export.ExportID, err)
+			continue
+		}
+
+		if err := j.exports.Expire(ctx, export.ExportID); err != nil {
+			log.Errorf("cannot expire data export %s: %s", export.ExportID, err)
+		}
+	}
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
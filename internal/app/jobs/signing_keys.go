@@ -0,0 +1,84 @@
+package job
+
+import (
+	"context"
+	"time"
+	repo "wildproject/internal/app/data/repositories"
+	event "wildproject/internal/app/domain/events"
+	manager "wildproject/internal/app/domain/managers"
+	model "wildproject/internal/app/domain/models"
+	"wildproject/internal/stamp"
+
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// Rotations are announced by the events, polling only catches the ones
+// missed while the bus was down
+const signingKeysReloadInterval = time.Minute
+
+// Keeps the keys the access tokens are signed with in sync with the ones
+// rotated by the operators
+type SigningKeys struct {
+	keys   repo.SigningKeysRepo
+	set    manager.SigningKeySet
+	events event.Subscriber
+}
+
+func NewSigningKeys(kr repo.SigningKeysRepo, ks manager.SigningKeySet, es event.Subscriber) *SigningKeys {
+	return &SigningKeys{kr, ks, es}
+}
+
+// Loads the keys into the set. Must succeed before any token is issued,
+// otherwise the tokens would be signed with a retired key
+func LoadSigningKeys(ctx context.Context, kr repo.SigningKeysRepo, ks manager.SigningKeySet) error {
+	ents, err := kr.FindAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]model.SigningKey, 0, len(ents))
+	for _, e := range ents {
+		keys = append(keys, model.SigningKey{
+			ID:        e.KeyID,
+			Secret:    e.Secret,
+			CreatedAt: stamp.Parse(e.CreatedAt).Time,
+		})
+	}
+
+	ks.SetKeys(keys)
+
+	return nil
+}
+
+// Blocks reloading the keys until context is canceled
+func (j *SigningKeys) Run(ctx context.Context) {
+	wake := make(chan struct{}, 1)
+
+	unsubscribe := j.events.Subscribe(func(e event.Event) {
+		if e.Name != event.SigningKeysRotated && e.Name != event.Resync {
+			return
+		}
+
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	})
+	defer unsubscribe()
+
+	ticker := time.NewTicker(signingKeysReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-wake:
+		}
+
+		if err := LoadSigningKeys(ctx, j.keys, j.set); err != nil {
+			log.Errorf("cannot reload signing keys: %s", err)
+		}
+	}
+}
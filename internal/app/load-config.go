@@ -1,55 +1,107 @@
 package app
 
 import (
-	"time"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	model "wildproject/internal/app/domain/models"
 	"wildproject/pkg/env"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrUnsupportedConfigFormat = errors.New("unsupported config file format")
+	ErrUnknownConfigKeys       = errors.New("unknown config keys")
 )
 
-func loadConfigFromEnv() (*model.Config, error) {
-	environment := env.String("ENV")
-	projName := env.String("NAME")
-
-	conn := env.String("DATABASE_CONN_STRING")
-
-	addr := env.String("SERVER_ADDR")
-	readTimeout := env.Int("SERVER_READ_TIMEOUT")
-	writeTimeout := env.Int("SERVER_WRITE_TIMEOUT")
-	idleTimeout := env.Int("SERVER_IDLE_TIMEOUT")
-
-	authJwtSecret := env.String("AUTH_JWT_SECRET")
-	accessTokenTTL := env.Int("AUTH_ACCESS_TOKEN_TTL")
-	refreshTokenTTL := env.Int("AUTH_REFRESH_TOKEN_TTL")
-
-	sentryDsn := env.String("SENTRY_DSN")
-	sentryTSRate := env.Float64("SENTRY_TRACES_SAMPLE_RATE")
-	sentryAttachST := env.Bool("SENTRY_ATTACH_STACKTRACE")
-	sentryDebug := env.Bool("SENTRY_DEBUG")
-
-	cfg := model.Config{
-		Name: projName,
-		Env:  environment,
-		Database: model.DatabaseConfig{
-			Conn: conn,
-		},
-		Server: model.ServerConfig{
-			Addr:         addr,
-			ReadTimeout:  time.Duration(readTimeout) * time.Second,
-			WriteTimeout: time.Duration(writeTimeout) * time.Second,
-			IdleTimeout:  time.Duration(idleTimeout) * time.Second,
-		},
-		Auth: model.AuthConfig{
-			AuthJwtSecret:   []byte(authJwtSecret),
-			AccessTokenTTL:  time.Duration(accessTokenTTL) * time.Minute,
-			RefreshTokenTTL: time.Duration(refreshTokenTTL) * time.Minute,
-		},
-		Sentry: model.SentryConfig{
-			Dsn:              sentryDsn,
-			TracesSampleRate: sentryTSRate,
-			AttachStackTrace: sentryAttachST,
-			Debug:            sentryDebug,
-		},
+// Builds config from the layers, each one overriding the previous:
+// defaults, YAML or TOML file (optional) and env. The result is checked by
+// "validate" and all the problems are reported at once
+func loadConfig(path string, validate func(*model.Config) error) (*model.Config, error) {
+	cfg := defaultConfig()
+
+	if path != "" {
+		if err := loadConfigFromFile(&cfg, path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := loadConfigFromEnv(&cfg); err != nil {
+		return nil, err
+	}
+
+	if err := validate(&cfg); err != nil {
+		return nil, err
 	}
 
 	return &cfg, nil
 }
+
+// Overrides config with the values set in the YAML or TOML file, unknown
+// keys are rejected to catch typos
+func loadConfigFromFile(cfg *model.Config, path string) error {
+	decode, ok := configDecoders[filepath.Ext(path)]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnsupportedConfigFormat, filepath.Ext(path))
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := decode(f, cfg); err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+var configDecoders = map[string]func(io.Reader, *model.Config) error{
+	".yaml": decodeYAMLConfig,
+	".yml":  decodeYAMLConfig,
+	".toml": decodeTOMLConfig,
+}
+
+func decodeYAMLConfig(r io.Reader, cfg *model.Config) error {
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+
+	// Empty file leaves the defaults as is
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	return nil
+}
+
+// Durations are strings like in YAML, e.g. access_token_ttl = "15m"
+func decodeTOMLConfig(r io.Reader, cfg *model.Config) error {
+	md, err := toml.NewDecoder(r).Decode(cfg)
+	if err != nil {
+		return err
+	}
+
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		keys := make([]string, 0, len(undecoded))
+		for _, key := range undecoded {
+			keys = append(keys, key.String())
+		}
+
+		return fmt.Errorf("%w: %s", ErrUnknownConfigKeys, strings.Join(keys, ", "))
+	}
+
+	return nil
+}
+
+// Overrides config with the set env variables named by the config tags,
+// any of them can be read from a file named by <key>_FILE instead
+func loadConfigFromEnv(cfg *model.Config) error {
+	return env.Bind(cfg)
+}
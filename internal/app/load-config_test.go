@@ -0,0 +1,88 @@
+package app
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	model "wildproject/internal/app/domain/models"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func noValidation(*model.Config) error { return nil }
+
+func TestLoadConfigFile(t *testing.T) {
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"config.yaml", "auth:\n  access_token_ttl: 20m\nserver:\n  addr: \":8081\"\n"},
+		{"config.toml", "[auth]\naccess_token_ttl = \"20m\"\n\n[server]\naddr = \":8081\"\n"},
+	}
+
+	for _, f := range files {
+		t.Run(f.name, func(t *testing.T) {
+			cfg, err := loadConfig(writeConfigFile(t, f.name, f.content), noValidation)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if cfg.Auth.AccessTokenTTL != 20*time.Minute {
+				t.Fatalf("expected access token TTL 20m, got %v", cfg.Auth.AccessTokenTTL)
+			}
+
+			if cfg.Server.Addr != ":8081" {
+				t.Fatalf("expected addr :8081, got %v", cfg.Server.Addr)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFileUnknownKeys(t *testing.T) {
+	path := writeConfigFile(t, "config.toml", "[auth]\naccess_token_tll = \"20m\"\n")
+
+	if _, err := loadConfig(path, noValidation); !errors.Is(err, ErrUnknownConfigKeys) {
+		t.Fatalf("expected ErrUnknownConfigKeys, got %v", err)
+	}
+
+	path = writeConfigFile(t, "config.json", "{}")
+
+	if _, err := loadConfig(path, noValidation); !errors.Is(err, ErrUnsupportedConfigFormat) {
+		t.Fatalf("expected ErrUnsupportedConfigFormat, got %v", err)
+	}
+}
+
+// Env takes precedence over the file and keeps its historical units
+func TestLoadConfigEnv(t *testing.T) {
+	path := writeConfigFile(t, "config.toml", "[auth]\naccess_token_ttl = \"20m\"\n")
+
+	t.Setenv("AUTH_ACCESS_TOKEN_TTL", "30")
+	t.Setenv("DATABASE_QUERY_TIMEOUT", "250")
+	t.Setenv("AVATAR_MAX_SIZE", "512")
+
+	cfg, err := loadConfig(path, noValidation)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Auth.AccessTokenTTL != 30*time.Minute {
+		t.Fatalf("expected access token TTL 30m, got %v", cfg.Auth.AccessTokenTTL)
+	}
+
+	if cfg.Database.QueryTimeout != 250*time.Millisecond {
+		t.Fatalf("expected query timeout 250ms, got %v", cfg.Database.QueryTimeout)
+	}
+
+	if cfg.Avatar.MaxSize != 512*1024 {
+		t.Fatalf("expected avatar max size 512 KiB, got %v", cfg.Avatar.MaxSize)
+	}
+}
@@ -0,0 +1,15 @@
+package mail
+
+import "github.com/gofiber/fiber/v2/log"
+
+// Writes emails to the app log instead of sending them, for development
+type Log struct{}
+
+func NewLog() *Log {
+	return &Log{}
+}
+
+func (l *Log) Send(m Message) error {
+	log.Infof("mail to %s: %s\n%s", m.To, m.Subject, m.Body)
+	return nil
+}
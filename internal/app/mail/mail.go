@@ -0,0 +1,23 @@
+package mail
+
+import "errors"
+
+const (
+	DriverLog  = "log"
+	DriverSMTP = "smtp"
+)
+
+var (
+	ErrUnknownDriver = errors.New("unknown mail driver")
+)
+
+// Plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(m Message) error
+}
@@ -0,0 +1,60 @@
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type SMTPOptions struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// Sends emails through SMTP server. Connection is upgraded with STARTTLS
+// if the server supports it, credentials are never sent unencrypted
+type SMTP struct {
+	opts SMTPOptions
+	addr string
+}
+
+func NewSMTP(opts SMTPOptions) *SMTP {
+	return &SMTP{opts, net.JoinHostPort(opts.Host, strconv.Itoa(opts.Port))}
+}
+
+func (s *SMTP) Send(m Message) error {
+	if strings.ContainsAny(m.To, "\r\n") {
+		return fmt.Errorf("invalid recipient %q", m.To)
+	}
+
+	var auth smtp.Auth
+	if s.opts.Username != "" {
+		auth = smtp.PlainAuth("", s.opts.Username, s.opts.Password, s.opts.Host)
+	}
+
+	return smtp.SendMail(s.addr, auth, s.opts.From, []string{m.To}, s.build(m))
+}
+
+func (s *SMTP) build(m Message) []byte {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "From: %s\r\n", s.opts.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", m.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(m.Body, "\n", "\r\n"))
+
+	return buf.Bytes()
+}
@@ -4,4 +4,6 @@ var (
 	LocalKeyCommon = "common_payload"
 
 	HeaderFingerprint = "X-Fingerprint"
+
+	FormKeyAvatar = "avatar"
 )
@@ -0,0 +1,74 @@
+package controller
+
+import (
+	model "wildproject/internal/app/domain/models"
+	service "wildproject/internal/app/domain/services"
+	constant "wildproject/internal/app/router/constants"
+
+	"github.com/gofiber/contrib/fibersentry"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	securityEventsDefaultLimit = 20
+	securityEventsMaxLimit     = 100
+)
+
+var (
+	ErrInvalidCursor = fiber.NewError(fiber.StatusBadRequest, "invalid before cursor, expected positive event id")
+)
+
+type Audit struct {
+	s service.AuditService
+}
+
+func NewAudit(s service.AuditService) *Audit {
+	return &Audit{s}
+}
+
+type securityEventsResponse struct {
+	Events []model.AuditEvent `json:"events"`
+	// Id to pass as "before" to get the next page, 0 if there are no more events
+	NextCursor int64 `json:"next_cursor"`
+}
+
+// Returns security events made by or to the user, newest first.
+// Paginated by "before" cursor and "limit" query params
+func (a *Audit) GetSecurityEvents(c *fiber.Ctx) error {
+	hub := fibersentry.GetHubFromContext(c)
+
+	p, ok := c.Locals(constant.LocalKeyCommon).(model.CommonRequestPayload)
+	if !ok {
+		return ErrInvalidCommonPayload
+	}
+
+	limit := c.QueryInt("limit", securityEventsDefaultLimit)
+	if limit <= 0 {
+		limit = securityEventsDefaultLimit
+	}
+
+	if limit > securityEventsMaxLimit {
+		limit = securityEventsMaxLimit
+	}
+
+	before := c.QueryInt("before", 0)
+	if before < 0 {
+		return ErrInvalidCursor
+	}
+
+	events, err := a.s.FindAll(c.UserContext(), p.UserID, int64(before), limit)
+	if err != nil {
+		hub.CaptureException(err)
+		return err
+	}
+
+	var next int64
+	if len(events) == limit {
+		next = events[len(events)-1].ID
+	}
+
+	return c.JSON(securityEventsResponse{
+		Events:     events,
+		NextCursor: next,
+	})
+}
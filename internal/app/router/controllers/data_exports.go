@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	model "wildproject/internal/app/domain/models"
+	service "wildproject/internal/app/domain/services"
+	constant "wildproject/internal/app/router/constants"
+
+	"github.com/gofiber/contrib/fibersentry"
+	"github.com/gofiber/fiber/v2"
+)
+
+var (
+	ErrUnknownExportFormat = fiber.NewError(fiber.StatusBadRequest, "unknown export format, expected json or zip")
+
+	ErrExportNotFound = fiber.NewError(fiber.StatusNotFound, "export not found")
+
+	ErrExportNotReady = fiber.NewError(fiber.StatusConflict, "export is not ready yet")
+
+	ErrExportExpired = fiber.NewError(fiber.StatusGone, "export download link is expired")
+)
+
+var exportContentTypes = map[string]string{
+	model.DataExportFormatJSON: fiber.MIMEApplicationJSON,
+	model.DataExportFormatZIP:  "application/zip",
+}
+
+type DataExports struct {
+	s service.DataExportsService
+}
+
+func NewDataExports(s service.DataExportsService) *DataExports {
+	return &DataExports{s}
+}
+
+type createExportRequest struct {
+	Format string `json:"format"`
+}
+
+// Requests export of all user's personal data, which is assembled
+// asynchronously. Only one export is processed at a time per user
+func (d *DataExports) Create(c *fiber.Ctx) error {
+	hub := fibersentry.GetHubFromContext(c)
+
+	request := createExportRequest{
+		Format: model.DataExportFormatJSON,
+	}
+
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&request); err != nil {
+			return ErrInvalidBody(err)
+		}
+	}
+
+	p, ok := c.Locals(constant.LocalKeyCommon).(model.CommonRequestPayload)
+	if !ok {
+		return ErrInvalidCommonPayload
+	}
+
+	export, err := d.s.Request(c.UserContext(), p.UserID, request.Format)
+	if err != nil {
+		if errors.Is(err, service.ErrUnknownExportFormat) {
+			return ErrUnknownExportFormat
+		}
+
+		hub.CaptureException(err)
+		return err
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(export)
+}
+
+// Returns export status and download link once it's ready
+func (d *DataExports) GetByID(c *fiber.Ctx) error {
+	hub := fibersentry.GetHubFromContext(c)
+
+	p, ok := c.Locals(constant.LocalKeyCommon).(model.CommonRequestPayload)
+	if !ok {
+		return ErrInvalidCommonPayload
+	}
+
+	export, err := d.s.Find(c.UserContext(), p.UserID, c.Params("export_id"))
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			return ErrExportNotFound
+		}
+
+		hub.CaptureException(err)
+		return err
+	}
+
+	return c.JSON(export)
+}
+
+// Downloads ready export by the secret token from its download link
+func (d *DataExports) Download(c *fiber.Ctx) error {
+	hub := fibersentry.GetHubFromContext(c)
+
+	archive, export, err := d.s.Open(c.UserContext(), c.Params("token"))
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			return ErrExportNotFound
+		}
+
+		if errors.Is(err, service.ErrExportNotReady) {
+			return ErrExportNotReady
+		}
+
+		if errors.Is(err, service.ErrExportExpired) {
+			return ErrExportExpired
+		}
+
+		hub.CaptureException(err)
+		return err
+	}
+
+	c.Set(fiber.HeaderContentType, exportContentTypes[export.Format])
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	c.Attachment(fmt.Sprintf("personal-data-%s.%s", export.ID, export.Format))
+
+	// Fiber closes the stream once it's sent
+	return c.SendStream(archive)
+}
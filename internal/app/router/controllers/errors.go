@@ -2,6 +2,7 @@ package controller
 
 import (
 	"fmt"
+	manager "wildproject/internal/app/domain/managers"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -20,3 +21,16 @@ func ErrUnauthorized(err error) error {
 	message := fmt.Sprintf("unauthorized: %s", err)
 	return fiber.NewError(fiber.StatusUnauthorized, message)
 }
+
+type passwordPolicyResponse struct {
+	Message    string   `json:"message"`
+	Violations []string `json:"violations"`
+}
+
+// Responds with 400 listing machine-readable codes of the violated password rules
+func ErrPasswordPolicy(c *fiber.Ctx, err *manager.PasswordPolicyError) error {
+	return c.Status(fiber.StatusBadRequest).JSON(passwordPolicyResponse{
+		Message:    manager.ErrPasswordPolicy.Error(),
+		Violations: err.Violations,
+	})
+}
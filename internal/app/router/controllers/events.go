@@ -0,0 +1,182 @@
+package controller
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+	event "wildproject/internal/app/domain/events"
+	model "wildproject/internal/app/domain/models"
+	constant "wildproject/internal/app/router/constants"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+const (
+	// Comment is sent periodically, so proxies keep the connection open
+	// and disconnected clients are noticed
+	eventsHeartbeatInterval = 20 * time.Second
+	// Events are dropped for the client, which doesn't keep up with them
+	eventsBufferSize = 16
+	// Sent before the stream is closed on the access token expiration,
+	// the client is expected to refresh the token and reconnect
+	eventTokenExpired = "token.expired"
+)
+
+// Events clients are notified about, the rest are internal
+var streamedEvents = map[string]bool{
+	event.SessionRevoked:  true,
+	event.PasswordChanged: true,
+	event.EmailChanged:    true,
+	event.UserDeleted:     true,
+	event.Resync:          true,
+}
+
+type Events struct {
+	es event.Subscriber
+	// Closed on shutdown to end all the streams
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func NewEvents(es event.Subscriber) *Events {
+	return &Events{es: es, done: make(chan struct{})}
+}
+
+// Ends all the open streams, so they don't hold the server's shutdown.
+// Clients reconnect to another instance
+func (e *Events) Close() {
+	e.closeOnce.Do(func() {
+		close(e.done)
+	})
+}
+
+// Streams user's events (session revocations, password changes, etc.)
+// as Server-Sent Events. The stream is closed once the session it's opened
+// with is revoked, so the client logs out right away, and once the access
+// token expires, so the stream doesn't outlive the token.
+//
+// Stream isn't limited by the server's write timeout: fasthttp sets the
+// write deadline once before the response is written, so it would cut
+// the stream on the first write after it. Disconnected clients are
+// noticed on the heartbeats instead
+func (e *Events) Stream(c *fiber.Ctx) error {
+	p, ok := c.Locals(constant.LocalKeyCommon).(model.CommonRequestPayload)
+	if !ok {
+		return ErrInvalidCommonPayload
+	}
+
+	events := make(chan event.Event, eventsBufferSize)
+
+	unsubscribe := e.es.Subscribe(func(ev event.Event) {
+		// Resync is sent to everyone, as it has no user
+		if (ev.UserID != p.UserID && ev.Name != event.Resync) || !streamedEvents[ev.Name] {
+			return
+		}
+
+		select {
+		case events <- ev:
+		default:
+			log.Warnf("events stream of user %s is full, %s dropped", p.UserID, ev.Name)
+		}
+	})
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	// Disables response buffering in nginx
+	c.Set("X-Accel-Buffering", "no")
+
+	// Context is reused once the handler returns, connection is not
+	conn := c.Context().Conn()
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer unsubscribe()
+
+		heartbeat := time.NewTicker(eventsHeartbeatInterval)
+		defer heartbeat.Stop()
+
+		// Nil channel never fires, so tokens without expiration are not limited
+		var expired <-chan time.Time
+		if !p.ExpiresAt.IsZero() {
+			timer := time.NewTimer(time.Until(p.ExpiresAt))
+			defer timer.Stop()
+
+			expired = timer.C
+		}
+
+		// Sent right away, so the client knows the stream is established
+		if err := writeComment(conn, w, "connected"); err != nil {
+			return
+		}
+
+		for {
+			select {
+			case ev := <-events:
+				if err := writeEvent(conn, w, ev); err != nil {
+					return
+				}
+
+				if revokesSession(ev, p.SessionID) {
+					return
+				}
+			case <-heartbeat.C:
+				if err := writeComment(conn, w, "heartbeat"); err != nil {
+					return
+				}
+			case <-expired:
+				writeEvent(conn, w, event.New(eventTokenExpired, p.UserID, nil))
+				return
+			case <-e.done:
+				return
+			}
+		}
+	})
+
+	return nil
+}
+
+func revokesSession(ev event.Event, sessionID int) bool {
+	if ev.Name == event.UserDeleted {
+		return true
+	}
+
+	if ev.Name != event.SessionRevoked {
+		return false
+	}
+
+	revoked, ok := ev.Data["session_id"]
+
+	return !ok || revoked == strconv.Itoa(sessionID)
+}
+
+func writeEvent(conn net.Conn, w *bufio.Writer, ev event.Event) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Name, data)
+
+	return flush(conn, w)
+}
+
+func writeComment(conn net.Conn, w *bufio.Writer, comment string) error {
+	fmt.Fprintf(w, ": %s\n\n", comment)
+
+	return flush(conn, w)
+}
+
+// Deadline is cleared before every write, as the server may set it
+// after the stream has started
+func flush(conn net.Conn, w *bufio.Writer) error {
+	if err := conn.SetWriteDeadline(time.Time{}); err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+	event "wildproject/internal/app/domain/events"
+	model "wildproject/internal/app/domain/models"
+	constant "wildproject/internal/app/router/constants"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Deadlines apply only to real connections, so the app listens on
+// a local port instead of app.Test
+func TestEventsStreamOutlivesWriteTimeout(t *testing.T) {
+	const writeTimeout = 100 * time.Millisecond
+
+	bus := event.NewBus()
+	evc := NewEvents(bus)
+
+	app := fiber.New(fiber.Config{WriteTimeout: writeTimeout})
+	app.Get("/events", func(c *fiber.Ctx) error {
+		c.Locals(constant.LocalKeyCommon, model.CommonRequestPayload{
+			TokenPayload: model.TokenPayload{UserID: "user", SessionID: 1},
+		})
+
+		return c.Next()
+	}, evc.Stream)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go app.Listener(ln)
+	t.Cleanup(func() { app.Shutdown() })
+	// Runs first, so the stream doesn't hold the shutdown
+	t.Cleanup(evc.Close)
+
+	resp, err := http.Get("http://" + ln.Addr().String() + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	readLine := func(prefix string) {
+		t.Helper()
+
+		for {
+			select {
+			case line, ok := <-lines:
+				if !ok {
+					t.Fatalf("expected %q, got stream closed", prefix)
+				}
+
+				if strings.HasPrefix(line, prefix) {
+					return
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("expected %q, got nothing", prefix)
+			}
+		}
+	}
+
+	readLine(": connected")
+
+	time.Sleep(3 * writeTimeout)
+	bus.Publish(event.New(event.PasswordChanged, "user", nil))
+
+	readLine("event: " + event.PasswordChanged)
+}
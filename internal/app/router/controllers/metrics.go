@@ -0,0 +1,39 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+	manager "wildproject/internal/app/domain/managers"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Metrics struct {
+	sc manager.SessionCache
+}
+
+func NewMetrics(sc manager.SessionCache) *Metrics {
+	return &Metrics{sc}
+}
+
+// Exposes metrics in the Prometheus text format
+func (m *Metrics) Get(c *fiber.Ctx) error {
+	stats := m.sc.Stats()
+
+	var b strings.Builder
+
+	writeMetric(&b, "session_cache_hits_total", "counter", "Sessions found in the cache", stats.Hits)
+	writeMetric(&b, "session_cache_misses_total", "counter", "Sessions not found in the cache", stats.Misses)
+	writeMetric(&b, "session_cache_evictions_total", "counter", "Sessions evicted from the full cache", stats.Evictions)
+	writeMetric(&b, "session_cache_size", "gauge", "Sessions currently cached", stats.Size)
+
+	c.Set(fiber.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+
+	return c.SendString(b.String())
+}
+
+func writeMetric(b *strings.Builder, name, kind, help string, value any) {
+	fmt.Fprintf(b, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(b, "# TYPE %s %s\n", name, kind)
+	fmt.Fprintf(b, "%s %v\n", name, value)
+}
@@ -0,0 +1,38 @@
+package controller
+
+import (
+	"sync/atomic"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type readinessResponse struct {
+	Status string `json:"status"`
+}
+
+// Tells the load balancer whether to route requests to the instance.
+// Unlike health check, it starts failing once shutdown begins
+type Readiness struct {
+	draining atomic.Bool
+}
+
+func NewReadiness() *Readiness {
+	return &Readiness{}
+}
+
+func (r *Readiness) Get(c *fiber.Ctx) error {
+	if r.draining.Load() {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(readinessResponse{
+			Status: "draining",
+		})
+	}
+
+	return c.JSON(readinessResponse{
+		Status: "ready",
+	})
+}
+
+// Makes readiness fail for the rest of the process lifetime
+func (r *Readiness) Drain() {
+	r.draining.Store(true)
+}
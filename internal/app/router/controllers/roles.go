@@ -0,0 +1,40 @@
+package controller
+
+import (
+	model "wildproject/internal/app/domain/models"
+	service "wildproject/internal/app/domain/services"
+	constant "wildproject/internal/app/router/constants"
+
+	"github.com/gofiber/contrib/fibersentry"
+	"github.com/gofiber/fiber/v2"
+)
+
+type Roles struct {
+	s service.RolesService
+}
+
+func NewRoles(s service.RolesService) *Roles {
+	return &Roles{s}
+}
+
+type rolesResponse struct {
+	Roles []string `json:"roles"`
+}
+
+// Returns roles granted to the user, they are granted only by operators
+func (r *Roles) GetAll(c *fiber.Ctx) error {
+	hub := fibersentry.GetHubFromContext(c)
+
+	p, ok := c.Locals(constant.LocalKeyCommon).(model.CommonRequestPayload)
+	if !ok {
+		return ErrInvalidCommonPayload
+	}
+
+	roles, err := r.s.FindAll(c.UserContext(), p.UserID)
+	if err != nil {
+		hub.CaptureException(err)
+		return err
+	}
+
+	return c.JSON(rolesResponse{Roles: roles})
+}
@@ -3,6 +3,7 @@ package controller
 import (
 	"errors"
 	"strconv"
+	"time"
 	model "wildproject/internal/app/domain/models"
 	service "wildproject/internal/app/domain/services"
 	constant "wildproject/internal/app/router/constants"
@@ -11,12 +12,21 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
+const (
+	sessionsDefaultLimit = 20
+	sessionsMaxLimit     = 100
+)
+
 var (
-	ErrInvalidSessionID     = fiber.NewError(fiber.StatusBadRequest, "invalid session_id")
-	ErrInvalidDevice        = fiber.NewError(fiber.StatusBadRequest, "invalid locals device_info")
-	ErrWrongEmailOrPassword = fiber.NewError(fiber.StatusBadRequest, "wrong email or password")
-	ErrUserAgentNotPassed   = fiber.NewError(fiber.StatusBadRequest, "User-Agent header is required")
-	ErrFingerprintNotPassed = fiber.NewError(fiber.StatusBadRequest, "X-Fingerprint header is required")
+	ErrInvalidSessionID      = fiber.NewError(fiber.StatusBadRequest, "invalid session_id")
+	ErrInvalidSessionStatus  = fiber.NewError(fiber.StatusBadRequest, "invalid status, expected active or expired")
+	ErrInvalidSortOrder      = fiber.NewError(fiber.StatusBadRequest, "invalid order, expected asc or desc")
+	ErrInvalidSessionsCursor = fiber.NewError(fiber.StatusBadRequest, "invalid cursor, expected positive session id")
+	ErrInvalidCreatedAfter   = fiber.NewError(fiber.StatusBadRequest, "invalid created_after, expected RFC 3339 time")
+	ErrInvalidDevice         = fiber.NewError(fiber.StatusBadRequest, "invalid locals device_info")
+	ErrWrongEmailOrPassword  = fiber.NewError(fiber.StatusBadRequest, "wrong email or password")
+	ErrUserAgentNotPassed    = fiber.NewError(fiber.StatusBadRequest, "User-Agent header is required")
+	ErrFingerprintNotPassed  = fiber.NewError(fiber.StatusBadRequest, "X-Fingerprint header is required")
 
 	ErrAlreadyAuthorized = fiber.NewError(fiber.StatusConflict, "passed token is valid and so user is already authorized")
 
@@ -42,12 +52,17 @@ func NewSessions(
 func (s *Sessions) GetByID(c *fiber.Ctx) error {
 	hub := fibersentry.GetHubFromContext(c)
 
+	p, ok := c.Locals(constant.LocalKeyCommon).(model.CommonRequestPayload)
+	if !ok {
+		return ErrInvalidCommonPayload
+	}
+
 	sessionID, err := strconv.Atoi(c.Params("session_id"))
 	if err != nil {
 		return ErrInvalidSessionID
 	}
 
-	session, err := s.sSer.Find(sessionID)
+	session, err := s.sSer.Find(c.UserContext(), p.UserID, sessionID)
 	if err != nil {
 		if errors.Is(err, service.ErrNotFound) {
 			return ErrSessionNotFound
@@ -57,14 +72,14 @@ func (s *Sessions) GetByID(c *fiber.Ctx) error {
 		return err
 	}
 
-	return c.JSON(session)
-}
+	session.IsCurrent = session.SessionID == p.SessionID
 
-type sessionsResponse struct {
-	Sessions []model.ClientRefreshSession `json:"sessions"`
+	return c.JSON(session)
 }
 
-// Get all active user's refresh sessions
+// Get page of user's refresh sessions. Supports "status" (active or expired),
+// "device", "created_after", "order" (asc or desc), "cursor" and "limit"
+// query params
 func (s *Sessions) GetAllByUserID(c *fiber.Ctx) error {
 	hub := fibersentry.GetHubFromContext(c)
 
@@ -73,17 +88,60 @@ func (s *Sessions) GetAllByUserID(c *fiber.Ctx) error {
 		return ErrInvalidCommonPayload
 	}
 
-	sessions, err := s.sSer.FindAll(p.UserID, "", "")
-	if err != nil {
-		if errors.Is(err, service.ErrNotFound) {
-			return ErrSessionNotFound
+	filter := model.SessionsFilter{
+		UserID:           p.UserID,
+		CurrentSessionID: p.SessionID,
+		Status:           c.Query("status"),
+		Device:           c.Query("device"),
+		Order:            c.Query("order", model.SortDesc),
+		Limit:            c.QueryInt("limit", sessionsDefaultLimit),
+	}
+
+	// QueryInt falls back to the default on garbage, so a typo would
+	// silently restart paging from the first page
+	if rawCursor := c.Query("cursor"); rawCursor != "" {
+		cursor, err := strconv.Atoi(rawCursor)
+		if err != nil || cursor < 0 {
+			return ErrInvalidSessionsCursor
+		}
+
+		filter.Cursor = cursor
+	}
+
+	if filter.Status != "" &&
+		filter.Status != model.SessionStatusActive &&
+		filter.Status != model.SessionStatusExpired {
+		return ErrInvalidSessionStatus
+	}
+
+	if filter.Order != model.SortAsc && filter.Order != model.SortDesc {
+		return ErrInvalidSortOrder
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = sessionsDefaultLimit
+	}
+
+	if filter.Limit > sessionsMaxLimit {
+		filter.Limit = sessionsMaxLimit
+	}
+
+	if rawCreatedAfter := c.Query("created_after"); rawCreatedAfter != "" {
+		createdAfter, err := time.Parse(time.RFC3339, rawCreatedAfter)
+		if err != nil {
+			return ErrInvalidCreatedAfter
 		}
 
+		filter.CreatedAfter = &createdAfter
+	}
+
+	page, err := s.sSer.FindPage(c.UserContext(), filter)
+	if err != nil {
 		hub.CaptureException(err)
 		return err
 	}
 
-	return c.JSON(sessionsResponse{sessions})
+	return c.JSON(page)
 }
 
 type newSessionRequest struct {
@@ -108,15 +166,19 @@ func (s *Sessions) Create(c *fiber.Ctx) error {
 		return ErrInvalidBody(err)
 	}
 
-	// TODO: add email and password validation
-	// minlength: 8;
-	// maxlength: 72;
-	// required: lower;
-	// required: upper;
-	// required: digit;
-	// required: [#$%&*.@^];
+	// Device is checked before credentials, so login attempts are audited with it
+	device := DeviceFromRequest(c)
+	if device.Uagent == "" {
+		return ErrUserAgentNotPassed
+	}
 
-	userID, err := s.uSer.Authenticate(request.Email, request.Password)
+	if device.Fprint == "" {
+		return ErrFingerprintNotPassed
+	}
+
+	// Password policy is enforced on password set (see service.Users),
+	// so credentials are not validated here to keep old passwords usable
+	userID, err := s.uSer.Authenticate(c.UserContext(), request.Email, request.Password, device)
 	if err != nil {
 		if errors.Is(err, service.ErrNotFound) || errors.Is(err, service.ErrPasswordsMismatch) {
 			return ErrWrongEmailOrPassword
@@ -127,17 +189,7 @@ func (s *Sessions) Create(c *fiber.Ctx) error {
 	}
 
 	// Check if new device is used else drop an old session
-	uagent := c.Get(fiber.HeaderUserAgent)
-	if uagent == "" {
-		return ErrUserAgentNotPassed
-	}
-
-	fprint := c.Get("X-Fingerprint")
-	if fprint == "" {
-		return ErrFingerprintNotPassed
-	}
-
-	tokens, err := s.sSer.Create(userID, uagent, fprint)
+	tokens, err := s.sSer.Create(c.UserContext(), userID, device)
 	if err != nil {
 		hub.CaptureException(err)
 		return err
@@ -171,12 +223,7 @@ func (s *Sessions) Refresh(c *fiber.Ctx) error {
 		return ErrInvalidDevice
 	}
 
-	tokens, err := s.sSer.Refresh(
-		request.RefreshToken,
-		userID,
-		cp.Uagent,
-		cp.Fprint,
-	)
+	tokens, err := s.sSer.Refresh(c.UserContext(), request.RefreshToken, userID, cp.DeviceInfo)
 
 	if err != nil {
 		if errors.Is(err, service.ErrUnknownToken) {
@@ -198,12 +245,21 @@ func (s *Sessions) Refresh(c *fiber.Ctx) error {
 func (s *Sessions) Drop(c *fiber.Ctx) error {
 	hub := fibersentry.GetHubFromContext(c)
 
+	p, ok := c.Locals(constant.LocalKeyCommon).(model.CommonRequestPayload)
+	if !ok {
+		return ErrInvalidCommonPayload
+	}
+
 	sessionID, err := strconv.Atoi(c.Params("session_id"))
 	if err != nil {
 		return ErrInvalidSessionID
 	}
 
-	if err := s.sSer.Drop(sessionID); err != nil {
+	if err := s.sSer.Drop(c.UserContext(), p.UserID, sessionID, p.DeviceInfo); err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			return ErrSessionNotFound
+		}
+
 		hub.CaptureException(err)
 		return err
 	}
@@ -220,10 +276,20 @@ func (s *Sessions) DropAll(c *fiber.Ctx) error {
 		return ErrInvalidCommonPayload
 	}
 
-	if err := s.sSer.DropAll(p.UserID, "", ""); err != nil {
+	if err := s.sSer.DropAll(c.UserContext(), p.UserID, p.DeviceInfo); err != nil {
 		hub.CaptureException(err)
 		return err
 	}
 
 	return c.SendStatus(fiber.StatusOK)
 }
+
+// Collects info about the device request is made from. Headers are not
+// required here, callers which need them must check for emptiness
+func DeviceFromRequest(c *fiber.Ctx) model.DeviceInfo {
+	return model.DeviceInfo{
+		Uagent: c.Get(fiber.HeaderUserAgent),
+		Fprint: c.Get(constant.HeaderFingerprint),
+		IP:     c.IP(),
+	}
+}
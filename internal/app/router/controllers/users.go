@@ -1,33 +1,60 @@
 package controller
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	manager "wildproject/internal/app/domain/managers"
 	model "wildproject/internal/app/domain/models"
 	service "wildproject/internal/app/domain/services"
 	constant "wildproject/internal/app/router/constants"
 	"wildproject/internal/app/utils"
+	"wildproject/internal/imaging"
+	"wildproject/internal/stamp"
 
 	"github.com/gofiber/contrib/fibersentry"
 	"github.com/gofiber/fiber/v2"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// Generated avatar changes with user's name if it shows initials, so it's
+// cached not for long
+const avatarCacheMaxAge = 60 * 60
+
 var (
-	ErrEmailNotValid    = fiber.NewError(fiber.StatusBadRequest, "email is not valid")
-	ErrPasswordTooSmall = fiber.NewError(fiber.StatusBadRequest, "password cannot be shorter 8 symbols")
-	ErrPasswordTooLong  = fiber.NewError(fiber.StatusBadRequest, "password cannot be longer 72 symbols")
+	ErrEmailNotValid   = fiber.NewError(fiber.StatusBadRequest, "email is not valid")
+	ErrPasswordTooLong = fiber.NewError(fiber.StatusBadRequest, "password cannot be longer 72 bytes")
+	ErrWrongPassword   = fiber.NewError(fiber.StatusBadRequest, "wrong password")
+
+	ErrAvatarNotPassed         = fiber.NewError(fiber.StatusBadRequest, "avatar file is required")
+	ErrAvatarUnsupportedSize   = fiber.NewError(fiber.StatusBadRequest, "avatar size is not supported")
+	ErrAvatarUnsupportedFormat = fiber.NewError(fiber.StatusBadRequest, "avatar format is not supported, expected svg or png")
+
+	ErrUserNotFound        = fiber.NewError(fiber.StatusNotFound, "user not found")
+	ErrEmailChangeNotFound = fiber.NewError(fiber.StatusNotFound, "email change not found, probably confirmed or canceled")
+
+	ErrEmailChangeExpired = fiber.NewError(fiber.StatusGone, "email change is expired, request it again")
+
+	ErrAvatarTooLarge      = fiber.NewError(fiber.StatusRequestEntityTooLarge, "avatar file is too large")
+	ErrAvatarTooManyPixels = fiber.NewError(fiber.StatusRequestEntityTooLarge, "avatar image dimensions are too large")
 
-	ErrUserNotFound = fiber.NewError(fiber.StatusNotFound, "user not found")
+	ErrAvatarUnsupportedType = fiber.NewError(fiber.StatusUnsupportedMediaType, "avatar file type is not supported")
 
 	ErrUserExists = fiber.NewError(fiber.StatusConflict, "user already exists")
+	ErrEmailTaken = fiber.NewError(fiber.StatusConflict, "email is already taken")
 )
 
 type Users struct {
-	s service.UsersService
+	cfg *model.AvatarConfig
+	s   service.UsersService
 }
 
-func NewUsers(s service.UsersService) *Users {
-	return &Users{s}
+func NewUsers(cfg *model.AvatarConfig, s service.UsersService) *Users {
+	return &Users{cfg, s}
 }
 
 func (u *Users) GetInfo(c *fiber.Ctx) error {
@@ -38,7 +65,7 @@ func (u *Users) GetInfo(c *fiber.Ctx) error {
 		return ErrInvalidCommonPayload
 	}
 
-	user, err := u.s.FindDetailedByID(p.UserID)
+	user, err := u.s.FindDetailedByID(c.UserContext(), p.UserID)
 	if err != nil {
 		if errors.Is(err, service.ErrNotFound) {
 			return ErrUserNotFound
@@ -72,12 +99,13 @@ func (u *Users) Create(c *fiber.Ctx) error {
 		return ErrEmailNotValid
 	}
 
-	if len(body.Password) < 8 {
-		return ErrPasswordTooSmall
-	}
-
-	userID, err := u.s.Create(body.Email, body.Password)
+	userID, err := u.s.Create(c.UserContext(), body.Email, body.Password, DeviceFromRequest(c))
 	if err != nil {
+		var policyErr *manager.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			return ErrPasswordPolicy(c, policyErr)
+		}
+
 		if errors.Is(err, bcrypt.ErrPasswordTooLong) {
 			return ErrPasswordTooLong
 		}
@@ -109,7 +137,7 @@ func (u *Users) ChangeName(c *fiber.Ctx) error {
 		return ErrInvalidCommonPayload
 	}
 
-	name, err := u.s.ChangeName(p.UserID, request.Name)
+	name, err := u.s.ChangeName(c.UserContext(), p.UserID, request.Name)
 	if err != nil {
 		return err
 	}
@@ -135,7 +163,7 @@ func (u *Users) ChangeSex(c *fiber.Ctx) error {
 		return ErrInvalidCommonPayload
 	}
 
-	sexID, err := u.s.ChangeSex(p.UserID, request.SexID)
+	sexID, err := u.s.ChangeSex(c.UserContext(), p.UserID, request.SexID)
 	if err != nil {
 		return err
 	}
@@ -149,7 +177,10 @@ type changeEmailRequest struct {
 	Email string `json:"email"`
 }
 
+// Requests email change, which is applied once confirmed from the new address
 func (u *Users) ChangeEmail(c *fiber.Ctx) error {
+	hub := fibersentry.GetHubFromContext(c)
+
 	var request changeEmailRequest
 
 	if err := c.BodyParser(&request); err != nil {
@@ -165,8 +196,52 @@ func (u *Users) ChangeEmail(c *fiber.Ctx) error {
 		return ErrInvalidCommonPayload
 	}
 
-	email, err := u.s.ChangeEmail(p.UserID, request.Email)
+	change, err := u.s.ChangeEmail(c.UserContext(), p.UserID, request.Email, p.DeviceInfo)
+	if err != nil {
+		if errors.Is(err, service.ErrAlreadyExists) {
+			return ErrEmailTaken
+		}
+
+		if errors.Is(err, service.ErrNotFound) {
+			return ErrUserNotFound
+		}
+
+		hub.CaptureException(err)
+		return err
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(change)
+}
+
+type emailChangeTokenRequest struct {
+	Token string `json:"token"`
+}
+
+// Applies email change by the token from the confirmation email
+func (u *Users) ConfirmEmail(c *fiber.Ctx) error {
+	hub := fibersentry.GetHubFromContext(c)
+
+	var request emailChangeTokenRequest
+
+	if err := c.BodyParser(&request); err != nil {
+		return ErrInvalidBody(err)
+	}
+
+	email, err := u.s.ConfirmEmail(c.UserContext(), request.Token, DeviceFromRequest(c))
 	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			return ErrEmailChangeNotFound
+		}
+
+		if errors.Is(err, service.ErrEmailChangeExpired) {
+			return ErrEmailChangeExpired
+		}
+
+		if errors.Is(err, service.ErrAlreadyExists) {
+			return ErrEmailTaken
+		}
+
+		hub.CaptureException(err)
 		return err
 	}
 
@@ -175,6 +250,29 @@ func (u *Users) ChangeEmail(c *fiber.Ctx) error {
 	})
 }
 
+// Discards pending email change by the token from the notice sent
+// to the old address
+func (u *Users) CancelEmailChange(c *fiber.Ctx) error {
+	hub := fibersentry.GetHubFromContext(c)
+
+	var request emailChangeTokenRequest
+
+	if err := c.BodyParser(&request); err != nil {
+		return ErrInvalidBody(err)
+	}
+
+	if err := u.s.CancelEmailChange(c.UserContext(), request.Token, DeviceFromRequest(c)); err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			return ErrEmailChangeNotFound
+		}
+
+		hub.CaptureException(err)
+		return err
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
 type changePasswordRequest struct {
 	Password string `json:"password"`
 }
@@ -186,17 +284,22 @@ func (u *Users) ChangePassword(c *fiber.Ctx) error {
 		return ErrInvalidBody(err)
 	}
 
-	if len(request.Password) < 8 {
-		return ErrPasswordTooSmall
-	}
-
 	p, ok := c.Locals(constant.LocalKeyCommon).(model.CommonRequestPayload)
 	if !ok {
 		return ErrInvalidCommonPayload
 	}
 
-	err := u.s.ChangePassword(p.UserID, request.Password)
+	err := u.s.ChangePassword(c.UserContext(), p.UserID, p.SessionID, request.Password, p.DeviceInfo)
 	if err != nil {
+		var policyErr *manager.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			return ErrPasswordPolicy(c, policyErr)
+		}
+
+		if errors.Is(err, service.ErrNotFound) {
+			return ErrUserNotFound
+		}
+
 		if errors.Is(err, bcrypt.ErrPasswordTooLong) {
 			return ErrPasswordTooLong
 		}
@@ -207,10 +310,160 @@ func (u *Users) ChangePassword(c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusOK)
 }
 
+type changeImageResponse struct {
+	Avatar []model.ImageVariant `json:"avatar"`
+}
+
+// Accepts avatar as "avatar" field of multipart form
 func (u *Users) ChangeImage(c *fiber.Ctx) error {
-	return fiber.ErrNotImplemented
+	hub := fibersentry.GetHubFromContext(c)
+
+	p, ok := c.Locals(constant.LocalKeyCommon).(model.CommonRequestPayload)
+	if !ok {
+		return ErrInvalidCommonPayload
+	}
+
+	header, err := c.FormFile(constant.FormKeyAvatar)
+	if err != nil {
+		return ErrAvatarNotPassed
+	}
+
+	if header.Size > u.cfg.MaxSize {
+		return ErrAvatarTooLarge
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Don't trust client's Content-Type, detect it by the content
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrInvalidBody(err)
+	}
+	head = head[:n]
+
+	contentType := http.DetectContentType(head)
+	if !slices.Contains(u.cfg.AllowedTypes, contentType) {
+		return ErrAvatarUnsupportedType
+	}
+
+	img := io.MultiReader(bytes.NewReader(head), file)
+
+	variants, err := u.s.ChangeImage(c.UserContext(), p.UserID, img)
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			return ErrUserNotFound
+		}
+
+		if errors.Is(err, manager.ErrAvatarTooLarge) {
+			return ErrAvatarTooLarge
+		}
+
+		if errors.Is(err, imaging.ErrTooManyPixels) {
+			return ErrAvatarTooManyPixels
+		}
+
+		if errors.Is(err, imaging.ErrUnsupportedFormat) {
+			return ErrAvatarUnsupportedType
+		}
+
+		hub.CaptureException(err)
+		return err
+	}
+
+	return c.JSON(changeImageResponse{variants})
+}
+
+// Renders generated avatar of the user. It's available without authorization
+// to be used as is in <img> and is cached by clients revalidating with ETag
+func (u *Users) GetDefaultAvatar(c *fiber.Ctx) error {
+	hub := fibersentry.GetHubFromContext(c)
+
+	if len(u.cfg.Sizes) == 0 {
+		return ErrAvatarUnsupportedSize
+	}
+
+	userID := c.Params("user_id")
+	size := c.QueryInt("size", u.cfg.Sizes[len(u.cfg.Sizes)-1])
+	format := c.Query("format", manager.AvatarFormatSVG)
+
+	img, contentType, err := u.s.RenderDefaultImage(c.UserContext(), userID, size, format)
+	if err != nil {
+		// Unknown users are revealed only if the avatar shows initials
+		if errors.Is(err, service.ErrNotFound) {
+			return ErrUserNotFound
+		}
+
+		if errors.Is(err, manager.ErrAvatarUnsupportedSize) {
+			return ErrAvatarUnsupportedSize
+		}
+
+		if errors.Is(err, manager.ErrAvatarUnsupportedFormat) {
+			return ErrAvatarUnsupportedFormat
+		}
+
+		hub.CaptureException(err)
+		return err
+	}
+
+	sum := sha256.Sum256(img)
+	etag := fmt.Sprintf(`"%x"`, sum[:16])
+
+	c.Set(fiber.HeaderCacheControl, fmt.Sprintf("public, max-age=%d", avatarCacheMaxAge))
+	c.Set(fiber.HeaderETag, etag)
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+
+	if c.Get(fiber.HeaderIfNoneMatch) == etag {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	c.Set(fiber.HeaderContentType, contentType)
+	return c.Send(img)
+}
+
+type deleteRequest struct {
+	Password string `json:"password"`
+}
+
+type deleteResponse struct {
+	DeleteAfter stamp.Stamp `json:"delete_after"`
 }
 
+// Schedules account deletion and drops all user's sessions.
+// Login before "delete_after" cancels the deletion
 func (u *Users) Delete(c *fiber.Ctx) error {
-	return fiber.ErrNotImplemented
+	hub := fibersentry.GetHubFromContext(c)
+
+	var request deleteRequest
+
+	if err := c.BodyParser(&request); err != nil {
+		return ErrInvalidBody(err)
+	}
+
+	p, ok := c.Locals(constant.LocalKeyCommon).(model.CommonRequestPayload)
+	if !ok {
+		return ErrInvalidCommonPayload
+	}
+
+	deleteAfter, err := u.s.Delete(c.UserContext(), p.UserID, request.Password, p.DeviceInfo)
+	if err != nil {
+		if errors.Is(err, service.ErrPasswordsMismatch) {
+			return ErrWrongPassword
+		}
+
+		if errors.Is(err, service.ErrNotFound) {
+			return ErrUserNotFound
+		}
+
+		hub.CaptureException(err)
+		return err
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(deleteResponse{
+		DeleteAfter: stamp.Stamp{Time: deleteAfter},
+	})
 }
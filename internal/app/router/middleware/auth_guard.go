@@ -13,6 +13,7 @@ import (
 	"github.com/getsentry/sentry-go"
 	"github.com/gofiber/contrib/fibersentry"
 	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
 )
 
 var (
@@ -28,12 +29,17 @@ var (
 type tokenValidatorFn func(t string) (model.TokenPayload, error)
 
 type AuthGuard struct {
-	s  service.SessionsService
-	tm manager.TokenManager
+	s     service.SessionsService
+	tm    manager.TokenManager
+	audit service.AuditRecorder
 }
 
-func NewAuthGuard(s service.SessionsService, tm manager.TokenManager) *AuthGuard {
-	return &AuthGuard{s, tm}
+func NewAuthGuard(
+	s service.SessionsService,
+	tm manager.TokenManager,
+	ar service.AuditRecorder,
+) *AuthGuard {
+	return &AuthGuard{s, tm, ar}
 }
 
 func (a *AuthGuard) RefreshGuard(c *fiber.Ctx) error {
@@ -52,23 +58,33 @@ func (a *AuthGuard) validate(c *fiber.Ctx, fn tokenValidatorFn) error {
 		return err
 	}
 
+	device := controller.DeviceFromRequest(c)
+
 	tokenPayload, err := fn(accessToken)
 	if err != nil {
+		// Expiration is a regular flow, anything else is tampering or a bug
+		if !errors.Is(err, jwt.ErrTokenExpired) {
+			a.audit.Record(c.UserContext(), model.AuditEvent{
+				Type:     model.AuditInvalidAccessToken,
+				IP:       device.IP,
+				Uagent:   device.Uagent,
+				Metadata: map[string]string{"reason": err.Error()},
+			})
+		}
+
 		hub.CaptureException(err)
 		return fiber.NewError(fiber.StatusUnauthorized, err.Error())
 	}
 
-	uagent := c.Get(fiber.HeaderUserAgent)
-	if uagent == "" {
+	if device.Uagent == "" {
 		return ErrUserAgentNotPassed
 	}
 
-	fprint := c.Get(constant.HeaderFingerprint)
-	if fprint == "" {
+	if device.Fprint == "" {
 		return ErrFingerprintNotPassed
 	}
 
-	err = a.s.Validate(tokenPayload.SessionID, accessToken, uagent, fprint)
+	err = a.s.Validate(c.UserContext(), tokenPayload.SessionID, accessToken, device)
 	if err != nil {
 		if errors.Is(err, service.ErrNotFound) {
 			return controller.ErrInvalidToken
@@ -80,10 +96,7 @@ func (a *AuthGuard) validate(c *fiber.Ctx, fn tokenValidatorFn) error {
 
 	c.Locals(constant.LocalKeyCommon, model.CommonRequestPayload{
 		TokenPayload: tokenPayload,
-		DeviceInfo: model.DeviceInfo{
-			Uagent: uagent,
-			Fprint: fprint,
-		},
+		DeviceInfo:   device,
 	})
 
 	hub.Scope().SetUser(sentry.User{
@@ -94,7 +107,7 @@ func (a *AuthGuard) validate(c *fiber.Ctx, fn tokenValidatorFn) error {
 	})
 
 	hub.Scope().SetTags(map[string]string{
-		"User-Agent": uagent,
+		"User-Agent": device.Uagent,
 	})
 
 	return c.Next()
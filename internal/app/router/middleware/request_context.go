@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"github.com/gofiber/contrib/fibersentry"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Limits request's context to the handler's lifetime and "timeout", so
+// queries of the timed out requests are canceled. Must be used after
+// the sentry middleware: the request is traced as a transaction on its
+// hub, so the queries are reported as its spans.
+//
+// Client disconnects are not propagated: fasthttp notices a closed
+// connection only when the response is written, so queries of the gone
+// client run until the handler returns or "timeout" expires
+func RequestContext(timeout time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var ctx context.Context
+		var cancel context.CancelFunc
+
+		if timeout > 0 {
+			ctx, cancel = context.WithTimeout(c.UserContext(), timeout)
+		} else {
+			ctx, cancel = context.WithCancel(c.UserContext())
+		}
+		defer cancel()
+
+		hub := fibersentry.GetHubFromContext(c)
+		if hub == nil {
+			c.SetUserContext(ctx)
+			return c.Next()
+		}
+
+		ctx = sentry.SetHubOnContext(ctx, hub)
+
+		// Continues the trace of the caller, if any
+		tx := sentry.StartTransaction(
+			ctx,
+			c.Method()+" "+c.Path(),
+			sentry.WithOpName("http.server"),
+			sentry.ContinueFromHeaders(c.Get(sentry.SentryTraceHeader), c.Get(sentry.SentryBaggageHeader)),
+		)
+		defer tx.Finish()
+
+		c.SetUserContext(tx.Context())
+
+		err := c.Next()
+
+		// Route is known only after routing, it groups requests better
+		// than the path with ids
+		tx.Name = c.Method() + " " + c.Route().Path
+		tx.Source = sentry.SourceRoute
+		tx.Status = sentry.HTTPtoSpanStatus(statusCode(c, err))
+
+		return err
+	}
+}
+
+// Returned errors are turned into the response by the error handler,
+// which runs after the middlewares
+func statusCode(c *fiber.Ctx, err error) int {
+	if err == nil {
+		return c.Response().StatusCode()
+	}
+
+	var ferr *fiber.Error
+	if errors.As(err, &ferr) {
+		return ferr.Code
+	}
+
+	return fiber.StatusInternalServerError
+}
+
+// Lifts the "timeout" of RequestContext for the long-lived routes, such
+// as event streams. Request's context is still canceled once the handler
+// returns, values (e.g. Sentry hub and transaction) are kept
+func WithoutTimeout(c *fiber.Ctx) error {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(c.UserContext()))
+	defer cancel()
+
+	c.SetUserContext(ctx)
+
+	return c.Next()
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"slices"
+	model "wildproject/internal/app/domain/models"
+	service "wildproject/internal/app/domain/services"
+	constant "wildproject/internal/app/router/constants"
+	controller "wildproject/internal/app/router/controllers"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var (
+	ErrRoleRequired = fiber.NewError(fiber.StatusForbidden, "role required by the route is not granted")
+)
+
+type RoleGuard struct {
+	s service.RolesService
+}
+
+func NewRoleGuard(s service.RolesService) *RoleGuard {
+	return &RoleGuard{s}
+}
+
+// Lets only the users granted the role through. Must be used after
+// the AccessGuard, which identifies the user
+func (g *RoleGuard) Require(role string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		p, ok := c.Locals(constant.LocalKeyCommon).(model.CommonRequestPayload)
+		if !ok {
+			return controller.ErrInvalidCommonPayload
+		}
+
+		roles, err := g.s.FindAll(c.UserContext(), p.UserID)
+		if err != nil {
+			return err
+		}
+
+		if !slices.Contains(roles, role) {
+			return ErrRoleRequired
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	model "wildproject/internal/app/domain/models"
+	constant "wildproject/internal/app/router/constants"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type stubRoles map[string][]string
+
+func (s stubRoles) Grant(ctx context.Context, userID string, roles []string, device model.DeviceInfo) ([]string, error) {
+	return nil, nil
+}
+
+func (s stubRoles) FindAll(ctx context.Context, userID string) ([]string, error) {
+	return s[userID], nil
+}
+
+func TestRoleGuard(t *testing.T) {
+	guard := NewRoleGuard(stubRoles{"admin": {"support", "admin"}, "support": {"support"}})
+
+	tests := []struct {
+		userID string
+		want   int
+	}{
+		{"admin", fiber.StatusOK},
+		{"support", fiber.StatusForbidden},
+		{"nobody", fiber.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.userID, func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/", func(c *fiber.Ctx) error {
+				c.Locals(constant.LocalKeyCommon, model.CommonRequestPayload{
+					TokenPayload: model.TokenPayload{UserID: tt.userID},
+				})
+
+				return c.Next()
+			}, guard.Require("admin"), func(c *fiber.Ctx) error {
+				return c.SendStatus(fiber.StatusOK)
+			})
+
+			resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.StatusCode != tt.want {
+				t.Fatalf("expected %d, got %d", tt.want, resp.StatusCode)
+			}
+		})
+	}
+}
@@ -1,12 +1,15 @@
 package router
 
 import (
+	"net/url"
 	"time"
-	"wildproject/internal/app/data/database"
 	repo "wildproject/internal/app/data/repositories"
+	"wildproject/internal/app/data/storage"
+	event "wildproject/internal/app/domain/events"
 	manager "wildproject/internal/app/domain/managers"
 	model "wildproject/internal/app/domain/models"
 	service "wildproject/internal/app/domain/services"
+	"wildproject/internal/app/mail"
 	controller "wildproject/internal/app/router/controllers"
 	"wildproject/internal/app/router/middleware"
 
@@ -16,27 +19,69 @@ import (
 )
 
 type Router struct {
-	app *fiber.App
-	cfg *model.Config
+	app       *fiber.App
+	cfg       *model.Config
+	readiness *controller.Readiness
+	events    *controller.Events
 }
 
 func NewRouter(app *fiber.App, cfg *model.Config) Router {
-	return Router{app, cfg}
+	return Router{app: app, cfg: cfg, readiness: controller.NewReadiness()}
 }
 
-func (r *Router) Setup(db database.Instance) error {
-	log.Info("Setting up router")
-
-	tm := manager.NewJwtManager(r.cfg.Auth.AuthJwtSecret, r.cfg.Auth.AccessTokenTTL)
+// Prepares the routes for shutdown: readiness starts failing and
+// event streams are closed, as they would hold the shutdown forever
+func (r *Router) Drain() {
+	r.readiness.Drain()
 
-	ur := repo.NewUsers(db)
-	sr := repo.NewSessions(db)
+	if r.events != nil {
+		r.events.Close()
+	}
+}
 
-	us := service.NewUsers(ur)
-	ss := service.NewSessions(&r.cfg.Auth, sr, tm)
+func (r *Router) Setup(
+	repos repo.Repositories,
+	uow repo.UnitOfWork,
+	bc manager.BreachChecker,
+	st storage.Storage,
+	pst storage.Objects,
+	m mail.Mailer,
+	ps event.PubSub,
+	tm manager.TokenManager,
+) error {
+	log.Info("Setting up router")
 
-	uc := controller.NewUsers(us)
+	pp := manager.NewPasswordPolicy(&r.cfg.Password, bc)
+	am := manager.NewAvatarProcessor(&r.cfg.Avatar, st)
+	scache := manager.NewSessionCache(&r.cfg.Auth, ps)
+
+	ph, err := manager.NewPasswordHasher(&r.cfg.Password)
+	if err != nil {
+		return err
+	}
+
+	ur := repos.Users
+	sr := repos.Sessions
+	hr := repos.PasswordHistory
+	er := repos.DataExports
+	ar := repos.AuditEvents
+	cr := repos.EmailChanges
+	ror := repos.UserRoles
+
+	as := service.NewAudit(ar, []byte(r.cfg.Auth.AuthJwtSecret))
+	us := service.NewUsers(&r.cfg.Users, &r.cfg.Password, ur, hr, pp, ph, am, cr, m, as, ps, uow)
+	ss := service.NewSessions(&r.cfg.Auth, sr, tm, as, ps, scache, uow)
+	es := service.NewDataExports(&r.cfg.Export, er, pst, ps)
+	ros := service.NewRoles(&r.cfg.Users, ror, ur, as)
+
+	uc := controller.NewUsers(&r.cfg.Avatar, us)
 	sc := controller.NewSessions(ss, us)
+	ec := controller.NewDataExports(es)
+	ac := controller.NewAudit(as)
+	roc := controller.NewRoles(ros)
+	evc := controller.NewEvents(ps)
+	r.events = evc
+	mc := controller.NewMetrics(scache)
 
 	// Setup middlewares
 	sentryMiddleware := fibersentry.New(fibersentry.Config{
@@ -44,22 +89,42 @@ func (r *Router) Setup(db database.Instance) error {
 		Timeout:         10 * time.Second,
 	})
 
-	authGuard := middleware.NewAuthGuard(ss, tm)
+	authGuard := middleware.NewAuthGuard(ss, tm, as)
+	roleGuard := middleware.NewRoleGuard(ros)
 
 	// Setup routes
 	r.app.Use(sentryMiddleware)
+	r.app.Use(middleware.RequestContext(r.cfg.Server.WriteTimeout))
+
+	// Local storage objects are served by the app itself
+	if r.cfg.Storage.Driver == storage.DriverLocal {
+		publicURL, err := url.Parse(r.cfg.Storage.PublicURL)
+		if err != nil {
+			return err
+		}
+
+		r.app.Static(publicURL.Path, r.cfg.Storage.LocalDir)
+	}
 
 	api := r.app.Group("/api")
 	api.Get("/health", controller.HealthCheck)
+	api.Get("/ready", r.readiness.Get)
+	api.Get("/metrics", authGuard.AccessGuard, roleGuard.Require(service.RoleAdmin), mc.Get)
 
 	v1 := api.Group("/v1")
 
 	// Unprotected routes
 	unUsers := v1.Group("/users")
 	unUsers.Post("/", uc.Create)
+	unUsers.Get("/:user_id<guid>/avatar", uc.GetDefaultAvatar)
+	unUsers.Post("/email/confirm", uc.ConfirmEmail)
+	unUsers.Post("/email/cancel", uc.CancelEmailChange)
 
 	unUser := unUsers.Group("/me")
 
+	unExports := v1.Group("/exports")
+	unExports.Get("/:token", ec.Download)
+
 	unSessions := unUser.Group("/sessions")
 	unSessions.Post("/", sc.Create)
 	unSessions.Put("/", authGuard.RefreshGuard, sc.Refresh)
@@ -78,6 +143,13 @@ func (r *Router) Setup(db database.Instance) error {
 	user.Put("/password", uc.ChangePassword)
 	user.Put("/avatar", uc.ChangeImage)
 
+	user.Post("/export", ec.Create)
+	user.Get("/export/:export_id<guid>", ec.GetByID)
+
+	user.Get("/security-events", ac.GetSecurityEvents)
+	user.Get("/roles", roc.GetAll)
+	user.Get("/events", middleware.WithoutTimeout, evc.Stream)
+
 	sessions := user.Group("/sessions")
 	sessions.Get("/", sc.GetAllByUserID)
 	sessions.Delete("/", sc.DropAll)
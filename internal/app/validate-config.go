@@ -0,0 +1,274 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"wildproject/internal/app/data/database"
+	repo "wildproject/internal/app/data/repositories"
+	"wildproject/internal/app/data/storage"
+	event "wildproject/internal/app/domain/events"
+	manager "wildproject/internal/app/domain/managers"
+	model "wildproject/internal/app/domain/models"
+	service "wildproject/internal/app/domain/services"
+	job "wildproject/internal/app/jobs"
+	"wildproject/internal/app/mail"
+)
+
+// HS256 key should be at least as long as the hash output
+const minJwtSecretLength = 32
+
+var ErrInvalidConfig = errors.New("invalid config")
+
+// Checks the resolved config. Problems are reported by their YAML key
+// all at once, so they don't have to be fixed one restart at a time
+func validateConfig(cfg *model.Config) error {
+	v := &configErrors{}
+
+	v.check(cfg.Name != "", "name", "is required")
+	v.check(cfg.Env != "", "env", "is required")
+
+	db := cfg.Database
+	v.database(db)
+
+	srv := cfg.Server
+	v.addr("server.addr", srv.Addr)
+	v.check(srv.ReadTimeout >= 0, "server.read_timeout", "must not be negative")
+	v.check(srv.WriteTimeout >= 0, "server.write_timeout", "must not be negative")
+	v.check(srv.IdleTimeout >= 0, "server.idle_timeout", "must not be negative")
+	v.check(srv.ShutdownDelay >= 0, "server.shutdown_delay", "must not be negative")
+	v.check(srv.ShutdownTimeout >= 0, "server.shutdown_timeout", "must not be negative")
+
+	auth := cfg.Auth
+	v.check(
+		len(auth.AuthJwtSecret) >= minJwtSecretLength,
+		"auth.jwt_secret", "must be at least %d bytes", minJwtSecretLength,
+	)
+	v.check(auth.AccessTokenTTL > 0, "auth.access_token_ttl", "must be positive")
+	v.check(
+		auth.RefreshTokenTTL > auth.AccessTokenTTL,
+		"auth.refresh_token_ttl", "must be longer than auth.access_token_ttl",
+	)
+	v.check(auth.SessionCacheSize >= 0, "auth.session_cache_size", "must not be negative")
+	if auth.SessionCacheSize > 0 {
+		v.check(auth.SessionCacheTTL > 0, "auth.session_cache_ttl", "must be positive when cache is enabled")
+		// Cached session outliving the token keeps the token valid
+		// after the session is dropped on another instance
+		v.check(
+			auth.SessionCacheTTL <= auth.AccessTokenTTL,
+			"auth.session_cache_ttl", "must not exceed auth.access_token_ttl",
+		)
+	}
+
+	sess := cfg.Sessions
+	v.oneOf("sessions.driver", sess.Driver, repo.SessionsDriverDatabase, repo.SessionsDriverRedis)
+	if sess.Driver == repo.SessionsDriverRedis {
+		v.addr("sessions.redis_addr", sess.RedisAddr)
+		v.check(sess.RedisDB >= 0, "sessions.redis_db", "must not be negative")
+		v.check(sess.RedisExpiredRetention >= 0, "sessions.redis_expired_retention", "must not be negative")
+	}
+
+	pwd := cfg.Password
+	v.check(pwd.MinLength > 0, "password.min_length", "must be positive")
+	v.check(pwd.MaxLength >= pwd.MinLength, "password.max_length", "must not be less than password.min_length")
+	v.check(pwd.MaxRepeated >= 0, "password.max_repeated", "must not be negative")
+	v.check(pwd.MinStrength >= 0 && pwd.MinStrength <= 4, "password.min_strength", "must be in 0..4")
+	v.check(pwd.HistorySize >= 0, "password.history_size", "must not be negative")
+	v.oneOf("password.hash_algorithm", pwd.HashAlgorithm, manager.HashAlgorithmArgon2id, manager.HashAlgorithmBcrypt)
+	switch pwd.HashAlgorithm {
+	case manager.HashAlgorithmArgon2id:
+		v.check(pwd.Argon2Time > 0, "password.argon2_time", "must be positive")
+		v.check(pwd.Argon2Threads > 0, "password.argon2_threads", "must be positive")
+		// Argon2 requires at least 8 KiB per thread
+		v.check(
+			pwd.Argon2Memory >= 8*uint32(pwd.Argon2Threads),
+			"password.argon2_memory", "must be at least 8 KiB per thread",
+		)
+	case manager.HashAlgorithmBcrypt:
+		v.check(pwd.BcryptCost >= 4 && pwd.BcryptCost <= 31, "password.bcrypt_cost", "must be in 4..31")
+	}
+
+	users := cfg.Users
+	v.check(users.DeletionGracePeriod >= 0, "users.deletion_grace_period", "must not be negative")
+	v.oneOf("users.deletion_mode", users.DeletionMode, job.DeletionModeAnonymize, job.DeletionModeDelete)
+	v.check(users.DeletionInterval > 0, "users.deletion_interval", "must be positive")
+	v.check(users.EmailChangeTTL > 0, "users.email_change_ttl", "must be positive")
+	v.template("users.email_confirm_url", users.EmailConfirmURL, "{token}")
+	v.template("users.email_cancel_url", users.EmailCancelURL, "{token}")
+	for _, role := range users.Roles {
+		v.check(role != "" && !strings.ContainsAny(role, ", "), "users.roles", "invalid role %q", role)
+	}
+	v.check(
+		slices.Contains(users.Roles, service.RoleAdmin),
+		"users.roles", "must include %q, it guards the metrics", service.RoleAdmin,
+	)
+
+	st := cfg.Storage
+	v.url("storage.public_url", st.PublicURL)
+	v.oneOf("storage.driver", st.Driver, storage.DriverLocal, storage.DriverS3)
+	switch st.Driver {
+	case storage.DriverLocal:
+		v.check(st.LocalDir != "", "storage.local_dir", "is required by %s driver", st.Driver)
+		v.check(st.PrivateDir != "", "storage.private_dir", "is required by %s driver", st.Driver)
+		// Everything under the public dir is served
+		v.check(
+			st.LocalDir == "" || st.PrivateDir == "" || !isSubdir(st.LocalDir, st.PrivateDir),
+			"storage.private_dir", "must not be inside storage.local_dir",
+		)
+	case storage.DriverS3:
+		v.url("storage.s3_endpoint", st.S3Endpoint)
+		v.check(st.S3Region != "", "storage.s3_region", "is required by %s driver", st.Driver)
+		v.check(st.S3Bucket != "", "storage.s3_bucket", "is required by %s driver", st.Driver)
+		v.check(st.S3PrivateBucket != "", "storage.s3_private_bucket", "is required by %s driver", st.Driver)
+		v.check(
+			st.S3PrivateBucket != st.S3Bucket,
+			"storage.s3_private_bucket", "must differ from the public storage.s3_bucket",
+		)
+		v.check(st.S3AccessKey != "", "storage.s3_access_key", "is required by %s driver", st.Driver)
+		v.check(st.S3SecretKey != "", "storage.s3_secret_key", "is required by %s driver", st.Driver)
+	}
+
+	av := cfg.Avatar
+	v.check(av.MaxSize > 0, "avatar.max_size", "must be positive")
+	v.check(len(av.AllowedTypes) > 0, "avatar.allowed_types", "must not be empty")
+	v.check(av.MaxPixels > 0, "avatar.max_pixels", "must be positive")
+	v.check(len(av.Sizes) > 0, "avatar.sizes", "must not be empty")
+	for _, size := range av.Sizes {
+		v.check(size > 0, "avatar.sizes", "must be positive, got %d", size)
+	}
+	v.check(av.Quality >= 1 && av.Quality <= 100, "avatar.quality", "must be in 1..100")
+	v.template("avatar.default_url", av.DefaultURL, "{user_id}")
+
+	exp := cfg.Export
+	v.check(exp.LinkTTL > 0, "export.link_ttl", "must be positive")
+	v.check(exp.PollInterval > 0, "export.poll_interval", "must be positive")
+	v.template("export.download_url", exp.DownloadURL, "{token}")
+
+	m := cfg.Mail
+	v.check(m.From != "", "mail.from", "is required")
+	v.oneOf("mail.driver", m.Driver, mail.DriverLog, mail.DriverSMTP)
+	if m.Driver == mail.DriverSMTP {
+		v.check(m.SMTPHost != "", "mail.smtp_host", "is required by %s driver", m.Driver)
+		v.check(m.SMTPPort > 0 && m.SMTPPort <= 65535, "mail.smtp_port", "must be in 1..65535")
+	}
+
+	ev := cfg.Events
+	v.oneOf("events.driver", ev.Driver, event.DriverLocal, event.DriverPostgres)
+	if ev.Driver == event.DriverPostgres {
+		v.check(ev.Channel != "", "events.channel", "is required by %s driver", ev.Driver)
+		v.check(
+			db.Driver == database.DriverPostgres,
+			"events.driver", "%s requires %s database", ev.Driver, database.DriverPostgres,
+		)
+	}
+
+	sentry := cfg.Sentry
+	v.check(
+		sentry.TracesSampleRate >= 0 && sentry.TracesSampleRate <= 1,
+		"sentry.traces_sample_rate", "must be in 0..1",
+	)
+
+	return v.err()
+}
+
+// Checks only the database section, enough for the schema migrations
+func validateDatabaseConfig(cfg *model.Config) error {
+	v := &configErrors{}
+	v.database(cfg.Database)
+
+	return v.err()
+}
+
+type configErrors struct {
+	errs []error
+}
+
+func (v *configErrors) database(db model.DatabaseConfig) {
+	v.oneOf("database.driver", db.Driver, database.DriverPostgres, database.DriverMemory)
+	if db.Driver == database.DriverPostgres {
+		v.check(db.Conn != "", "database.conn_string", "is required by %s driver", db.Driver)
+	}
+	v.check(
+		!db.MigrateOnStart || db.Driver != database.DriverMemory,
+		"database.migrate_on_start", "is not supported by %s driver", database.DriverMemory,
+	)
+	v.check(db.QueryTimeout >= 0, "database.query_timeout", "must not be negative")
+}
+
+func (v *configErrors) err() error {
+	if len(v.errs) > 0 {
+		return fmt.Errorf("%w:\n%w", ErrInvalidConfig, errors.Join(v.errs...))
+	}
+
+	return nil
+}
+
+func (v *configErrors) check(ok bool, key, format string, args ...any) {
+	if !ok {
+		v.errs = append(v.errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+}
+
+func (v *configErrors) oneOf(key, value string, allowed ...string) {
+	v.check(
+		slices.Contains(allowed, value),
+		key, "must be one of %s, got %q", strings.Join(allowed, ", "), value,
+	)
+}
+
+// Checks "host:port" address, host may be omitted to listen on all
+// the interfaces
+func (v *configErrors) addr(key, value string) {
+	_, port, err := net.SplitHostPort(value)
+	if err != nil {
+		v.check(false, key, "must be host:port, got %q", value)
+		return
+	}
+
+	portNum, err := strconv.Atoi(port)
+	v.check(err == nil && portNum >= 0 && portNum <= 65535, key, "has invalid port %q", port)
+}
+
+// Checks absolute http(s) URL
+func (v *configErrors) url(key, value string) {
+	if value == "" {
+		v.check(false, key, "is required")
+		return
+	}
+
+	u, err := url.Parse(value)
+	v.check(
+		err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+		key, "must be absolute http(s) URL, got %q", value,
+	)
+}
+
+// Checks URL with the placeholder replaced on use
+func (v *configErrors) template(key, value, placeholder string) {
+	v.url(key, strings.ReplaceAll(value, placeholder, "placeholder"))
+
+	if value != "" {
+		v.check(strings.Contains(value, placeholder), key, "must contain %s", placeholder)
+	}
+}
+
+// Reports whether "dir" is "parent" or is inside it
+func isSubdir(parent, dir string) bool {
+	parent, err := filepath.Abs(parent)
+	if err != nil {
+		return false
+	}
+
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+
+	rel, err := filepath.Rel(parent, dir)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
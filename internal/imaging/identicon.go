@@ -0,0 +1,155 @@
+package imaging
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+// Identicon is 5x5 cells grid mirrored horizontally with a cell wide margin
+const (
+	identiconCells = 5
+	identiconGrid  = identiconCells + 1
+)
+
+var identiconBackground = color.RGBA{0xF0, 0xF0, 0xF0, 0xFF}
+
+// Cells filling and color derived from the seed
+type identicon struct {
+	cells [identiconCells][identiconCells]bool
+	color color.RGBA
+}
+
+func newIdenticon(seed string) identicon {
+	hash := sha256.Sum256([]byte(seed))
+
+	var icon identicon
+
+	// Only the left 3 columns are derived, the rest mirrors them
+	bit := 0
+	for x := 0; x < (identiconCells+1)/2; x++ {
+		for y := 0; y < identiconCells; y++ {
+			filled := hash[bit/8]&(1<<(bit%8)) != 0
+			icon.cells[y][x] = filled
+			icon.cells[y][identiconCells-1-x] = filled
+			bit++
+		}
+	}
+
+	icon.color = SeedColor(hash[len(hash)-2:])
+
+	return icon
+}
+
+// Renders GitHub-like identicon of the "size" side deterministic for the seed
+func Identicon(seed string, size int) image.Image {
+	icon := newIdenticon(seed)
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(identiconBackground), image.Point{}, draw.Src)
+
+	fg := image.NewUniform(icon.color)
+
+	for y, row := range icon.cells {
+		for x, filled := range row {
+			if !filled {
+				continue
+			}
+
+			rect := image.Rect(
+				cellOffset(x, size), cellOffset(y, size),
+				cellOffset(x+1, size), cellOffset(y+1, size),
+			)
+
+			draw.Draw(dst, rect, fg, image.Point{}, draw.Src)
+		}
+	}
+
+	return dst
+}
+
+// Same as Identicon, but rendered as SVG
+func IdenticonSVG(seed string, size int) []byte {
+	icon := newIdenticon(seed)
+
+	var b strings.Builder
+
+	fmt.Fprintf(
+		&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`,
+		size, size, identiconGrid*2, identiconGrid*2,
+	)
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="%s"/>`, hexColor(identiconBackground))
+	fmt.Fprintf(&b, `<g fill="%s">`, hexColor(icon.color))
+
+	for y, row := range icon.cells {
+		for x, filled := range row {
+			if filled {
+				fmt.Fprintf(&b, `<rect x="%d" y="%d" width="2" height="2"/>`, 1+x*2, 1+y*2)
+			}
+		}
+	}
+
+	b.WriteString(`</g></svg>`)
+
+	return []byte(b.String())
+}
+
+// Returns pixel offset of the cell border with half-cell margin
+func cellOffset(cell, size int) int {
+	return (2*cell + 1) * size / (identiconGrid * 2)
+}
+
+// Derives saturated, mid-lightness color from the two seed bytes
+func SeedColor(seed []byte) color.RGBA {
+	hue := float64(int(seed[0])<<8|int(seed[1])) / 65536 * 360
+	return hslToRGB(hue, 0.55, 0.5)
+}
+
+func hslToRGB(h, s, l float64) color.RGBA {
+	c := (1 - abs(2*l-1)) * s
+	x := c * (1 - abs(mod(h/60, 2)-1))
+	m := l - c/2
+
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g, b = c, x, 0
+	case h < 120:
+		r, g, b = x, c, 0
+	case h < 180:
+		r, g, b = 0, c, x
+	case h < 240:
+		r, g, b = 0, x, c
+	case h < 300:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+
+	return color.RGBA{
+		R: uint8((r + m) * 255),
+		G: uint8((g + m) * 255),
+		B: uint8((b + m) * 255),
+		A: 0xFF,
+	}
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+func abs(v float64) float64 {
+	if v < 0 {
+		return -v
+	}
+
+	return v
+}
+
+func mod(a, b float64) float64 {
+	return a - b*float64(int(a/b))
+}
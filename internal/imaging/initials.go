@@ -0,0 +1,123 @@
+package imaging
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// Font size relative to the avatar side
+const initialsFontScale = 0.42
+
+var (
+	initialsFont     *opentype.Font
+	initialsFontErr  error
+	initialsFontOnce sync.Once
+)
+
+// Returns up to two uppercased first letters of the first and the last words
+func Initials(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	if len(words) == 0 {
+		return ""
+	}
+
+	initials := []rune{firstRune(words[0])}
+	if len(words) > 1 {
+		initials = append(initials, firstRune(words[len(words)-1]))
+	}
+
+	return strings.ToUpper(string(initials))
+}
+
+// Renders initials in white over the background color derived from the seed
+func InitialsImage(seed, initials string, size int) (image.Image, error) {
+	face, err := initialsFace(float64(size) * initialsFontScale)
+	if err != nil {
+		return nil, err
+	}
+	defer face.Close()
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(initialsColor(seed)), image.Point{}, draw.Src)
+
+	drawer := font.Drawer{
+		Dst:  dst,
+		Src:  image.NewUniform(color.White),
+		Face: face,
+	}
+
+	metrics := face.Metrics()
+	width := drawer.MeasureString(initials)
+
+	// Center the text box between the ascent and descent lines
+	drawer.Dot = fixed.Point26_6{
+		X: (fixed.I(size) - width) / 2,
+		Y: (fixed.I(size) + metrics.Ascent - metrics.Descent) / 2,
+	}
+	drawer.DrawString(initials)
+
+	return dst, nil
+}
+
+// Same as InitialsImage, but rendered as SVG
+func InitialsSVG(seed, initials string, size int) []byte {
+	var b strings.Builder
+
+	fmt.Fprintf(
+		&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 100 100">`,
+		size, size,
+	)
+	fmt.Fprintf(&b, `<rect width="100%%" height="100%%" fill="%s"/>`, hexColor(initialsColor(seed)))
+	fmt.Fprintf(
+		&b, `<text x="50" y="50" dy=".35em" text-anchor="middle" fill="#ffffff" `+
+			`font-family="sans-serif" font-size="%.0f">%s</text>`,
+		100*initialsFontScale, html.EscapeString(initials),
+	)
+	b.WriteString(`</svg>`)
+
+	return []byte(b.String())
+}
+
+func initialsColor(seed string) color.RGBA {
+	hash := sha256.Sum256([]byte(seed))
+	return SeedColor(hash[:2])
+}
+
+func initialsFace(size float64) (font.Face, error) {
+	initialsFontOnce.Do(func() {
+		initialsFont, initialsFontErr = opentype.Parse(goregular.TTF)
+	})
+
+	if initialsFontErr != nil {
+		return nil, initialsFontErr
+	}
+
+	return opentype.NewFace(initialsFont, &opentype.FaceOptions{
+		Size:    size,
+		DPI:     72,
+		Hinting: font.HintingFull,
+	})
+}
+
+func firstRune(s string) rune {
+	for _, r := range s {
+		return r
+	}
+
+	return 0
+}
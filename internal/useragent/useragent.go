@@ -0,0 +1,158 @@
+package useragent
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+)
+
+// Rules are matched in order, so more specific ones go first
+// (e.g. Edge before Chrome, as Edge's user agent contains Chrome's one)
+//
+//go:embed rules.json
+var defaultRules []byte
+
+var defaultParser = mustNewParser(defaultRules)
+
+// Structured information about the client, fields are empty if unknown
+type Info struct {
+	Browser        string
+	BrowserVersion string
+	OS             string
+	OSVersion      string
+	DeviceType     string
+	// Model of the device, only known for our app
+	DeviceModel string
+	// Version of our app, if the request is made by it
+	AppVersion string
+}
+
+type rules struct {
+	Apps     []rule `json:"apps"`
+	Browsers []rule `json:"browsers"`
+	OS       []rule `json:"os"`
+	Devices  []rule `json:"devices"`
+}
+
+type rule struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Regex string `json:"regex"`
+
+	re *regexp.Regexp
+}
+
+// Parses user agent by the embedded rules
+func Parse(ua string) Info {
+	return defaultParser.Parse(ua)
+}
+
+type Parser struct {
+	rules rules
+}
+
+// Creates parser by the rules in JSON, see "rules.json" for the format
+func NewParser(data []byte) (*Parser, error) {
+	var r rules
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+
+	for _, set := range [][]rule{r.Apps, r.Browsers, r.OS, r.Devices} {
+		for i := range set {
+			re, err := regexp.Compile(set[i].Regex)
+			if err != nil {
+				return nil, fmt.Errorf("invalid rule %q: %w", set[i].Regex, err)
+			}
+
+			set[i].re = re
+		}
+	}
+
+	return &Parser{r}, nil
+}
+
+func mustNewParser(data []byte) *Parser {
+	p, err := NewParser(data)
+	if err != nil {
+		panic(err)
+	}
+
+	return p
+}
+
+func (p *Parser) Parse(ua string) Info {
+	if info, ok := p.parseApp(ua); ok {
+		return info
+	}
+
+	var info Info
+
+	if r, groups := match(p.rules.Browsers, ua); r != nil {
+		info.Browser = r.Name
+		info.BrowserVersion = version(groups["version"])
+	}
+
+	if r, groups := match(p.rules.OS, ua); r != nil {
+		info.OS = r.Name
+		info.OSVersion = version(groups["version"])
+	}
+
+	if r, _ := match(p.rules.Devices, ua); r != nil {
+		info.DeviceType = r.Type
+	}
+
+	return info
+}
+
+// Our app sends "Wild/<version> (<os> <os version>; <device model>)"
+func (p *Parser) parseApp(ua string) (Info, bool) {
+	r, groups := match(p.rules.Apps, ua)
+	if r == nil {
+		return Info{}, false
+	}
+
+	return Info{
+		Browser:     r.Name,
+		OS:          groups["os"],
+		OSVersion:   version(groups["os_version"]),
+		DeviceType:  DeviceMobile,
+		DeviceModel: groups["device"],
+		AppVersion:  groups["version"],
+	}, true
+}
+
+// Returns the first matching rule with its named groups
+func match(set []rule, ua string) (*rule, map[string]string) {
+	for i := range set {
+		m := set[i].re.FindStringSubmatch(ua)
+		if m == nil {
+			continue
+		}
+
+		groups := make(map[string]string)
+		for j, name := range set[i].re.SubexpNames() {
+			if name != "" {
+				groups[name] = m[j]
+			}
+		}
+
+		return &set[i], groups
+	}
+
+	return nil, nil
+}
+
+// Some platforms separate version parts with underscores (e.g. "10_15_7")
+func version(v string) string {
+	return strings.ReplaceAll(v, "_", ".")
+}
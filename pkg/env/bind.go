@@ -0,0 +1,278 @@
+package env
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotStructPointer = errors.New("env bind target is not a pointer to struct")
+	ErrRequired         = errors.New("required variable is not set")
+	ErrUnsupportedType  = errors.New("unsupported field type")
+	ErrUnitOverflow     = errors.New("value in its unit overflows")
+)
+
+var (
+	durationType        = reflect.TypeOf(time.Duration(0))
+	urlType             = reflect.TypeOf(url.URL{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Sets struct fields from the variables named by their tags:
+//
+//	type Config struct {
+//		Addr    string        `env:"ADDR" default:":8000"`
+//		Secret  string        `env:"SECRET,required"`
+//		Timeout time.Duration `env:"TIMEOUT" default:"5s"`
+//		TTL     time.Duration `env:"TTL" unit:"1m"`
+//		DB      DBConfig      `envPrefix:"DB_"`
+//	}
+//
+// Fields without the tag are left as is, nested structs are bound
+// recursively with the optional prefix. Nil struct pointer is set only
+// if any of its variables is, defaults alone don't set it. Unset variables keep the
+// current value unless there is a default. Slices are comma separated.
+//
+// Plain integer given to a field with "unit" is the count of units, e.g.
+// TTL=15 is 15 minutes above. Durations are accepted as Go durations too,
+// and integer fields take the unit as a factor, e.g. `unit:"1024"`.
+//
+// Unlike the other functions, it doesn't panic: all the problems are
+// returned at once
+func Bind(dst any) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return ErrNotStructPointer
+	}
+
+	errs := make([]error, 0)
+	bindStruct(v.Elem(), "", &errs)
+
+	return errors.Join(errs...)
+}
+
+// Reports whether any of the variables is set
+func bindStruct(v reflect.Value, prefix string, errs *[]error) bool {
+	t := v.Type()
+	set := false
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		fv := v.Field(i)
+
+		tag, ok := field.Tag.Lookup("env")
+		if !ok {
+			nestedPrefix := prefix + field.Tag.Get("envPrefix")
+
+			switch {
+			case isNested(field.Type):
+				set = bindStruct(fv, nestedPrefix, errs) || set
+			case field.Type.Kind() == reflect.Pointer && isNested(field.Type.Elem()):
+				ptr := fv
+				if fv.IsNil() {
+					ptr = reflect.New(field.Type.Elem())
+				}
+
+				if bindStruct(ptr.Elem(), nestedPrefix, errs) {
+					fv.Set(ptr)
+					set = true
+				}
+			}
+
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		key := prefix + name
+
+		val, found, err := Lookup(key)
+		if err != nil {
+			*errs = append(*errs, err)
+			continue
+		}
+
+		set = set || found
+
+		if !found {
+			if opts == "required" {
+				*errs = append(*errs, fmt.Errorf("env %s: %w", key, ErrRequired))
+				continue
+			}
+
+			val, found = field.Tag.Lookup("default")
+			if !found {
+				continue
+			}
+		}
+
+		if unit, ok := field.Tag.Lookup("unit"); ok {
+			val, err = applyUnit(fv.Type(), val, unit)
+			if err != nil {
+				*errs = append(*errs, fmt.Errorf("env %s: %w", key, err))
+				continue
+			}
+		}
+
+		if err := setValue(fv, val); err != nil {
+			*errs = append(*errs, fmt.Errorf("env %s: %w", key, err))
+		}
+	}
+
+	return set
+}
+
+// Converts plain integer to the value of "unit" multiplied by it, other
+// values are returned as is
+func applyUnit(t reflect.Type, val, unit string) (string, error) {
+	n, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return val, nil
+	}
+
+	if t == durationType {
+		d, err := time.ParseDuration(unit)
+		if err != nil {
+			return "", fmt.Errorf("unit %q: %w", unit, err)
+		}
+
+		scaled, ok := multiply(n, int64(d))
+		if !ok {
+			return "", fmt.Errorf("%w: %s of %s", ErrUnitOverflow, val, unit)
+		}
+
+		return time.Duration(scaled).String(), nil
+	}
+
+	factor, err := strconv.ParseInt(unit, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("unit %q: %w", unit, err)
+	}
+
+	scaled, ok := multiply(n, factor)
+	if !ok {
+		return "", fmt.Errorf("%w: %s of %s", ErrUnitOverflow, val, unit)
+	}
+
+	return strconv.FormatInt(scaled, 10), nil
+}
+
+// Returns false if the product doesn't fit int64
+func multiply(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+
+	c := a * b
+	if c/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, false
+	}
+
+	return c, true
+}
+
+// Structs parsed from a single value are not nested
+func isNested(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct &&
+		t != urlType &&
+		!reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+func setValue(v reflect.Value, val string) error {
+	if v.Kind() == reflect.Pointer {
+		ptr := reflect.New(v.Type().Elem())
+		if err := setValue(ptr.Elem(), val); err != nil {
+			return err
+		}
+
+		v.Set(ptr)
+		return nil
+	}
+
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(val))
+	}
+
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(int64(d))
+		return nil
+	case v.Type() == urlType:
+		u, err := parseURL(val)
+		if err != nil {
+			return err
+		}
+
+		v.Set(reflect.ValueOf(*u))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(val)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(val, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(val, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(val, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		v.SetFloat(f)
+	case reflect.Slice:
+		// Byte slices hold the raw value, e.g. a key
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			v.SetBytes([]byte(val))
+			return nil
+		}
+
+		items, _ := parseStrings(val)
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+
+		for i, item := range items {
+			if err := setValue(slice.Index(i), item); err != nil {
+				return err
+			}
+		}
+
+		v.Set(slice)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedType, v.Type())
+	}
+
+	return nil
+}
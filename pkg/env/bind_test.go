@@ -0,0 +1,246 @@
+package env
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testDBConfig struct {
+	Host string `env:"HOST" default:"localhost"`
+	Port int    `env:"PORT"`
+}
+
+type testTLSConfig struct {
+	Cert string `env:"CERT"`
+}
+
+type testConfig struct {
+	Addr     string        `env:"ADDR" default:":8000"`
+	Secret   string        `env:"SECRET,required"`
+	Timeout  time.Duration `env:"TIMEOUT" default:"5s"`
+	Debug    bool          `env:"DEBUG"`
+	Ratio    float64       `env:"RATIO"`
+	Limit    uint16        `env:"LIMIT"`
+	Origins  []string      `env:"ORIGINS"`
+	Ports    []int         `env:"PORTS"`
+	Key      []byte        `env:"KEY"`
+	Endpoint url.URL       `env:"ENDPOINT"`
+	IP       net.IP        `env:"IP"`
+	MaxConns *int          `env:"MAX_CONNS"`
+	Untagged string
+
+	DB      testDBConfig   `envPrefix:"DB_"`
+	Replica *testDBConfig  `envPrefix:"REPLICA_"`
+	TLS     *testTLSConfig `envPrefix:"TLS_"`
+}
+
+func TestBind(t *testing.T) {
+	t.Setenv("SECRET", "")
+	t.Setenv("SECRET_FILE", writeTestFile(t, "secret\n"))
+	t.Setenv("DEBUG", "true")
+	t.Setenv("RATIO", "0.5")
+	t.Setenv("LIMIT", "100")
+	t.Setenv("ORIGINS", "https://a.example, https://b.example")
+	t.Setenv("PORTS", "80,443")
+	t.Setenv("KEY", "raw,key")
+	t.Setenv("ENDPOINT", "https://s3.example.com")
+	t.Setenv("IP", "10.0.0.1")
+	t.Setenv("MAX_CONNS", "10")
+	t.Setenv("DB_PORT", "5432")
+	t.Setenv("REPLICA_HOST", "replica")
+
+	cfg := testConfig{Untagged: "kept"}
+	if err := Bind(&cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	want := testConfig{
+		Addr:     ":8000",
+		Secret:   "secret",
+		Timeout:  5 * time.Second,
+		Debug:    true,
+		Ratio:    0.5,
+		Limit:    100,
+		Origins:  []string{"https://a.example", "https://b.example"},
+		Ports:    []int{80, 443},
+		Key:      []byte("raw,key"),
+		Endpoint: url.URL{Scheme: "https", Host: "s3.example.com"},
+		IP:       net.ParseIP("10.0.0.1"),
+		MaxConns: cfg.MaxConns,
+		Untagged: "kept",
+		DB:       testDBConfig{Host: "localhost", Port: 5432},
+		Replica:  &testDBConfig{Host: "replica"},
+	}
+
+	if !reflect.DeepEqual(cfg, want) {
+		t.Fatalf("got %+v, want %+v", cfg, want)
+	}
+
+	if cfg.MaxConns == nil || *cfg.MaxConns != 10 {
+		t.Fatalf("unexpected max conns %v", cfg.MaxConns)
+	}
+}
+
+func TestBindNestedPointer(t *testing.T) {
+	t.Setenv("SECRET", "secret")
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		cfg  testConfig
+		want *testTLSConfig
+	}{
+		{"unset stays nil", nil, testConfig{}, nil},
+		{"set allocates", map[string]string{"TLS_CERT": "cert"}, testConfig{}, &testTLSConfig{Cert: "cert"}},
+		{
+			"set updates existing",
+			map[string]string{"TLS_CERT": "cert"},
+			testConfig{TLS: &testTLSConfig{Cert: "old"}},
+			&testTLSConfig{Cert: "cert"},
+		},
+		{"unset keeps value", nil, testConfig{TLS: &testTLSConfig{Cert: "old"}}, &testTLSConfig{Cert: "old"}},
+	}
+
+	// Defaults alone don't allocate
+	var cfg testConfig
+	if err := Bind(&cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.Replica != nil {
+		t.Fatalf("unset replica is allocated: %+v", cfg.Replica)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TLS_CERT", "")
+
+			for key, val := range tt.env {
+				t.Setenv(key, val)
+			}
+
+			if err := Bind(&tt.cfg); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(tt.cfg.TLS, tt.want) {
+				t.Fatalf("got %+v, want %+v", tt.cfg.TLS, tt.want)
+			}
+		})
+	}
+}
+
+func TestBindAggregatesErrors(t *testing.T) {
+	t.Setenv("SECRET", "")
+	t.Setenv("DEBUG", "maybe")
+	t.Setenv("DB_PORT", "port")
+	t.Setenv("ENDPOINT", "/relative")
+
+	var cfg testConfig
+	err := Bind(&cfg)
+
+	if !errors.Is(err, ErrRequired) {
+		t.Fatalf("expected ErrRequired, got %v", err)
+	}
+
+	if !errors.Is(err, strconv.ErrSyntax) {
+		t.Fatalf("expected parse errors, got %v", err)
+	}
+
+	// Missing secret, bad bool, bad nested int and relative url
+	if n := len(err.(interface{ Unwrap() []error }).Unwrap()); n != 4 {
+		t.Fatalf("expected 4 errors, got %d: %v", n, err)
+	}
+}
+
+func TestBindRejectsInvalidTarget(t *testing.T) {
+	var cfg testConfig
+
+	for _, dst := range []any{nil, cfg, (*testConfig)(nil), new(int)} {
+		if err := Bind(dst); !errors.Is(err, ErrNotStructPointer) {
+			t.Fatalf("%T: expected ErrNotStructPointer, got %v", dst, err)
+		}
+	}
+}
+
+func TestBindUnsupportedType(t *testing.T) {
+	t.Setenv("CH", "value")
+
+	var cfg struct {
+		Ch chan int `env:"CH"`
+	}
+
+	if err := Bind(&cfg); !errors.Is(err, ErrUnsupportedType) {
+		t.Fatalf("expected ErrUnsupportedType, got %v", err)
+	}
+}
+
+func TestBindUnit(t *testing.T) {
+	tests := []struct {
+		name    string
+		ttl     string
+		size    string
+		wantTTL time.Duration
+		want    int64
+	}{
+		{"plain numbers are units", "15", "2", 15 * time.Minute, 2048},
+		{"go duration as is", "90s", "2", 90 * time.Second, 2048},
+		{"zero", "0", "0", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TTL", tt.ttl)
+			t.Setenv("SIZE", tt.size)
+
+			var cfg struct {
+				TTL  time.Duration `env:"TTL" unit:"1m"`
+				Size int64         `env:"SIZE" unit:"1024"`
+			}
+
+			if err := Bind(&cfg); err != nil {
+				t.Fatal(err)
+			}
+
+			if cfg.TTL != tt.wantTTL || cfg.Size != tt.want {
+				t.Fatalf("got %s and %d, want %s and %d", cfg.TTL, cfg.Size, tt.wantTTL, tt.want)
+			}
+		})
+	}
+
+	t.Setenv("TTL", "15")
+
+	var invalid struct {
+		TTL time.Duration `env:"TTL" unit:"minute"`
+	}
+
+	if err := Bind(&invalid); err == nil {
+		t.Fatal("expected invalid unit error")
+	}
+
+	// Fits int64 as is, but not in nanoseconds or KiB
+	t.Setenv("TTL", "9223372036854775807")
+	t.Setenv("SIZE", "-9223372036854775807")
+
+	var overflowing struct {
+		TTL  time.Duration `env:"TTL" unit:"1m"`
+		Size int64         `env:"SIZE" unit:"1024"`
+	}
+
+	err := Bind(&overflowing)
+	if !errors.Is(err, ErrUnitOverflow) {
+		t.Fatalf("expected ErrUnitOverflow, got %v", err)
+	}
+
+	for _, key := range []string{"TTL", "SIZE"} {
+		if !strings.Contains(err.Error(), "env "+key) {
+			t.Fatalf("expected overflow of %s reported, got %v", key, err)
+		}
+	}
+}
@@ -2,13 +2,72 @@ package env
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
+// Suffix of the variable holding path to the file with the value,
+// e.g. DB_PASSWORD_FILE, so secrets can be mounted as files
+const FileSuffix = "_FILE"
+
+// Returns value of the variable. Empty variable is treated as unset,
+// then <key>_FILE is read instead if set. Trailing newline of the file
+// is trimmed, as editors tend to add one
+func Lookup(key string) (string, bool, error) {
+	if val := os.Getenv(key); val != "" {
+		return val, true, nil
+	}
+
+	path := os.Getenv(key + FileSuffix)
+	if path == "" {
+		return "", false, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("env %s: %w", key+FileSuffix, err)
+	}
+
+	return strings.TrimRight(string(data), "\r\n"), true, nil
+}
+
+func LookupInt(key string) (int, bool, error) {
+	return lookup(key, strconv.Atoi)
+}
+
+func LookupFloat64(key string) (float64, bool, error) {
+	return lookup(key, parseFloat64)
+}
+
+func LookupBool(key string) (bool, bool, error) {
+	return lookup(key, strconv.ParseBool)
+}
+
+// Value is Go duration, e.g. "15m"
+func LookupDuration(key string) (time.Duration, bool, error) {
+	return lookup(key, time.ParseDuration)
+}
+
+// Value is comma separated list, items are trimmed
+func LookupStrings(key string) ([]string, bool, error) {
+	return lookup(key, parseStrings)
+}
+
+// Value is absolute URL
+func LookupURL(key string) (*url.URL, bool, error) {
+	return lookup(key, parseURL)
+}
+
 func String(key string) string {
-	val := os.Getenv(key)
-	if val == "" {
+	val, ok, err := Lookup(key)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	if !ok {
 		panic(fmt.Sprintf("env empty key: %v", key))
 	}
 
@@ -16,34 +75,129 @@ func String(key string) string {
 }
 
 func Int(key string) int {
-	val := String(key)
+	return required(key, "int", strconv.Atoi)
+}
+
+func Float64(key string) float64 {
+	return required(key, "float", parseFloat64)
+}
+
+func Bool(key string) bool {
+	return required(key, "bool", strconv.ParseBool)
+}
+
+func Duration(key string) time.Duration {
+	return required(key, "duration", time.ParseDuration)
+}
+
+func Strings(key string) []string {
+	return required(key, "strings", parseStrings)
+}
+
+func URL(key string) *url.URL {
+	return required(key, "url", parseURL)
+}
 
-	intVal, err := strconv.Atoi(val)
+// The *Or variants return the default if the variable is unset, but
+// still panic on malformed values, as typos should not go unnoticed
+
+func StringOr(key, def string) string {
+	return or(key, "string", def, func(val string) (string, error) {
+		return val, nil
+	})
+}
+
+func IntOr(key string, def int) int {
+	return or(key, "int", def, strconv.Atoi)
+}
+
+func Float64Or(key string, def float64) float64 {
+	return or(key, "float", def, parseFloat64)
+}
+
+func BoolOr(key string, def bool) bool {
+	return or(key, "bool", def, strconv.ParseBool)
+}
+
+func DurationOr(key string, def time.Duration) time.Duration {
+	return or(key, "duration", def, time.ParseDuration)
+}
+
+func StringsOr(key string, def []string) []string {
+	return or(key, "strings", def, parseStrings)
+}
+
+func URLOr(key string, def *url.URL) *url.URL {
+	return or(key, "url", def, parseURL)
+}
+
+func lookup[T any](key string, parse func(string) (T, error)) (T, bool, error) {
+	var zero T
+
+	val, ok, err := Lookup(key)
+	if err != nil || !ok {
+		return zero, false, err
+	}
+
+	parsed, err := parse(val)
 	if err != nil {
-		panic(fmt.Sprintf("env int error: %v", err))
+		return zero, false, fmt.Errorf("env %s: %w", key, err)
 	}
 
-	return intVal
+	return parsed, true, nil
 }
 
-func Float64(key string) float64 {
+func required[T any](key, kind string, parse func(string) (T, error)) T {
 	val := String(key)
 
-	floatVal, err := strconv.ParseFloat(val, 64)
+	parsed, err := parse(val)
 	if err != nil {
-		panic(fmt.Sprintf("env float error: %v", err))
+		panic(fmt.Sprintf("env %s error: %v", kind, err))
 	}
 
-	return floatVal
+	return parsed
 }
 
-func Bool(key string) bool {
-	val := String(key)
+func or[T any](key, kind string, def T, parse func(string) (T, error)) T {
+	val, ok, err := Lookup(key)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	if !ok {
+		return def
+	}
 
-	boolVal, err := strconv.ParseBool(val)
+	parsed, err := parse(val)
 	if err != nil {
-		panic(fmt.Sprintf("env bool error: %v", err))
+		panic(fmt.Sprintf("env %s error: %v", kind, err))
+	}
+
+	return parsed
+}
+
+func parseFloat64(val string) (float64, error) {
+	return strconv.ParseFloat(val, 64)
+}
+
+func parseStrings(val string) ([]string, error) {
+	items := strings.Split(val, ",")
+	for i, item := range items {
+		items[i] = strings.TrimSpace(item)
+	}
+
+	return items, nil
+}
+
+func parseURL(val string) (*url.URL, error) {
+	u, err := url.Parse(val)
+	if err != nil {
+		return nil, err
+	}
+
+	if !u.IsAbs() {
+		return nil, fmt.Errorf("url is not absolute: %s", val)
 	}
 
-	return boolVal
+	return u, nil
 }
@@ -0,0 +1,162 @@
+package env
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func writeTestFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "value")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    string
+		found   bool
+		wantErr bool
+	}{
+		{"unset", nil, "", false, false},
+		{"set", map[string]string{"KEY": "value"}, "value", true, false},
+		{"empty is unset", map[string]string{"KEY": ""}, "", false, false},
+		{"file", map[string]string{"KEY_FILE": "secret\n"}, "secret", true, false},
+		{"file with crlf", map[string]string{"KEY_FILE": "secret\r\n"}, "secret", true, false},
+		{"value wins over file", map[string]string{"KEY": "value", "KEY_FILE": "secret"}, "value", true, false},
+		{"empty value falls back to file", map[string]string{"KEY": "", "KEY_FILE": "secret"}, "secret", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("KEY", "")
+			t.Setenv("KEY_FILE", "")
+
+			for key, val := range tt.env {
+				// File variables hold the content, it's written to a file
+				if key == "KEY_FILE" {
+					val = writeTestFile(t, val)
+				}
+
+				t.Setenv(key, val)
+			}
+
+			got, found, err := Lookup("KEY")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if got != tt.want || found != tt.found {
+				t.Fatalf("got %q, %v, want %q, %v", got, found, tt.want, tt.found)
+			}
+		})
+	}
+}
+
+func TestLookupMissingFile(t *testing.T) {
+	t.Setenv("KEY", "")
+	t.Setenv("KEY_FILE", filepath.Join(t.TempDir(), "missing"))
+
+	if _, _, err := Lookup("KEY"); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestLookupParsed(t *testing.T) {
+	t.Setenv("INT", "42")
+	t.Setenv("BAD_INT", "forty two")
+	t.Setenv("DURATION", "15m")
+	t.Setenv("STRINGS", " a, b ,c ")
+	t.Setenv("URL", "https://example.com/path")
+	t.Setenv("RELATIVE_URL", "/path")
+
+	if n, ok, err := LookupInt("INT"); n != 42 || !ok || err != nil {
+		t.Fatalf("LookupInt: got %d, %v, %v", n, ok, err)
+	}
+
+	if _, ok, err := LookupInt("BAD_INT"); ok || err == nil {
+		t.Fatalf("LookupInt: expected error, got %v, %v", ok, err)
+	}
+
+	if _, ok, err := LookupInt("UNSET_INT"); ok || err != nil {
+		t.Fatalf("LookupInt: expected unset, got %v, %v", ok, err)
+	}
+
+	if d, ok, err := LookupDuration("DURATION"); d != 15*time.Minute || !ok || err != nil {
+		t.Fatalf("LookupDuration: got %s, %v, %v", d, ok, err)
+	}
+
+	items, ok, err := LookupStrings("STRINGS")
+	if !ok || err != nil || !reflect.DeepEqual(items, []string{"a", "b", "c"}) {
+		t.Fatalf("LookupStrings: got %q, %v, %v", items, ok, err)
+	}
+
+	if u, ok, err := LookupURL("URL"); !ok || err != nil || u.Host != "example.com" {
+		t.Fatalf("LookupURL: got %v, %v, %v", u, ok, err)
+	}
+
+	if _, ok, err := LookupURL("RELATIVE_URL"); ok || err == nil {
+		t.Fatalf("LookupURL: expected error for relative url, got %v, %v", ok, err)
+	}
+}
+
+func TestOr(t *testing.T) {
+	t.Setenv("INT", "42")
+	t.Setenv("BAD_INT", "forty two")
+
+	if n := IntOr("INT", 1); n != 42 {
+		t.Fatalf("got %d, want 42", n)
+	}
+
+	if n := IntOr("UNSET_INT", 1); n != 1 {
+		t.Fatalf("got %d, want default 1", n)
+	}
+
+	assertPanics(t, func() { IntOr("BAD_INT", 1) })
+
+	t.Setenv("URL", "https://example.com")
+	t.Setenv("RELATIVE_URL", "/path")
+
+	def := &url.URL{Scheme: "https", Host: "default.example.com"}
+
+	if u := URLOr("URL", def); u.Host != "example.com" {
+		t.Fatalf("got %v, want example.com", u)
+	}
+
+	if u := URLOr("UNSET_URL", def); u != def {
+		t.Fatalf("got %v, want default %v", u, def)
+	}
+
+	assertPanics(t, func() { URLOr("RELATIVE_URL", def) })
+}
+
+func TestRequired(t *testing.T) {
+	t.Setenv("INT", "42")
+
+	if n := Int("INT"); n != 42 {
+		t.Fatalf("got %d, want 42", n)
+	}
+
+	assertPanics(t, func() { Int("UNSET_INT") })
+	assertPanics(t, func() { String("UNSET_STRING") })
+}
+
+func assertPanics(t *testing.T, fn func()) {
+	t.Helper()
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic")
+		}
+	}()
+
+	fn()
+}